@@ -1,18 +1,24 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/config"
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/handlers"
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
 	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/auth"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
 	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/database"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/payment"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/similarity"
 	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/watermark"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/cors"
@@ -20,59 +26,124 @@ import (
 )
 
 func main() {
-	var (
-		serverPort = os.Getenv("PORT")
-		dbname     = os.Getenv("DB_DATABASE")
-		password   = os.Getenv("DB_PASSWORD")
-		username   = os.Getenv("DB_USERNAME")
-		dbPort     = os.Getenv("DB_PORT")
-		jwtSecret  = os.Getenv("JWT_SECRET")
-		minioPort  = os.Getenv("MINIO_API_PORT")
-		accessKey  = os.Getenv("MINIO_ACCESS_KEY")
-		secretKey  = os.Getenv("MINIO_SECRET_KEY")
-		bucketName = os.Getenv("MINIO_BUCKET_NAME")
-		similarURL = os.Getenv("SIMILARITY_CHECK_URL")
-	)
-
-	connStr := fmt.Sprintf("postgres://%s:%s@localhost:%s/%s?sslmode=disable", username, password, dbPort, dbname)
-	db, err := database.NewDatabase(connStr)
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+
+	db, err := database.NewDatabase(cfg.Database.DSN())
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	fileStorage, err := storage.NewFileStorage(
-		fmt.Sprintf("localhost:%s", minioPort),
-		accessKey,
-		secretKey,
-		bucketName,
-		false,
-	)
+	fileStorage, err := storage.New(cfg.Storage.StorageConfig())
 	if err != nil {
 		log.Fatalf("failed to create storage service: %v", err)
 	}
 
-	auth.Init(jwtSecret)
+	if err := auth.Init(cfg.Auth.JWTSecret); err != nil {
+		log.Fatalf("failed to initialise auth: %v", err)
+	}
 
 	userRepo := repositories.NewUserRepository(db)
 	contentRepo := repositories.NewContentRepository(db)
 	licenseRepo := repositories.NewLicenseRepository(db)
 	sessionKeyRepo := repositories.NewSessionKeyRepo(db)
+	playRepo := repositories.NewPlayRepository(db)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	collectionRepo := repositories.NewCollectionRepository(db)
+	subscriptionRepo := repositories.NewSubscriptionRepository(db)
+	giftRepo := repositories.NewGiftRepository(db)
+	pricingRepo := repositories.NewPricingRepository(db)
+	orderRepo := repositories.NewOrderRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	refundRepo := repositories.NewRefundRepository(db)
+	watermarkRepo := repositories.NewWatermarkRepository(db)
+	renditionRepo := repositories.NewRenditionRepository(db)
+	previewRepo := repositories.NewPreviewRepository(db)
+	similarityRepo := repositories.NewSimilarityRepository(db)
+	fingerprintRepo := repositories.NewFingerprintRepository(db)
+	objectRepo := repositories.NewObjectRepository(db)
+	quotaRepo := repositories.NewQuotaRepository(db)
+
+	clk := clock.New()
+	fileStorage = services.NewTrackedStorage(fileStorage, objectRepo, clk)
+	var similarityChecker similarity.Checker
+	switch cfg.Similarity.Backend {
+	case "http":
+		similarityChecker = similarity.NewHTTP(similarity.HTTPConfig{BaseURL: cfg.Similarity.URL,
+			Timeout: cfg.Similarity.Timeout.Duration, Deadline: cfg.Similarity.Deadline.Duration, Clock: clk})
+	case "phash":
+		similarityChecker = similarity.NewLocal(fingerprintRepo,
+			similarity.LocalConfig{Timeout: cfg.Similarity.Deadline.Duration})
+	}
+	payments := payment.NewManual()
 
 	userService := services.NewUserService(userRepo)
-	contentService := services.NewContentService(contentRepo, fileStorage, similarURL)
-	licenseService := services.NewLicenseService(licenseRepo)
-	sessionKeyService := services.NewSessionKeyService(sessionKeyRepo)
+	quotaService := services.NewQuotaService(quotaRepo)
+	contentService := services.NewContentService(contentRepo, licenseRepo, categoryRepo, similarityRepo, fingerprintRepo,
+		fileStorage, similarityChecker, cfg.Similarity.UnavailablePolicy, cfg.Similarity.Threshold, quotaService)
+	licenseService := services.NewLicenseService(licenseRepo, subscriptionRepo, clk)
+	sessionKeyService := services.NewSessionKeyService(sessionKeyRepo, playRepo)
+	collectionService := services.NewCollectionService(collectionRepo, contentRepo, licenseService)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepo, payments, clk)
+	giftService := services.NewGiftService(giftRepo, contentRepo, licenseRepo, sessionKeyRepo, userRepo)
+	pricingService := services.NewPricingService(pricingRepo, contentRepo, orderRepo)
+	ledgerService := services.NewLedgerService(ledgerRepo, cfg.Revenue.Split(), clk)
+	purchaseService := services.NewPurchaseService(contentRepo, collectionRepo, orderRepo, pricingService, licenseService,
+		ledgerService, payments)
+	refundService := services.NewRefundService(refundRepo, orderRepo, licenseRepo, giftRepo, playRepo, ledgerService,
+		payments, clk)
+	watermarkService := services.NewWatermarkService(watermarkRepo, contentRepo, licenseRepo, fileStorage, clk)
+	packagingService := services.NewPackagingService(renditionRepo, contentRepo, contentService, sessionKeyService,
+		watermarkService, fileStorage, clk)
+	previewService := services.NewPreviewService(previewRepo, contentRepo, fileStorage, clk)
+	disputeService := services.NewDisputeService(similarityRepo, contentRepo, clk)
+	storageService := services.NewStorageService(objectRepo, fileStorage, cfg.Storage.GCMode,
+		cfg.Storage.GCGrace.Duration, clk)
+
+	if !watermark.Available() {
+		log.Println("ffmpeg not found, content will be served without forensic watermarks, HLS renditions or generated previews")
+	}
+
+	go runPeriodically(time.Hour, "purge deleted content", contentService.PurgeDeleted)
+	go runPeriodically(time.Hour, "renew subscriptions", subscriptionService.RenewDue)
+	go runPeriodically(5*time.Minute, "recheck unchecked content", contentService.RecheckUnchecked)
+	go runPeriodically(10*time.Minute, "scrub content checksums", contentService.ScrubChecksums)
+	go runPeriodically(time.Minute, "prepare watermark variants", watermarkService.PrepareMissing)
+	go runPeriodically(time.Minute, "package HLS renditions", packagingService.PackageMissing)
+	go runPeriodically(time.Minute, "generate previews", previewService.GenerateMissing)
+	go runPeriodically(15*time.Minute, "collect abandoned uploads", storageService.CollectPending)
+	go runPeriodically(24*time.Hour, "reconcile storage", storageService.ReconcileScheduled)
 
 	userHandler := handlers.NewUserHandler(userService)
-	contentHandler := handlers.NewContentHandler(contentService, licenseService, sessionKeyService)
+	contentHandler := handlers.NewContentHandler(contentService, licenseService, sessionKeyService, watermarkService,
+		previewService, quotaService)
+	collectionHandler := handlers.NewCollectionHandler(collectionService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	giftHandler := handlers.NewGiftHandler(giftService)
+	pricingHandler := handlers.NewPricingHandler(pricingService)
+	purchaseHandler := handlers.NewPurchaseHandler(purchaseService)
+	earningsHandler := handlers.NewEarningsHandler(ledgerService)
+	refundHandler := handlers.NewRefundHandler(refundService)
+	packagingHandler := handlers.NewPackagingHandler(contentService, licenseService, packagingService, quotaService)
+	previewHandler := handlers.NewPreviewHandler(previewService, quotaService)
+	disputeHandler := handlers.NewDisputeHandler(disputeService)
+	storageHandler := handlers.NewStorageHandler(storageService, quotaService)
 
 	router := chi.NewRouter()
 	router.Use(middleware.Logger)
 
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
@@ -85,27 +156,142 @@ func main() {
 
 	router.Post("/register", userHandler.Register)
 	router.Post("/login", userHandler.Login)
+	router.Get("/preview/{id}/{kind}", previewHandler.GetPreview)
+
+	upload := handlers.UploadTimeout(cfg.Server.UploadTimeout.Duration)
 
 	contentRouter := chi.NewRouter()
 	contentRouter.Use(auth.AuthenticateToken)
 
-	contentRouter.Post("/create", contentHandler.CreateContent)
+	contentRouter.With(upload).Post("/create", contentHandler.CreateContent)
 	contentRouter.Get("/list", contentHandler.ListContent)
 	contentRouter.Get("/list-self", contentHandler.ListSelfContent)
-	contentRouter.Post("/purchase/{id}", contentHandler.PurchaseContent)
+	contentRouter.Post("/purchase/{id}", purchaseHandler.PurchaseContent)
+	contentRouter.Get("/quote/{id}", purchaseHandler.QuoteContent)
 	contentRouter.Get("/get/{id}", contentHandler.GetContentData)
 	contentRouter.Get("/stream/{id}", contentHandler.GetContent)
+	contentRouter.Get("/hls/{id}/index.m3u8", packagingHandler.GetManifest)
+	contentRouter.Get("/hls/{id}/init.mp4", packagingHandler.GetInit)
+	contentRouter.Get("/hls/{id}/segment/{n}", packagingHandler.GetSegment)
+	contentRouter.Get("/hls/{id}/key", packagingHandler.GetKey)
+	contentRouter.Patch("/update/{id}", contentHandler.UpdateContent)
+	contentRouter.With(upload).Put("/update/{id}/file", contentHandler.ReplaceContentFile)
+	contentRouter.With(upload).Put("/update/{id}/hls", packagingHandler.ImportSegments)
+	contentRouter.With(upload).Put("/update/{id}/preview/{kind}", previewHandler.UploadPreview)
+	contentRouter.Delete("/delete/{id}", contentHandler.DeleteContent)
+	contentRouter.Get("/categories", contentHandler.ListCategories)
+	contentRouter.With(auth.RequireAdmin).Put("/categories/{slug}/threshold", contentHandler.SetCategoryThreshold)
+	contentRouter.With(auth.RequireAdmin).Get("/corrupt", contentHandler.ListCorruptContent)
+	contentRouter.Post("/gift/{id}", purchaseHandler.PurchaseGift)
+	contentRouter.Get("/gifts", giftHandler.ListGifts)
+	contentRouter.Post("/redeem", giftHandler.RedeemGift)
+	contentRouter.Post("/transfer/{id}", giftHandler.TransferLicense)
+	contentRouter.Get("/licenses", contentHandler.ListLicenses)
 
 	router.Mount("/content", contentRouter)
 
+	collectionRouter := chi.NewRouter()
+	collectionRouter.Use(auth.AuthenticateToken)
+
+	collectionRouter.Post("/create", collectionHandler.CreateCollection)
+	collectionRouter.Get("/get/{id}", collectionHandler.GetCollection)
+	collectionRouter.Get("/list/{creatorId}", collectionHandler.ListCreatorCollections)
+	collectionRouter.Get("/list-self", collectionHandler.ListSelfCollections)
+	collectionRouter.Patch("/update/{id}", collectionHandler.UpdateCollection)
+	collectionRouter.Delete("/delete/{id}", collectionHandler.DeleteCollection)
+	collectionRouter.Put("/items/{id}", collectionHandler.SetCollectionItems)
+	collectionRouter.Post("/items/{id}/{contentId}", collectionHandler.AddCollectionItem)
+	collectionRouter.Delete("/items/{id}/{contentId}", collectionHandler.RemoveCollectionItem)
+	collectionRouter.Post("/purchase/{id}", purchaseHandler.PurchaseCollection)
+	collectionRouter.Get("/quote/{id}", purchaseHandler.QuoteCollection)
+
+	router.Mount("/collections", collectionRouter)
+
+	subscriptionRouter := chi.NewRouter()
+	subscriptionRouter.Use(auth.AuthenticateToken)
+
+	subscriptionRouter.Post("/plans/create", subscriptionHandler.CreatePlan)
+	subscriptionRouter.Get("/plans/list", subscriptionHandler.ListPlans)
+	subscriptionRouter.Post("/subscribe/{id}", subscriptionHandler.Subscribe)
+	subscriptionRouter.Post("/cancel/{id}", subscriptionHandler.Cancel)
+	subscriptionRouter.Get("/list-self", subscriptionHandler.ListSelfSubscriptions)
+
+	router.Mount("/subscriptions", subscriptionRouter)
+
+	pricingRouter := chi.NewRouter()
+	pricingRouter.Use(auth.AuthenticateToken)
+
+	pricingRouter.Post("/coupons/create", pricingHandler.CreateCoupon)
+	pricingRouter.Get("/coupons/list-self", pricingHandler.ListSelfCoupons)
+	pricingRouter.Post("/sales/create", pricingHandler.CreateSale)
+
+	router.Mount("/pricing", pricingRouter)
+
+	earningsRouter := chi.NewRouter()
+	earningsRouter.Use(auth.AuthenticateToken)
+
+	earningsRouter.Get("/balance", earningsHandler.GetBalance)
+	earningsRouter.Get("/sales", earningsHandler.ListSales)
+	earningsRouter.Get("/statement", earningsHandler.GetStatement)
+	earningsRouter.Get("/payouts/list-self", earningsHandler.ListSelfPayouts)
+	earningsRouter.With(auth.RequireAdmin).Post("/payouts/run", earningsHandler.RunPayouts)
+
+	router.Mount("/earnings", earningsRouter)
+
+	orderRouter := chi.NewRouter()
+	orderRouter.Use(auth.AuthenticateToken)
+
+	orderRouter.Get("/list-self", refundHandler.ListSelfOrders)
+	orderRouter.Post("/refund/{id}", refundHandler.RequestRefund)
+	orderRouter.Get("/refunds/list-self", refundHandler.ListSelfRefunds)
+	orderRouter.Get("/refunds/list-pending", refundHandler.ListPendingRefunds)
+	orderRouter.Post("/refunds/approve/{id}", refundHandler.ApproveRefund)
+	orderRouter.Post("/refunds/reject/{id}", refundHandler.RejectRefund)
+
+	router.Mount("/orders", orderRouter)
+
+	disputeRouter := chi.NewRouter()
+	disputeRouter.Use(auth.AuthenticateToken)
+
+	disputeRouter.Get("/checks/{id}", disputeHandler.ListChecks)
+	disputeRouter.Post("/create/{id}", disputeHandler.OpenDispute)
+	disputeRouter.Get("/list-self", disputeHandler.ListSelfDisputes)
+	disputeRouter.With(auth.RequireAdmin).Get("/list-open", disputeHandler.ListOpenDisputes)
+	disputeRouter.With(auth.RequireAdmin).Post("/approve/{id}", disputeHandler.ApproveDispute)
+	disputeRouter.With(auth.RequireAdmin).Post("/deny/{id}", disputeHandler.DenyDispute)
+
+	router.Mount("/disputes", disputeRouter)
+
+	storageRouter := chi.NewRouter()
+	storageRouter.Use(auth.AuthenticateToken)
+
+	storageRouter.Get("/usage", storageHandler.GetUsage)
+	storageRouter.Get("/tiers", storageHandler.ListTiers)
+	storageRouter.With(auth.RequireAdmin).Put("/tiers/{userId}", storageHandler.SetUserTier)
+	storageRouter.With(auth.RequireAdmin).Get("/report", storageHandler.GetReport)
+	storageRouter.With(auth.RequireAdmin).Post("/collect", storageHandler.Collect)
+
+	router.Mount("/storage", storageRouter)
+
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", serverPort),
+		Addr:         fmt.Sprintf(":%s", cfg.Server.Port),
 		Handler:      router,
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
 	}
 
-	log.Printf("server started on port %v\n", serverPort)
+	log.Printf("server started on port %v\n", cfg.Server.Port)
 	log.Fatal(srv.ListenAndServe())
 }
+
+func runPeriodically(interval time.Duration, name string, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := job(); err != nil {
+			log.Printf("failed to %s: %v\n", name, err)
+		}
+	}
+}
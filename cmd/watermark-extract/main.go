@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/config"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/database"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+func main() {
+	contentId := flag.String("content", "", "id of the content the leaked file was taken from")
+	file := flag.String("file", "", "path to the leaked file")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON config file")
+	flag.Parse()
+
+	if *contentId == "" || *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	if err := errors.Join(cfg.Database.Validate(), cfg.Storage.Validate()); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+
+	db, err := database.NewDatabase(cfg.Database.DSN())
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	fileStorage, err := storage.New(cfg.Storage.StorageConfig())
+	if err != nil {
+		log.Fatalf("failed to create storage service: %v", err)
+	}
+
+	watermarkService := services.NewWatermarkService(
+		repositories.NewWatermarkRepository(db),
+		repositories.NewContentRepository(db),
+		repositories.NewLicenseRepository(db),
+		fileStorage,
+		clock.New(),
+	)
+
+	match, err := watermarkService.Identify(*contentId, *file)
+	if err != nil {
+		if errors.Is(err, services.ErrWatermarkNotFound) {
+			log.Fatalf("no source license could be identified for %s", *file)
+		}
+		log.Fatalf("failed to extract watermark: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(match)
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/similarity"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+)
+
+// Config is loaded from defaults, then an optional JSON file, then environment variables, each overriding the last.
+type Config struct {
+	Server     ServerConfig     `json:"server"`
+	Database   DatabaseConfig   `json:"database"`
+	Auth       AuthConfig       `json:"auth"`
+	Storage    StorageConfig    `json:"storage"`
+	Similarity SimilarityConfig `json:"similarity"`
+	Revenue    RevenueConfig    `json:"revenue"`
+}
+
+type ServerConfig struct {
+	Port         string   `json:"port"`
+	ReadTimeout  Duration `json:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout"`
+	// UploadTimeout replaces the read and write timeouts on routes that accept file uploads. Zero means no limit.
+	UploadTimeout Duration `json:"upload_timeout"`
+}
+
+// DatabaseConfig takes either a full connection URL or its parts. The URL wins when both are given.
+type DatabaseConfig struct {
+	URL      string `json:"url"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Name     string `json:"name"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	SSLMode  string `json:"sslmode"`
+}
+
+type AuthConfig struct {
+	JWTSecret string `json:"jwt_secret"`
+}
+
+// StorageConfig configures the object store. Endpoint, when set, replaces Host and Port.
+type StorageConfig struct {
+	Backend   string         `json:"backend"`
+	Endpoint  string         `json:"endpoint"`
+	Host      string         `json:"host"`
+	Port      string         `json:"port"`
+	UseSSL    bool           `json:"use_ssl"`
+	AccessKey string         `json:"access_key"`
+	SecretKey string         `json:"secret_key"`
+	Bucket    string         `json:"bucket"`
+	Dir       string         `json:"dir"`
+	GCMode    storage.GCMode `json:"gc_mode"`
+	GCGrace   Duration       `json:"gc_grace"`
+}
+
+type SimilarityConfig struct {
+	Backend string `json:"backend"`
+	URL     string `json:"url"`
+	// Timeout bounds one request to the http backend. Deadline bounds the whole check, retries included, and must
+	// leave the upload time to finish within the server's write timeout.
+	Timeout           Duration                     `json:"timeout"`
+	Deadline          Duration                     `json:"deadline"`
+	UnavailablePolicy similarity.UnavailablePolicy `json:"unavailable_policy"`
+	Threshold         float64                      `json:"threshold"`
+}
+
+type RevenueConfig struct {
+	PlatformFeeBps int64 `json:"platform_fee_bps"`
+	TaxBps         int64 `json:"tax_bps"`
+}
+
+// Duration reads as a string such as "30s" or "24h" in config files.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:          "8080",
+			ReadTimeout:   Duration{10 * time.Second},
+			WriteTimeout:  Duration{30 * time.Second},
+			IdleTimeout:   Duration{time.Minute},
+			UploadTimeout: Duration{10 * time.Minute},
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: "5432", SSLMode: "disable"},
+		Storage: StorageConfig{
+			Backend: storage.BackendMinIO,
+			Host:    "localhost",
+			Port:    "9000",
+			GCMode:  storage.GCReport,
+			GCGrace: Duration{24 * time.Hour},
+		},
+		Similarity: SimilarityConfig{
+			Backend:           "http",
+			Deadline:          Duration{20 * time.Second},
+			UnavailablePolicy: similarity.RejectWhenUnavailable,
+			Threshold:         0.9,
+		},
+		Revenue: RevenueConfig{PlatformFeeBps: 2000},
+	}
+}
+
+// Load reads the file at path, if any, over the defaults and applies environment overrides. It only reports values
+// that cannot be parsed; call Validate before using the result.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks every section and reports all problems at once.
+func (c *Config) Validate() error {
+	errs := []error{c.Server.Validate(), c.Database.Validate(), c.Auth.Validate(), c.Storage.Validate(),
+		c.Similarity.Validate(), c.Revenue.Validate()}
+	if c.Server.WriteTimeout.Duration > 0 && c.Similarity.Deadline.Duration >= c.Server.WriteTimeout.Duration {
+		errs = append(errs, errors.New("similarity deadline must be shorter than the server write timeout"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *ServerConfig) Validate() error {
+	var errs []error
+	if c.Port == "" {
+		errs = append(errs, errors.New("server port is required"))
+	}
+	if c.ReadTimeout.Duration < 0 || c.WriteTimeout.Duration < 0 || c.IdleTimeout.Duration < 0 ||
+		c.UploadTimeout.Duration < 0 {
+		errs = append(errs, errors.New("server timeouts cannot be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *DatabaseConfig) Validate() error {
+	if c.URL != "" {
+		if _, err := url.Parse(c.URL); err != nil {
+			return fmt.Errorf("database url: %w", err)
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.Host == "" || c.Port == "" || c.Name == "" || c.User == "" {
+		errs = append(errs, errors.New("database needs a url, or a host, port, name and user"))
+	}
+	switch c.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("unknown database sslmode %q", c.SSLMode))
+	}
+
+	return errors.Join(errs...)
+}
+
+// DSN returns the connection URL, building it from the parts when no URL was given.
+func (c *DatabaseConfig) DSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, c.Port),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+
+	return u.String()
+}
+
+func (c *AuthConfig) Validate() error {
+	if c.JWTSecret == "" {
+		return errors.New("jwt secret is required")
+	}
+
+	return nil
+}
+
+func (c *StorageConfig) Validate() error {
+	var errs []error
+	switch c.Backend {
+	case storage.BackendMinIO:
+		if c.MinIOEndpoint() == "" {
+			errs = append(errs, errors.New("storage needs an endpoint, or a host and port"))
+		}
+		if c.AccessKey == "" || c.SecretKey == "" || c.Bucket == "" {
+			errs = append(errs, errors.New("minio storage needs an access key, secret key and bucket"))
+		}
+	case storage.BackendLocal:
+		if c.Dir == "" {
+			errs = append(errs, errors.New("local storage needs a directory"))
+		}
+	case storage.BackendMemory:
+	default:
+		errs = append(errs, fmt.Errorf("unknown storage backend %q, expected minio, local or memory", c.Backend))
+	}
+
+	if !c.GCMode.Valid() {
+		errs = append(errs, fmt.Errorf("unknown storage gc mode %q, expected off, report or delete", c.GCMode))
+	}
+	if c.GCGrace.Duration <= 0 {
+		errs = append(errs, errors.New("storage gc grace period must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *StorageConfig) MinIOEndpoint() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	if c.Host == "" || c.Port == "" {
+		return ""
+	}
+
+	return net.JoinHostPort(c.Host, c.Port)
+}
+
+func (c *StorageConfig) StorageConfig() storage.Config {
+	return storage.Config{
+		Backend:   c.Backend,
+		Endpoint:  c.MinIOEndpoint(),
+		AccessKey: c.AccessKey,
+		SecretKey: c.SecretKey,
+		Bucket:    c.Bucket,
+		UseSSL:    c.UseSSL,
+		Dir:       c.Dir,
+	}
+}
+
+func (c *SimilarityConfig) Validate() error {
+	var errs []error
+	switch c.Backend {
+	case "http":
+		if c.URL == "" {
+			errs = append(errs, errors.New("the http similarity backend needs a url"))
+		}
+	case "phash":
+	default:
+		errs = append(errs, fmt.Errorf("unknown similarity backend %q, expected http or phash", c.Backend))
+	}
+
+	if c.Timeout.Duration < 0 || c.Deadline.Duration < 0 {
+		errs = append(errs, errors.New("similarity timeout and deadline cannot be negative"))
+	}
+	if !c.UnavailablePolicy.Valid() {
+		errs = append(errs, fmt.Errorf("unknown similarity unavailable policy %q, expected reject, queue or flag",
+			c.UnavailablePolicy))
+	}
+	if !similarity.ValidThreshold(c.Threshold) {
+		errs = append(errs, errors.New("similarity threshold must be greater than 0 and at most 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *RevenueConfig) Validate() error {
+	if c.PlatformFeeBps < 0 || c.PlatformFeeBps > 10000 || c.TaxBps < 0 || c.TaxBps > 10000 {
+		return errors.New("revenue basis points must be between 0 and 10000")
+	}
+
+	return nil
+}
+
+func (c *RevenueConfig) Split() models.RevenueSplit {
+	return models.RevenueSplit{PlatformFeeBps: c.PlatformFeeBps, TaxBps: c.TaxBps}
+}
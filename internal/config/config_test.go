@@ -0,0 +1,178 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every variable the config reads, so the tests do not depend on the environment they run in.
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	for _, v := range Default().envVars() {
+		t.Setenv(v.name, "")
+	}
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `{
+		"server": {"port": "9000", "read_timeout": "5s"},
+		"database": {"host": "db.internal", "name": "drm"}
+	}`)
+	t.Setenv("PORT", "7000")
+	t.Setenv("DB_DATABASE", "drm_test")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"environment over file", cfg.Server.Port, "7000"},
+		{"environment over file", cfg.Database.Name, "drm_test"},
+		{"file over default", cfg.Server.ReadTimeout.Duration, 5 * time.Second},
+		{"file over default", cfg.Database.Host, "db.internal"},
+		{"default", cfg.Server.WriteTimeout.Duration, 30 * time.Second},
+		{"default", cfg.Database.Port, "5432"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{"unknown field", `{"server": {"prot": "9000"}}`, nil, "unknown field"},
+		{"duration not a string", `{"server": {"read_timeout": 5}}`, nil, "duration must be a string"},
+		{"every bad variable", `{}`, map[string]string{"SERVER_READ_TIMEOUT": "soon", "TAX_BPS": "lots"},
+			"SERVER_READ_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load(writeConfig(t, tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.want)
+			}
+			for name := range tt.env {
+				if !strings.Contains(err.Error(), name) {
+					t.Errorf("err = %v, want it to mention %s", err, name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = ""
+	cfg.Database.SSLMode = "sometimes"
+	cfg.Similarity.Deadline = Duration{time.Minute}
+
+	err := cfg.Validate()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("err = %v, want joined errors", err)
+	}
+
+	for _, want := range []string{
+		"server port is required",
+		"database needs a url",
+		`unknown database sslmode "sometimes"`,
+		"jwt secret is required",
+		"minio storage needs an access key",
+		"the http similarity backend needs a url",
+		"similarity deadline must be shorter than the server write timeout",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err does not mention %q:\n%v", want, err)
+		}
+	}
+	if len(joined.Unwrap()) != 6 {
+		t.Errorf("got %d sections of errors, want 6: %v", len(joined.Unwrap()), err)
+	}
+}
+
+func TestValidateAcceptsCompleteConfig(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Name, cfg.Database.User = "drm", "drm"
+	cfg.Auth.JWTSecret = "secret"
+	cfg.Storage.AccessKey, cfg.Storage.SecretKey, cfg.Storage.Bucket = "access", "secret", "content"
+	cfg.Similarity.URL = "http://similarity:8000"
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestDSNEscapesCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		password string
+	}{
+		{"plain", "drm", "secret"},
+		{"url delimiters", "drm", "p@ss:w/rd?#"},
+		{"percent and spaces", "drm admin", "100% sure &more"},
+		{"unicode", "drm", "pässwörd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DatabaseConfig{Host: "db.internal", Port: "5432", Name: "drm", User: tt.user, Password: tt.password,
+				SSLMode: "require"}
+
+			u, err := url.Parse(cfg.DSN())
+			if err != nil {
+				t.Fatalf("parse %q: %v", cfg.DSN(), err)
+			}
+
+			password, _ := u.User.Password()
+			if u.User.Username() != tt.user || password != tt.password {
+				t.Errorf("credentials = %q:%q, want %q:%q", u.User.Username(), password, tt.user, tt.password)
+			}
+			if u.Host != "db.internal:5432" || u.Path != "/drm" || u.Query().Get("sslmode") != "require" {
+				t.Errorf("DSN %q lost the host, database or sslmode", cfg.DSN())
+			}
+		})
+	}
+}
+
+func TestDSNPrefersURL(t *testing.T) {
+	cfg := DatabaseConfig{URL: "postgres://u:p@elsewhere/db", Host: "db.internal", Port: "5432"}
+
+	if got := cfg.DSN(); got != cfg.URL {
+		t.Errorf("DSN = %q, want the configured url", got)
+	}
+}
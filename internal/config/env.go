@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type envVar struct {
+	name string
+	set  func(value string) error
+}
+
+func stringVar(name string, dst *string) envVar {
+	return envVar{name, func(value string) error {
+		*dst = value
+		return nil
+	}}
+}
+
+func boolVar(name string, dst *bool) envVar {
+	return envVar{name, func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		*dst = parsed
+		return err
+	}}
+}
+
+func intVar(name string, dst *int64) envVar {
+	return envVar{name, func(value string) error {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		*dst = parsed
+		return err
+	}}
+}
+
+func floatVar(name string, dst *float64) envVar {
+	return envVar{name, func(value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		*dst = parsed
+		return err
+	}}
+}
+
+func durationVar(name string, dst *Duration) envVar {
+	return envVar{name, func(value string) error {
+		parsed, err := time.ParseDuration(value)
+		dst.Duration = parsed
+		return err
+	}}
+}
+
+func (c *Config) envVars() []envVar {
+	return []envVar{
+		stringVar("PORT", &c.Server.Port),
+		durationVar("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout),
+		durationVar("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout),
+		durationVar("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout),
+		durationVar("SERVER_UPLOAD_TIMEOUT", &c.Server.UploadTimeout),
+
+		stringVar("DATABASE_URL", &c.Database.URL),
+		stringVar("DB_HOST", &c.Database.Host),
+		stringVar("DB_PORT", &c.Database.Port),
+		stringVar("DB_DATABASE", &c.Database.Name),
+		stringVar("DB_USERNAME", &c.Database.User),
+		stringVar("DB_PASSWORD", &c.Database.Password),
+		stringVar("DB_SSLMODE", &c.Database.SSLMode),
+
+		stringVar("JWT_SECRET", &c.Auth.JWTSecret),
+
+		stringVar("STORAGE_BACKEND", &c.Storage.Backend),
+		stringVar("MINIO_ENDPOINT", &c.Storage.Endpoint),
+		stringVar("MINIO_HOST", &c.Storage.Host),
+		stringVar("MINIO_API_PORT", &c.Storage.Port),
+		boolVar("MINIO_USE_SSL", &c.Storage.UseSSL),
+		stringVar("MINIO_ACCESS_KEY", &c.Storage.AccessKey),
+		stringVar("MINIO_SECRET_KEY", &c.Storage.SecretKey),
+		stringVar("MINIO_BUCKET_NAME", &c.Storage.Bucket),
+		stringVar("STORAGE_DIR", &c.Storage.Dir),
+		stringVar("STORAGE_GC_MODE", (*string)(&c.Storage.GCMode)),
+		durationVar("STORAGE_GC_GRACE", &c.Storage.GCGrace),
+
+		stringVar("SIMILARITY_BACKEND", &c.Similarity.Backend),
+		stringVar("SIMILARITY_CHECK_URL", &c.Similarity.URL),
+		durationVar("SIMILARITY_TIMEOUT", &c.Similarity.Timeout),
+		durationVar("SIMILARITY_DEADLINE", &c.Similarity.Deadline),
+		stringVar("SIMILARITY_UNAVAILABLE_POLICY", (*string)(&c.Similarity.UnavailablePolicy)),
+		floatVar("SIMILARITY_THRESHOLD", &c.Similarity.Threshold),
+
+		intVar("PLATFORM_FEE_BPS", &c.Revenue.PlatformFeeBps),
+		intVar("TAX_BPS", &c.Revenue.TaxBps),
+	}
+}
+
+// applyEnv overrides the config with every variable that is set and not empty.
+func (c *Config) applyEnv() error {
+	var errs []error
+	for _, v := range c.envVars() {
+		value := os.Getenv(v.name)
+		if value == "" {
+			continue
+		}
+		if err := v.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
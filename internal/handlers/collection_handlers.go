@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type CollectionHandler struct {
+	collectionService services.CollectionService
+}
+
+func NewCollectionHandler(collectionService services.CollectionService) *CollectionHandler {
+	return &CollectionHandler{collectionService: collectionService}
+}
+
+func (h *CollectionHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.Context().Value("id").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var collection models.Collection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		http.Error(w, "Invalid collection data", http.StatusBadRequest)
+		return
+	}
+	collection.CreatorID = id
+	collection.Items = nil
+
+	if err := h.collectionService.Create(&collection); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *CollectionHandler) GetCollection(w http.ResponseWriter, r *http.Request) {
+	collectionId := chi.URLParam(r, "id")
+
+	collection, err := h.collectionService.Get(collectionId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *CollectionHandler) ListCreatorCollections(w http.ResponseWriter, r *http.Request) {
+	creatorId := chi.URLParam(r, "creatorId")
+
+	collections, err := h.collectionService.ListByCreator(creatorId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+func (h *CollectionHandler) ListSelfCollections(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	collections, err := h.collectionService.ListByCreator(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+func (h *CollectionHandler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+
+	var update models.CollectionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid collection data", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.collectionService.Update(id, collectionId, &update)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *CollectionHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+
+	if err := h.collectionService.Delete(id, collectionId); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CollectionHandler) SetCollectionItems(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+
+	var req struct {
+		ContentIDs []string `json:"content_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid collection items", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.collectionService.SetItems(id, collectionId, req.ContentIDs); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CollectionHandler) AddCollectionItem(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+	contentId := chi.URLParam(r, "contentId")
+
+	if err := h.collectionService.AddItem(id, collectionId, contentId); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CollectionHandler) RemoveCollectionItem(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+	contentId := chi.URLParam(r, "contentId")
+
+	if err := h.collectionService.RemoveItem(id, collectionId, contentId); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
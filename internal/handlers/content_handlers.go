@@ -1,15 +1,22 @@
 package handlers
 
 import (
-	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
 	"github.com/go-chi/chi"
 	"github.com/gofrs/uuid"
 )
@@ -18,11 +25,16 @@ type ContentHandler struct {
 	contentService    services.ContentService
 	licenseService    services.LicenseService
 	sessionKeyService services.SessionKeyService
+	watermarkService  services.WatermarkService
+	previewService    services.PreviewService
+	quotaService      services.QuotaService
 }
 
 func NewContentHandler(contentService services.ContentService, licenseService services.LicenseService,
-	sessionKeyService services.SessionKeyService) *ContentHandler {
-	return &ContentHandler{contentService: contentService, licenseService: licenseService, sessionKeyService: sessionKeyService}
+	sessionKeyService services.SessionKeyService, watermarkService services.WatermarkService,
+	previewService services.PreviewService, quotaService services.QuotaService) *ContentHandler {
+	return &ContentHandler{contentService: contentService, licenseService: licenseService, sessionKeyService: sessionKeyService,
+		watermarkService: watermarkService, previewService: previewService, quotaService: quotaService}
 }
 
 func (h *ContentHandler) CreateContent(w http.ResponseWriter, r *http.Request) {
@@ -31,9 +43,13 @@ func (h *ContentHandler) CreateContent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 
-	err = r.ParseMultipartForm(10 << 20) // 10 MB limit
+	limit, err := h.quotaService.UploadLimit(id.String(), 0, true)
 	if err != nil {
-		http.Error(w, "Unable to parse form", http.StatusBadRequest)
+		writeError(w, err)
+		return
+	}
+
+	if !parseUpload(w, r, limit) {
 		return
 	}
 
@@ -63,9 +79,9 @@ func (h *ContentHandler) CreateContent(w http.ResponseWriter, r *http.Request) {
 
 	fileExtension := filepath.Ext(header.Filename)
 
-	similarId, created, similarity, err := h.contentService.Create(&content, file, fileExtension, header.Size)
+	check, created, err := h.contentService.Create(&content, file, fileExtension, header.Size)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -76,132 +92,542 @@ func (h *ContentHandler) CreateContent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	match := newSimilarityMatch(content.ContentID.String(), check)
 	json.NewEncoder(w).Encode(struct {
-		Created    bool    `json:"created"`
-		SimilarID  string  `json:"similar_id"`
-		Similarity float64 `json:"similarity"`
+		Created          bool   `json:"created"`
+		ContentID        string `json:"content_id,omitempty"`
+		SimilarityStatus string `json:"similarity_status,omitempty"`
+		similarityMatch
 	}{
-		Created:    created,
-		SimilarID:  similarId,
-		Similarity: similarity,
+		Created:          created,
+		ContentID:        contentIdIfStored(&content),
+		SimilarityStatus: content.Similarity,
+		similarityMatch:  match,
 	})
 }
 
+type similarityMatch struct {
+	SimilarID  string  `json:"similar_id"`
+	Similarity float64 `json:"similarity"`
+	Exempt     bool    `json:"exempt,omitempty"`
+	ReplaceURL string  `json:"replace_url,omitempty"`
+}
+
+// newSimilarityMatch describes the closest match for an upload. When it is another of the creator's own uploads, the
+// response points at replacing that file instead of keeping both.
+func newSimilarityMatch(contentId string, check *models.SimilarityCheck) similarityMatch {
+	var match similarityMatch
+	if check == nil {
+		return match
+	}
+
+	match.Similarity = check.Score
+	match.Exempt = check.Exempt
+	if check.MatchedID != nil {
+		match.SimilarID = *check.MatchedID
+	}
+	if check.Exempt && match.SimilarID != contentId {
+		match.ReplaceURL = fmt.Sprintf("/content/update/%s/file", match.SimilarID)
+	}
+
+	return match
+}
+
+type contentListItem struct {
+	Id           uuid.UUID   `json:"content_id"`
+	Title        string      `json:"title"`
+	Description  string      `json:"description"`
+	Price        money.Money `json:"price"`
+	Purchased    *bool       `json:"purchased,omitempty"`
+	ThumbnailURL string      `json:"thumbnail_url,omitempty"`
+	PreviewURL   string      `json:"preview_url,omitempty"`
+}
+
+type previewURLs struct {
+	thumbnail string
+	trailer   string
+}
+
+func (h *ContentHandler) previewURLs(contents []*models.Content) (map[string]previewURLs, error) {
+	contentIds := make([]string, len(contents))
+	for i, content := range contents {
+		contentIds[i] = content.ContentID.String()
+	}
+
+	kinds, err := h.previewService.GetKinds(contentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[string]previewURLs, len(kinds))
+	for contentId, available := range kinds {
+		var u previewURLs
+		for _, kind := range available {
+			switch kind {
+			case models.PreviewThumbnail:
+				u.thumbnail = previewURL(contentId, kind)
+			case models.PreviewTrailer:
+				u.trailer = previewURL(contentId, kind)
+			}
+		}
+		urls[contentId] = u
+	}
+
+	return urls, nil
+}
+
+func previewURL(contentId, kind string) string {
+	return fmt.Sprintf("/preview/%s/%s", contentId, kind)
+}
+
+type contentListResponse struct {
+	Contents   []contentListItem `json:"contents"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
 func (h *ContentHandler) ListContent(w http.ResponseWriter, r *http.Request) {
 	id := r.Context().Value("id").(string)
-	contents, err := h.contentService.List()
+
+	filter, err := parseContentFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.UserID = id
+
+	contents, nextCursor, err := h.contentService.List(filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	contentIds := make([]string, len(contents))
+	for i, content := range contents {
+		contentIds[i] = content.ContentID.String()
+	}
+
+	purchased, err := h.licenseService.VerifyBatch(id, contentIds)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	filteredContents := make([]struct {
-		Id          uuid.UUID `json:"content_id"`
-		Title       string    `json:"title"`
-		Description string    `json:"description"`
-		Price       float64   `json:"price"`
-		Purchased   bool      `json:"purchased"`
-	}, len(contents))
+	previews, err := h.previewURLs(contents)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	resp := contentListResponse{Contents: make([]contentListItem, len(contents)), NextCursor: nextCursor}
 	for i, content := range contents {
-		filteredContents[i].Id = content.ContentID
-		filteredContents[i].Title = content.Title
-		filteredContents[i].Description = content.Description
-		filteredContents[i].Price = content.Price
-		isPurchased := h.licenseService.Verify(id, content.ContentID.String())
+		isPurchased := purchased[content.ContentID.String()]
 		if content.CreatorID.String() == id {
 			isPurchased = true
 		}
-		filteredContents[i].Purchased = isPurchased
+		urls := previews[content.ContentID.String()]
+		resp.Contents[i] = contentListItem{
+			Id:           content.ContentID,
+			Title:        content.Title,
+			Description:  content.Description,
+			Price:        content.Price,
+			Purchased:    &isPurchased,
+			ThumbnailURL: urls.thumbnail,
+			PreviewURL:   urls.trailer,
+		}
 	}
 
-	json.NewEncoder(w).Encode(filteredContents)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *ContentHandler) ListSelfContent(w http.ResponseWriter, r *http.Request) {
 	id := r.Context().Value("id").(string)
-	contents, err := h.contentService.List()
+
+	filter, err := parseContentFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.UserID = id
+	filter.CreatorID = id
+
+	contents, nextCursor, err := h.contentService.List(filter)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	filteredContents := make([]struct {
-		Id          uuid.UUID `json:"content_id"`
-		Title       string    `json:"title"`
-		Description string    `json:"description"`
-		Price       float64   `json:"price"`
-	}, len(contents))
+	previews, err := h.previewURLs(contents)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	resp := contentListResponse{Contents: make([]contentListItem, len(contents)), NextCursor: nextCursor}
 	for i, content := range contents {
-		if id == content.CreatorID.String() {
-			filteredContents[i].Id = content.ContentID
-			filteredContents[i].Title = content.Title
-			filteredContents[i].Description = content.Description
-			filteredContents[i].Price = content.Price
+		urls := previews[content.ContentID.String()]
+		resp.Contents[i] = contentListItem{
+			Id:           content.ContentID,
+			Title:        content.Title,
+			Description:  content.Description,
+			Price:        content.Price,
+			ThumbnailURL: urls.thumbnail,
+			PreviewURL:   urls.trailer,
 		}
 	}
 
-	json.NewEncoder(w).Encode(filteredContents)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
-func (h *ContentHandler) PurchaseContent(w http.ResponseWriter, r *http.Request) {
-	id := r.Context().Value("id").(string)
+func parseContentFilter(r *http.Request) (*models.ContentFilter, error) {
+	query := r.URL.Query()
+	filter := &models.ContentFilter{
+		CreatorID: query.Get("creator_id"),
+		Query:     query.Get("q"),
+		Sort:      query.Get("sort"),
+		Order:     query.Get("order"),
+		Cursor:    query.Get("cursor"),
+		Tag:       strings.ToLower(query.Get("tag")),
+		Category:  query.Get("category"),
+	}
+
+	if v := query.Get("collection_id"); v != "" {
+		if _, err := uuid.FromString(v); err != nil {
+			return nil, errors.New("invalid collection_id")
+		}
+		filter.CollectionID = v
+	}
+
+	if filter.CreatorID != "" {
+		if _, err := uuid.FromString(filter.CreatorID); err != nil {
+			return nil, errors.New("invalid creator_id")
+		}
+	}
+
+	if v := query.Get("min_price"); v != "" {
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid min_price")
+		}
+		filter.MinPrice = &price
+	}
+
+	if v := query.Get("max_price"); v != "" {
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid max_price")
+		}
+		filter.MaxPrice = &price
+	}
+
+	filter.Currency = query.Get("currency")
+
+	if v := query.Get("purchased"); v != "" {
+		purchased, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("invalid purchased")
+		}
+		filter.Purchased = &purchased
+	}
+
+	if v := query.Get("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.New("invalid created_after")
+		}
+		filter.CreatedAfter = &t
+	}
+
+	if v := query.Get("created_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.New("invalid created_before")
+		}
+		filter.CreatedBefore = &t
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func (h *ContentHandler) GetContentData(w http.ResponseWriter, r *http.Request) {
 	contentId := chi.URLParam(r, "id")
 
-	err := h.licenseService.Generate(id, contentId, time.Now().Add(time.Hour*24))
+	content, err := h.contentService.GetMetadata(contentId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	previews, err := h.previewURLs([]*models.Content{content})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	urls := previews[content.ContentID.String()]
 
-	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		ContentId    string   `json:"content_id"`
+		Title        string   `json:"title"`
+		Description  string   `json:"description"`
+		Tags         []string `json:"tags"`
+		Categories   []string `json:"categories"`
+		MimeType     string   `json:"mime_type"`
+		SHA256       string   `json:"sha256,omitempty"`
+		ThumbnailURL string   `json:"thumbnail_url,omitempty"`
+		PreviewURL   string   `json:"preview_url,omitempty"`
+	}{
+		ContentId:    content.ContentID.String(),
+		Title:        content.Title,
+		Description:  content.Description,
+		Tags:         content.Tags,
+		Categories:   content.Categories,
+		MimeType:     content.MimeType,
+		SHA256:       content.SHA256,
+		ThumbnailURL: urls.thumbnail,
+		PreviewURL:   urls.trailer,
+	})
 }
 
-func (h *ContentHandler) GetContentData(w http.ResponseWriter, r *http.Request) {
+func (h *ContentHandler) GetContent(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
 	contentId := chi.URLParam(r, "id")
 
-	content, _, err := h.contentService.Get(contentId)
+	content, err := h.contentService.GetMetadata(contentId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	isCreator := content.CreatorID.String() == id
+	if !isCreator && !content.Listed() {
+		writeError(w, services.ErrContentUnderReview)
+		return
+	}
+	if !isCreator && !h.licenseService.Verify(id, contentId) {
+		http.Error(w, "Invalid license", http.StatusForbidden)
+		return
+	}
+
+	if !isCreator && startsPlayback(r) {
+		if err = h.sessionKeyService.RecordPlay(id, contentId); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	if !isCreator {
+		stream, ok, err := h.watermarkService.Open(content, id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if ok {
+			defer stream.Close()
+
+			w.Header().Set("Content-Type", "video/mp2t")
+			w.Header().Set("Content-Disposition", "inline; filename=\"video.ts\"")
+			http.ServeContent(w, r, "video.ts", content.UpdatedAt, stream)
+			return
+		}
+	}
+
+	content, file, err := h.contentService.Get(contentId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer file.Close()
+
+	fileType := mediatype.Lookup(content.MimeType)
+	filename := "content" + fileType.Extension
+	disposition := "attachment"
+	if fileType.Inline() {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", fileType.MIME)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	setChecksumHeaders(w, content.SHA256)
+
+	http.ServeContent(w, r, filename, content.UpdatedAt, file)
+}
+
+// startsPlayback reports whether the request reads from the start of the file, so a player seeking through it with
+// range requests is only counted once.
+func startsPlayback(r *http.Request) bool {
+	rangeHeader := r.Header.Get("Range")
+	return rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-")
+}
+
+func (h *ContentHandler) UpdateContent(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	var update models.ContentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid content data", http.StatusBadRequest)
+		return
+	}
+
+	content, err := h.contentService.Update(id, contentId, &update)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
+
+func (h *ContentHandler) ReplaceContentFile(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	// The file being replaced is freed by the upload, so it does not count against the limit.
+	var replacing int64
+	if content, err := h.contentService.GetMetadata(contentId); err == nil && content.CreatorID.String() == id {
+		replacing = content.FileSize
+	}
+
+	limit, err := h.quotaService.UploadLimit(id, replacing, false)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !parseUpload(w, r, limit) {
+		return
+	}
+
+	file, header, err := r.FormFile("content")
+	if err != nil {
+		http.Error(w, "Unable to get file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	fileExtension := filepath.Ext(header.Filename)
+
+	check, replaced, err := h.contentService.ReplaceFile(id, contentId, file, fileExtension, header.Size)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if replaced {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusConflict)
+	}
+
 	json.NewEncoder(w).Encode(struct {
-		ContentId   string `json:"content_id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
+		Replaced bool `json:"replaced"`
+		similarityMatch
 	}{
-		ContentId:   content.ContentID.String(),
-		Title:       content.Title,
-		Description: content.Description,
+		Replaced:        replaced,
+		similarityMatch: newSimilarityMatch(contentId, check),
 	})
 }
 
-func (h *ContentHandler) GetContent(w http.ResponseWriter, r *http.Request) {
+func (h *ContentHandler) DeleteContent(w http.ResponseWriter, r *http.Request) {
 	id := r.Context().Value("id").(string)
 	contentId := chi.URLParam(r, "id")
 
-	content, fileContent, err := h.contentService.Get(contentId)
+	err := h.contentService.Delete(id, contentId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ContentHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.contentService.ListCategories()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	isValidLicense := h.licenseService.Verify(id, contentId)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
 
-	if content.CreatorID.String() == id {
-		isValidLicense = true
+func (h *ContentHandler) SetCategoryThreshold(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	var req struct {
+		Threshold *float64 `json:"threshold"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	if !isValidLicense {
-		http.Error(w, "Invalid license", http.StatusForbidden)
+	category, err := h.contentService.SetCategoryThreshold(slug, req.Threshold)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "video/mp4")
-	w.Header().Set("Content-Disposition", "inline; filename=\"video.mp4\"")
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(fileContent)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+func (h *ContentHandler) ListLicenses(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	licenses, err := h.licenseService.ListByUser(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(licenses)
+}
+
+// setChecksumHeaders exposes the stored checksum as a strong ETag, which also lets ServeContent answer conditional
+// and If-Range requests, and as a Digest header clients can verify a full download against.
+func setChecksumHeaders(w http.ResponseWriter, sha256 string) {
+	sum, err := hex.DecodeString(sha256)
+	if err != nil || len(sum) == 0 {
+		return
+	}
+
+	w.Header().Set("ETag", `"`+sha256+`"`)
+	w.Header().Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(sum))
+}
+
+func (h *ContentHandler) ListCorruptContent(w http.ResponseWriter, r *http.Request) {
+	contents, err := h.contentService.ListCorrupt()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contents)
+}
+
+// contentIdIfStored returns the id of an upload that was saved, including one rejected as a duplicate so the creator
+// can dispute it.
+func contentIdIfStored(content *models.Content) string {
+	if content.FileID == "" {
+		return ""
+	}
 
-	http.ServeContent(w, r, "video.mp4", content.UpdatedAt, bytes.NewReader(fileContent))
+	return content.ContentID.String()
 }
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/auth"
+	"github.com/go-chi/chi"
+)
+
+type DisputeHandler struct {
+	disputeService services.DisputeService
+}
+
+func NewDisputeHandler(disputeService services.DisputeService) *DisputeHandler {
+	return &DisputeHandler{disputeService: disputeService}
+}
+
+func (h *DisputeHandler) ListChecks(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	checks, err := h.disputeService.ListChecks(id, contentId, auth.IsAdmin(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(checks)
+}
+
+func (h *DisputeHandler) OpenDispute(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid dispute data", http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := h.disputeService.Open(id, contentId, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+func (h *DisputeHandler) ListSelfDisputes(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	disputes, err := h.disputeService.ListByCreator(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disputes)
+}
+
+func (h *DisputeHandler) ListOpenDisputes(w http.ResponseWriter, r *http.Request) {
+	disputes, err := h.disputeService.ListOpen()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disputes)
+}
+
+func decodeNote(r *http.Request) (string, error) {
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return req.Note, nil
+}
+
+func (h *DisputeHandler) ApproveDispute(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	disputeId := chi.URLParam(r, "id")
+
+	note, err := decodeNote(r)
+	if err != nil {
+		http.Error(w, "Invalid dispute data", http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := h.disputeService.Approve(id, disputeId, note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dispute)
+}
+
+func (h *DisputeHandler) DenyDispute(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	disputeId := chi.URLParam(r, "id")
+
+	note, err := decodeNote(r)
+	if err != nil {
+		http.Error(w, "Invalid dispute data", http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := h.disputeService.Deny(id, disputeId, note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dispute)
+}
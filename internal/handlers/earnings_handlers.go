@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+)
+
+type EarningsHandler struct {
+	ledgerService services.LedgerService
+}
+
+func NewEarningsHandler(ledgerService services.LedgerService) *EarningsHandler {
+	return &EarningsHandler{ledgerService: ledgerService}
+}
+
+func (h *EarningsHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	balances, err := h.ledgerService.Balance(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balances)
+}
+
+func (h *EarningsHandler) ListSales(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	sales, err := h.ledgerService.Sales(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sales)
+}
+
+func (h *EarningsHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	month := time.Now().UTC()
+	if v := r.URL.Query().Get("month"); v != "" {
+		parsed, err := time.Parse("2006-01", v)
+		if err != nil {
+			http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	lines, err := h.ledgerService.Statement(id, month)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=statement-%s.csv", month.Format("2006-01")))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"date", "kind", "transaction_id", "order_id", "title", "currency", "gross", "platform_fee",
+		"tax", "net"})
+	for _, line := range lines {
+		orderId := ""
+		if line.OrderID != nil {
+			orderId = line.OrderID.String()
+		}
+
+		writer.Write([]string{
+			line.CreatedAt.Format(time.RFC3339),
+			line.Kind,
+			line.TransactionID.String(),
+			orderId,
+			line.Title,
+			line.Net.Currency,
+			strconv.FormatInt(line.Gross.Amount, 10),
+			strconv.FormatInt(line.PlatformFee.Amount, 10),
+			strconv.FormatInt(line.Tax.Amount, 10),
+			strconv.FormatInt(line.Net.Amount, 10),
+		})
+	}
+	writer.Flush()
+}
+
+func (h *EarningsHandler) ListSelfPayouts(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	payouts, err := h.ledgerService.ListPayouts(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payouts)
+}
+
+func (h *EarningsHandler) RunPayouts(w http.ResponseWriter, r *http.Request) {
+	payouts, err := h.ledgerService.RunPayouts()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payouts)
+}
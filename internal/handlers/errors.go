@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/packager"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/payment"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/similarity"
+)
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrContentNotFound), errors.Is(err, services.ErrCollectionNotFound),
+		errors.Is(err, services.ErrPlanNotFound), errors.Is(err, services.ErrSubscriptionNotFound),
+		errors.Is(err, services.ErrLicenseNotFound), errors.Is(err, services.ErrRecipientNotFound),
+		errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrRefundNotFound),
+		errors.Is(err, services.ErrWatermarkNotFound), errors.Is(err, services.ErrRenditionNotFound),
+		errors.Is(err, services.ErrPreviewNotFound), errors.Is(err, services.ErrDisputeNotFound),
+		errors.Is(err, services.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidFilter), errors.Is(err, services.ErrUnknownCategory),
+		errors.Is(err, services.ErrInvalidThreshold), errors.Is(err, services.ErrUnknownTier),
+		errors.Is(err, services.ErrInvalidGiftCode), errors.Is(err, services.ErrInvalidCoupon),
+		errors.Is(err, services.ErrPriceUnavailable), errors.Is(err, money.ErrInvalidCurrency),
+		errors.Is(err, packager.ErrInvalidPlaylist), errors.Is(err, services.ErrMissingSegment),
+		errors.Is(err, services.ErrMissingReason), errors.Is(err, services.ErrInvalidTag):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrCollectionNotForSale), errors.Is(err, services.ErrCouponExhausted),
+		errors.Is(err, services.ErrCouponCodeTaken), errors.Is(err, services.ErrAlreadyRefunded),
+		errors.Is(err, services.ErrRefundInProgress), errors.Is(err, services.ErrNotRefundable),
+		errors.Is(err, services.ErrContentUnderReview), errors.Is(err, services.ErrNotDisputable),
+		errors.Is(err, services.ErrDisputeOpen), errors.Is(err, services.ErrDisputeClosed),
+		errors.Is(err, services.ErrAlreadyOwned), errors.Is(err, services.ErrAlreadySubscribed),
+		errors.Is(err, services.ErrNotWatermarked):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrNotCreator), errors.Is(err, services.ErrForbidden),
+		errors.Is(err, services.ErrNotTransferable), errors.Is(err, services.ErrInvalidSession),
+		errors.Is(err, services.ErrItemLimitReached):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrQuotaExceeded), errors.Is(err, services.ErrFileTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, payment.ErrPaymentFailed):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, similarity.ErrUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, similarity.ErrRejected):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	case errors.Is(err, mediatype.ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
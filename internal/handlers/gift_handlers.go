@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/go-chi/chi"
+)
+
+type GiftHandler struct {
+	giftService services.GiftService
+}
+
+func NewGiftHandler(giftService services.GiftService) *GiftHandler {
+	return &GiftHandler{giftService: giftService}
+}
+
+func (h *GiftHandler) RedeemGift(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Missing gift code", http.StatusBadRequest)
+		return
+	}
+
+	gift, err := h.giftService.Redeem(id, strings.ToUpper(strings.TrimSpace(req.Code)), time.Now().Add(time.Hour*24))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		ContentID string `json:"content_id"`
+	}{
+		ContentID: gift.ContentID.String(),
+	})
+}
+
+func (h *GiftHandler) ListGifts(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	gifts, err := h.giftService.ListByPurchaser(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gifts)
+}
+
+func (h *GiftHandler) TransferLicense(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	licenseId := chi.URLParam(r, "id")
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Missing recipient email", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.giftService.Transfer(id, licenseId, req.Email); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/go-chi/chi"
+)
+
+type PackagingHandler struct {
+	contentService   services.ContentService
+	licenseService   services.LicenseService
+	packagingService services.PackagingService
+	quotaService     services.QuotaService
+}
+
+func NewPackagingHandler(contentService services.ContentService, licenseService services.LicenseService,
+	packagingService services.PackagingService, quotaService services.QuotaService) *PackagingHandler {
+	return &PackagingHandler{contentService: contentService, licenseService: licenseService, packagingService: packagingService,
+		quotaService: quotaService}
+}
+
+func (h *PackagingHandler) authorize(w http.ResponseWriter, r *http.Request) (*models.Content, bool) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	content, err := h.contentService.GetMetadata(contentId)
+	if err != nil {
+		writeError(w, err)
+		return nil, false
+	}
+
+	isCreator := content.CreatorID.String() == id
+	if !isCreator && !content.Listed() {
+		writeError(w, services.ErrContentUnderReview)
+		return nil, false
+	}
+	if !isCreator && !h.licenseService.Verify(id, contentId) {
+		http.Error(w, "Invalid license", http.StatusForbidden)
+		return nil, false
+	}
+
+	return content, true
+}
+
+func (h *PackagingHandler) GetManifest(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	content, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	manifest, err := h.packagingService.Manifest(content, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(manifest)
+}
+
+func (h *PackagingHandler) GetInit(w http.ResponseWriter, r *http.Request) {
+	content, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.Context().Value("id").(string)
+
+	data, err := h.packagingService.Init(content, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.Write(data)
+}
+
+func (h *PackagingHandler) GetSegment(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "n"))
+	if err != nil {
+		http.Error(w, "Invalid segment", http.StatusBadRequest)
+		return
+	}
+
+	content, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.Context().Value("id").(string)
+
+	data, err := h.packagingService.Segment(content, id, index)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/iso.segment")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.Write(data)
+}
+
+func (h *PackagingHandler) GetKey(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	content, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.packagingService.Key(id, content.ContentID.String(), r.URL.Query().Get("session"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(key)
+}
+
+func (h *PackagingHandler) ImportSegments(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	// The joined segments replace the content's file, so they count against the quota like any replacement.
+	var replacing int64
+	if content, err := h.contentService.GetMetadata(contentId); err == nil && content.CreatorID.String() == id {
+		replacing = content.FileSize
+	}
+
+	limit, err := h.quotaService.UploadLimit(id, replacing, false)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !parseUpload(w, r, limit) {
+		return
+	}
+
+	playlist := r.FormValue("playlist")
+	if playlist == "" {
+		http.Error(w, "Missing playlist", http.StatusBadRequest)
+		return
+	}
+
+	files := make(map[string][]byte)
+	for _, header := range r.MultipartForm.File["segments"] {
+		file, err := header.Open()
+		if err != nil {
+			http.Error(w, "Unable to get file", http.StatusBadRequest)
+			return
+		}
+
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			http.Error(w, "Unable to get file", http.StatusBadRequest)
+			return
+		}
+
+		files[filepath.Base(header.Filename)] = data
+	}
+
+	rendition, check, err := h.packagingService.Import(id, contentId, []byte(playlist), files)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if rendition == nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(newSimilarityMatch(contentId, check))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rendition)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+	"github.com/go-chi/chi"
+)
+
+type PreviewHandler struct {
+	previewService services.PreviewService
+	quotaService   services.QuotaService
+}
+
+func NewPreviewHandler(previewService services.PreviewService, quotaService services.QuotaService) *PreviewHandler {
+	return &PreviewHandler{previewService: previewService, quotaService: quotaService}
+}
+
+func (h *PreviewHandler) GetPreview(w http.ResponseWriter, r *http.Request) {
+	contentId := chi.URLParam(r, "id")
+	kind := chi.URLParam(r, "kind")
+
+	preview, data, err := h.previewService.Get(contentId, kind)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	filename := kind + mediatype.Lookup(preview.MimeType).Extension
+
+	w.Header().Set("Content-Type", preview.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(w, r, filename, preview.CreatedAt, bytes.NewReader(data))
+}
+
+func (h *PreviewHandler) UploadPreview(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+	kind := chi.URLParam(r, "kind")
+
+	limit, err := h.quotaService.FileSizeLimit(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if !parseUpload(w, r, limit) {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Unable to get file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	preview, err := h.previewService.Upload(id, contentId, kind, file, filepath.Ext(header.Filename))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/auth"
+	"github.com/gofrs/uuid"
+)
+
+type PricingHandler struct {
+	pricingService services.PricingService
+}
+
+func NewPricingHandler(pricingService services.PricingService) *PricingHandler {
+	return &PricingHandler{pricingService: pricingService}
+}
+
+func (h *PricingHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.Context().Value("id").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var coupon models.Coupon
+	if err := json.NewDecoder(r.Body).Decode(&coupon); err != nil {
+		http.Error(w, "Invalid coupon data", http.StatusBadRequest)
+		return
+	}
+	coupon.CreatedBy = id
+
+	if err := h.pricingService.CreateCoupon(&coupon, auth.IsAdmin(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+func (h *PricingHandler) ListSelfCoupons(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	coupons, err := h.pricingService.ListCoupons(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coupons)
+}
+
+func (h *PricingHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	var sale models.Sale
+	if err := json.NewDecoder(r.Body).Decode(&sale); err != nil {
+		http.Error(w, "Invalid sale data", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.pricingService.CreateSale(id, &sale); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sale)
+}
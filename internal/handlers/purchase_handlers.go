@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/go-chi/chi"
+)
+
+type PurchaseHandler struct {
+	purchaseService services.PurchaseService
+}
+
+func NewPurchaseHandler(purchaseService services.PurchaseService) *PurchaseHandler {
+	return &PurchaseHandler{purchaseService: purchaseService}
+}
+
+type purchaseRequest struct {
+	Coupon   string `json:"coupon"`
+	Currency string `json:"currency"`
+}
+
+func decodePurchase(r *http.Request) (*purchaseRequest, error) {
+	var req purchaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func (h *PurchaseHandler) PurchaseContent(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	req, err := decodePurchase(r)
+	if err != nil {
+		http.Error(w, "Invalid purchase data", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.purchaseService.PurchaseContent(id, contentId, req.Currency, req.Coupon, time.Now().Add(time.Hour*24))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *PurchaseHandler) PurchaseCollection(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+
+	req, err := decodePurchase(r)
+	if err != nil {
+		http.Error(w, "Invalid purchase data", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.purchaseService.PurchaseCollection(id, collectionId, req.Currency, req.Coupon,
+		time.Now().Add(time.Hour*24))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *PurchaseHandler) PurchaseGift(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	req, err := decodePurchase(r)
+	if err != nil {
+		http.Error(w, "Invalid purchase data", http.StatusBadRequest)
+		return
+	}
+
+	gift, err := h.purchaseService.PurchaseGift(id, contentId, req.Currency, req.Coupon)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(gift)
+}
+
+func (h *PurchaseHandler) QuoteContent(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	contentId := chi.URLParam(r, "id")
+
+	query := r.URL.Query()
+	quote, err := h.purchaseService.QuoteContent(id, contentId, query.Get("currency"), query.Get("coupon"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+func (h *PurchaseHandler) QuoteCollection(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	collectionId := chi.URLParam(r, "id")
+
+	query := r.URL.Query()
+	quote, err := h.purchaseService.QuoteCollection(id, collectionId, query.Get("currency"), query.Get("coupon"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
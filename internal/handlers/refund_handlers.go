@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/auth"
+	"github.com/go-chi/chi"
+)
+
+type RefundHandler struct {
+	refundService services.RefundService
+}
+
+func NewRefundHandler(refundService services.RefundService) *RefundHandler {
+	return &RefundHandler{refundService: refundService}
+}
+
+func (h *RefundHandler) ListSelfOrders(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	orders, err := h.refundService.ListOrders(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *RefundHandler) RequestRefund(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	orderId := chi.URLParam(r, "id")
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid refund data", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.refundService.Request(id, orderId, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if refund.Status == models.RefundPending {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *RefundHandler) ApproveRefund(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	refundId := chi.URLParam(r, "id")
+
+	refund, err := h.refundService.Approve(id, refundId, auth.IsAdmin(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *RefundHandler) RejectRefund(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	refundId := chi.URLParam(r, "id")
+
+	refund, err := h.refundService.Reject(id, refundId, auth.IsAdmin(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *RefundHandler) ListSelfRefunds(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	refunds, err := h.refundService.ListByUser(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
+
+func (h *RefundHandler) ListPendingRefunds(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	refunds, err := h.refundService.ListPending(id, auth.IsAdmin(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/go-chi/chi"
+)
+
+type StorageHandler struct {
+	storageService services.StorageService
+	quotaService   services.QuotaService
+}
+
+func NewStorageHandler(storageService services.StorageService, quotaService services.QuotaService) *StorageHandler {
+	return &StorageHandler{storageService: storageService, quotaService: quotaService}
+}
+
+func (h *StorageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	usage, err := h.quotaService.Usage(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+func (h *StorageHandler) ListTiers(w http.ResponseWriter, r *http.Request) {
+	tiers, err := h.quotaService.ListTiers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tiers)
+}
+
+func (h *StorageHandler) SetUserTier(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "userId")
+
+	var req struct {
+		Tier string `json:"tier"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.quotaService.SetTier(userId, req.Tier); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	usage, err := h.quotaService.Usage(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// GetReport is a dry run of the collector: it lists what would be deleted without deleting anything.
+func (h *StorageHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	h.reconcile(w, false)
+}
+
+func (h *StorageHandler) Collect(w http.ResponseWriter, r *http.Request) {
+	h.reconcile(w, true)
+}
+
+func (h *StorageHandler) reconcile(w http.ResponseWriter, deleteOrphans bool) {
+	report, err := h.storageService.Reconcile(deleteOrphans)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
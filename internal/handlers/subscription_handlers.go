@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/auth"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type SubscriptionHandler struct {
+	subscriptionService services.SubscriptionService
+}
+
+func NewSubscriptionHandler(subscriptionService services.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{subscriptionService: subscriptionService}
+}
+
+func (h *SubscriptionHandler) CreatePlan(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(r.Context().Value("id").(string))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var plan models.Plan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, "Invalid plan data", http.StatusBadRequest)
+		return
+	}
+
+	if plan.Scope == models.PlanScopeCreator {
+		plan.CreatorID = &id
+	}
+
+	if err := h.subscriptionService.CreatePlan(&plan, auth.IsAdmin(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+func (h *SubscriptionHandler) ListPlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := h.subscriptionService.ListPlans(r.URL.Query().Get("creator_id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
+
+func (h *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	planId := chi.URLParam(r, "id")
+
+	subscription, err := h.subscriptionService.Subscribe(id, planId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func (h *SubscriptionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	subscriptionId := chi.URLParam(r, "id")
+
+	subscription, err := h.subscriptionService.Cancel(id, subscriptionId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func (h *SubscriptionHandler) ListSelfSubscriptions(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+
+	subscriptions, err := h.subscriptionService.ListByUser(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
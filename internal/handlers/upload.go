@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/services"
+)
+
+const (
+	// multipartOverhead allows for the form fields and part headers sent alongside the file.
+	multipartOverhead = 1 << 20
+	// multipartMemory is how much of a form is held in memory before the rest spills to temporary files.
+	multipartMemory = 32 << 20
+)
+
+// parseUpload parses a multipart upload whose files may total at most limit bytes, or any size for
+// services.NoLimit. Bodies over the limit are refused as soon as that is known, before the rest is read.
+func parseUpload(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	if limit != services.NoLimit {
+		if r.ContentLength > limit+multipartOverhead {
+			http.Error(w, services.ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	}
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, services.ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "Unable to parse form", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// UploadTimeout replaces the server's read and write deadlines on routes that take file uploads, which need longer to
+// arrive than the rest of the API allows. A zero timeout leaves uploads without a deadline.
+func UploadTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var deadline time.Time
+			if timeout > 0 {
+				deadline = time.Now().Add(timeout)
+			}
+
+			rc := http.NewResponseController(w)
+			if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowUpload sends a body in two halves with a pause between them longer than the server's read timeout.
+func slowUpload(t *testing.T, handler http.Handler) (int, error) {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	body, w := io.Pipe()
+	go func() {
+		w.Write([]byte(strings.Repeat("a", 1024)))
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte(strings.Repeat("b", 1024)))
+		w.Close()
+	}()
+
+	resp, err := http.Post(server.URL, "application/octet-stream", body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil || len(data) != 2048 {
+		http.Error(w, "short body", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestUploadTimeout(t *testing.T) {
+	status, err := slowUpload(t, UploadTimeout(5*time.Second)(http.HandlerFunc(readBody)))
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+}
+
+func TestWithoutUploadTimeout(t *testing.T) {
+	status, err := slowUpload(t, http.HandlerFunc(readBody))
+	if err == nil && status == http.StatusNoContent {
+		t.Errorf("slow upload succeeded under the server read timeout")
+	}
+}
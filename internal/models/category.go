@@ -0,0 +1,10 @@
+package models
+
+import "github.com/gofrs/uuid"
+
+type Category struct {
+	CategoryID          uuid.UUID `json:"category_id"`
+	Slug                string    `json:"slug"`
+	Name                string    `json:"name"`
+	SimilarityThreshold *float64  `json:"similarity_threshold,omitempty"`
+}
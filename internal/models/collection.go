@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+type Collection struct {
+	CollectionID  uuid.UUID         `json:"collection_id"`
+	CreatorID     uuid.UUID         `json:"creator_id"`
+	Title         string            `json:"title"`
+	Description   string            `json:"description"`
+	Price         *money.Money      `json:"price,omitempty"`
+	GrantNewItems bool              `json:"grant_new_items"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Items         []*CollectionItem `json:"items,omitempty"`
+}
+
+type CollectionItem struct {
+	ContentID uuid.UUID `json:"content_id"`
+	Title     string    `json:"title"`
+	Position  int       `json:"position"`
+}
+
+type CollectionUpdate struct {
+	Title         *string      `json:"title"`
+	Description   *string      `json:"description"`
+	Price         *money.Money `json:"price"`
+	GrantNewItems *bool        `json:"grant_new_items"`
+}
+
+type CollectionPurchase struct {
+	PurchaseID   uuid.UUID   `json:"purchase_id"`
+	CollectionID uuid.UUID   `json:"collection_id"`
+	UserID       uuid.UUID   `json:"user_id"`
+	Price        money.Money `json:"price"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
@@ -3,17 +3,73 @@ package models
 import (
 	"time"
 
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
 	"github.com/gofrs/uuid"
 )
 
+// Similarity statuses. Unchecked content was accepted while the similarity service was down and stays listed;
+// flagged content was accepted the same way but is held back until it is checked. Content found to duplicate
+// another on a later check is moved to review, and an upload matched on arrival is stored as rejected.
+const (
+	SimilarityPassed    = "passed"
+	SimilarityUnchecked = "unchecked"
+	SimilarityFlagged   = "flagged"
+	SimilarityReview    = "review"
+	SimilarityRejected  = "rejected"
+)
+
 type Content struct {
-	ContentID   uuid.UUID `json:"content_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CreatorID   uuid.UUID `json:"creator_id"`
-	Price       float64   `json:"price"`
-	FileID      string    `json:"file_id"`
-	FileSize    int64     `json:"file_size"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-}
\ No newline at end of file
+	ContentID    uuid.UUID     `json:"content_id"`
+	Title        string        `json:"title"`
+	Description  string        `json:"description"`
+	CreatorID    uuid.UUID     `json:"creator_id"`
+	Price        money.Money   `json:"price"`
+	Prices       []money.Money `json:"prices,omitempty"`
+	FileID       string        `json:"file_id"`
+	FileSize     int64         `json:"file_size"`
+	MimeType     string        `json:"mime_type"`
+	SHA256       string        `json:"sha256,omitempty"`
+	Corrupt      bool          `json:"corrupt,omitempty"`
+	Similarity   string        `json:"similarity_status"`
+	Transferable bool          `json:"transferable"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	DeletedAt    *time.Time    `json:"deleted_at,omitempty"`
+	PurgedAt     *time.Time    `json:"purged_at,omitempty"`
+	Tags         []string      `json:"tags,omitempty"`
+	Categories   []string      `json:"categories,omitempty"`
+}
+
+// Listed reports whether the content may be shown to and bought by anyone other than its creator.
+func (c *Content) Listed() bool {
+	return c.Similarity == SimilarityPassed || c.Similarity == SimilarityUnchecked
+}
+
+type ContentUpdate struct {
+	Title        *string        `json:"title"`
+	Description  *string        `json:"description"`
+	Price        *money.Money   `json:"price"`
+	Prices       *[]money.Money `json:"prices"`
+	Tags         *[]string      `json:"tags"`
+	Categories   *[]string      `json:"categories"`
+	Transferable *bool          `json:"transferable"`
+}
+
+type ContentFilter struct {
+	UserID        string
+	CreatorID     string
+	Currency      string
+	MinPrice      *int64
+	MaxPrice      *int64
+	Purchased     *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Query         string
+	Tag           string
+	Category      string
+	CollectionID  string
+	Sort          string
+	Order         string
+	Cursor        string
+	Limit         int
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type GiftCode struct {
+	GiftID      uuid.UUID  `json:"gift_id"`
+	Code        string     `json:"code"`
+	ContentID   uuid.UUID  `json:"content_id"`
+	PurchaserID uuid.UUID  `json:"purchaser_id"`
+	OrderID     *uuid.UUID `json:"order_id,omitempty"`
+	RedeemedBy  *uuid.UUID `json:"redeemed_by,omitempty"`
+	RedeemedAt  *time.Time `json:"redeemed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
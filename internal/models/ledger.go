@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	LedgerSale   = "sale"
+	LedgerRefund = "refund"
+	LedgerPayout = "payout"
+
+	AccountCash     = "cash"
+	AccountCreator  = "creator"
+	AccountPlatform = "platform"
+	AccountTax      = "tax"
+)
+
+type RevenueSplit struct {
+	PlatformFeeBps int64 `json:"platform_fee_bps"`
+	TaxBps         int64 `json:"tax_bps"`
+}
+
+// Amounts are signed: debits are positive, credits negative, and every transaction sums to zero.
+type LedgerEntry struct {
+	EntryID       uuid.UUID   `json:"entry_id"`
+	TransactionID uuid.UUID   `json:"transaction_id"`
+	Kind          string      `json:"kind"`
+	Account       string      `json:"account"`
+	CreatorID     *uuid.UUID  `json:"creator_id,omitempty"`
+	OrderID       *uuid.UUID  `json:"order_id,omitempty"`
+	PayoutID      *uuid.UUID  `json:"payout_id,omitempty"`
+	Amount        money.Money `json:"amount"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+type Balance struct {
+	Earned    money.Money `json:"earned"`
+	PaidOut   money.Money `json:"paid_out"`
+	Available money.Money `json:"available"`
+}
+
+type SalesSummary struct {
+	ContentID    *uuid.UUID  `json:"content_id,omitempty"`
+	CollectionID *uuid.UUID  `json:"collection_id,omitempty"`
+	Title        string      `json:"title"`
+	Orders       int         `json:"orders"`
+	Refunds      int         `json:"refunds"`
+	Gross        money.Money `json:"gross"`
+	Earnings     money.Money `json:"earnings"`
+}
+
+type StatementLine struct {
+	TransactionID uuid.UUID   `json:"transaction_id"`
+	Kind          string      `json:"kind"`
+	OrderID       *uuid.UUID  `json:"order_id,omitempty"`
+	Title         string      `json:"title"`
+	Gross         money.Money `json:"gross"`
+	PlatformFee   money.Money `json:"platform_fee"`
+	Tax           money.Money `json:"tax"`
+	Net           money.Money `json:"net"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+type Payout struct {
+	PayoutID  uuid.UUID   `json:"payout_id"`
+	CreatorID uuid.UUID   `json:"creator_id"`
+	Amount    money.Money `json:"amount"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -7,9 +7,10 @@ import (
 )
 
 type License struct {
-	LicenseID uuid.UUID `json:"license_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	ContentID uuid.UUID `json:"content_id"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	LicenseID uuid.UUID  `json:"license_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ContentID uuid.UUID  `json:"content_id"`
+	OrderID   *uuid.UUID `json:"order_id,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type Play struct {
+	PlayID    uuid.UUID `json:"play_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ContentID uuid.UUID `json:"content_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	PreviewThumbnail = "thumbnail"
+	PreviewTrailer   = "trailer"
+)
+
+// Preview is a freely viewable thumbnail or trailer. SourceFileID is set when it was generated from the content
+// file, so it can be regenerated when that file is replaced; uploaded previews leave it nil and are kept as is.
+type Preview struct {
+	ContentID    uuid.UUID `json:"content_id"`
+	Kind         string    `json:"kind"`
+	FileID       string    `json:"-"`
+	MimeType     string    `json:"mime_type"`
+	FileSize     int64     `json:"file_size"`
+	SourceFileID *string   `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	CouponPercent = "percent"
+	CouponFixed   = "fixed"
+
+	OrderPending = "pending"
+	OrderPaid    = "paid"
+	OrderFailed  = "failed"
+)
+
+type Coupon struct {
+	CouponID     uuid.UUID    `json:"coupon_id"`
+	Code         string       `json:"code"`
+	CreatedBy    uuid.UUID    `json:"created_by"`
+	CreatorID    *uuid.UUID   `json:"creator_id,omitempty"`
+	ContentID    *uuid.UUID   `json:"content_id,omitempty"`
+	Kind         string       `json:"kind"`
+	Percent      *int64       `json:"percent,omitempty"`
+	Amount       *money.Money `json:"amount,omitempty"`
+	StartsAt     time.Time    `json:"starts_at"`
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`
+	MaxUses      *int         `json:"max_uses,omitempty"`
+	PerUserLimit *int         `json:"per_user_limit,omitempty"`
+	Uses         int          `json:"uses"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type Sale struct {
+	SaleID    uuid.UUID   `json:"sale_id"`
+	ContentID uuid.UUID   `json:"content_id"`
+	SalePrice money.Money `json:"sale_price"`
+	StartsAt  time.Time   `json:"starts_at"`
+	EndsAt    time.Time   `json:"ends_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type Quote struct {
+	ListPrice money.Money `json:"list_price"`
+	BasePrice money.Money `json:"base_price"`
+	Discount  money.Money `json:"discount"`
+	Price     money.Money `json:"price"`
+	Coupon    *Coupon     `json:"-"`
+}
+
+// Fulfilment is everything an order delivers. It is written in the same transaction as the order itself.
+type Fulfilment struct {
+	Order    *Order
+	Entries  []*LedgerEntry
+	Licenses []*License
+	Purchase *CollectionPurchase
+	Gift     *GiftCode
+}
+
+type Order struct {
+	OrderID uuid.UUID `json:"order_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	// CreatorID is nil on orders placed before creators were recorded whose content or collection is gone.
+	CreatorID    *uuid.UUID  `json:"creator_id,omitempty"`
+	ContentID    *uuid.UUID  `json:"content_id,omitempty"`
+	CollectionID *uuid.UUID  `json:"collection_id,omitempty"`
+	ListPrice    money.Money `json:"list_price"`
+	Discount     money.Money `json:"discount"`
+	Price        money.Money `json:"price"`
+	CouponID     *uuid.UUID  `json:"coupon_id,omitempty"`
+	Status       string      `json:"status"`
+	PaymentID    string      `json:"-"`
+	RefundedAt   *time.Time  `json:"refunded_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
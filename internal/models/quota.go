@@ -0,0 +1,20 @@
+package models
+
+// StorageTier holds a creator's storage limits. A nil limit means unlimited.
+type StorageTier struct {
+	Name        string `json:"name"`
+	MaxBytes    *int64 `json:"max_bytes"`
+	MaxItems    *int   `json:"max_items"`
+	MaxFileSize *int64 `json:"max_file_size"`
+}
+
+// StorageUsage counts the files of content that has not been deleted.
+type StorageUsage struct {
+	Tier           string `json:"tier"`
+	UsedBytes      int64  `json:"used_bytes"`
+	Items          int    `json:"items"`
+	MaxBytes       *int64 `json:"max_bytes"`
+	MaxItems       *int   `json:"max_items"`
+	MaxFileSize    *int64 `json:"max_file_size"`
+	RemainingBytes *int64 `json:"remaining_bytes,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	RefundPending = "pending"
+	// RefundRefunding marks a refund claimed for payout whose money is on its way back to the buyer.
+	RefundRefunding = "refunding"
+	RefundRefunded  = "refunded"
+	RefundRejected  = "rejected"
+)
+
+type Refund struct {
+	RefundID  uuid.UUID  `json:"refund_id"`
+	OrderID   uuid.UUID  `json:"order_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	CreatorID uuid.UUID  `json:"creator_id"`
+	Reason    string     `json:"reason"`
+	Status    string     `json:"status"`
+	DecidedBy *uuid.UUID `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Reversal is everything a refund takes back. It is written in the same transaction that marks the order refunded.
+type Reversal struct {
+	Refund *Refund
+	Order  *Order
+	// ContentIDs lose their session keys unless the buyer still holds them some other way.
+	ContentIDs []string
+	Entries    []*LedgerEntry
+}
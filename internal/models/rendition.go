@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	FormatHLS = "hls"
+
+	EncryptionAES128 = "AES-128"
+)
+
+type Rendition struct {
+	ContentID      uuid.UUID `json:"content_id"`
+	SourceFileID   string    `json:"source_file_id"`
+	Format         string    `json:"format"`
+	Method         string    `json:"method"`
+	Key            []byte    `json:"-"`
+	InitFileID     *string   `json:"-"`
+	TargetDuration int       `json:"target_duration"`
+	// Watermarked renditions hold an A and a B variant of every segment; others only the A variant.
+	Watermarked bool                `json:"watermarked"`
+	Segments    []*RenditionSegment `json:"segments,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+type RenditionSegment struct {
+	Index    int     `json:"index"`
+	Variant  string  `json:"-"`
+	FileID   string  `json:"-"`
+	Duration float64 `json:"duration"`
+	FileSize int64   `json:"file_size"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type SimilarityCheck struct {
+	CheckID      uuid.UUID `json:"check_id"`
+	ContentID    uuid.UUID `json:"content_id"`
+	FileID       *string   `json:"-"`
+	MatchedID    *string   `json:"matched_id,omitempty"`
+	Score        float64   `json:"score"`
+	Similar      bool      `json:"similar"`
+	Exempt       bool      `json:"exempt,omitempty"`
+	ModelVersion string    `json:"model_version,omitempty"`
+	Threshold    *float64  `json:"threshold,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+const (
+	DisputeOpen     = "open"
+	DisputeApproved = "approved"
+	DisputeDenied   = "denied"
+)
+
+// Dispute is a creator's claim that a similarity match on their content is a false positive.
+type Dispute struct {
+	DisputeID uuid.UUID  `json:"dispute_id"`
+	ContentID uuid.UUID  `json:"content_id"`
+	CheckID   *uuid.UUID `json:"check_id,omitempty"`
+	CreatorID uuid.UUID  `json:"creator_id"`
+	Reason    string     `json:"reason"`
+	Status    string     `json:"status"`
+	Note      string     `json:"note,omitempty"`
+	DecidedBy *uuid.UUID `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
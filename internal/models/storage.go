@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ObjectReference is a row pointing at a stored object. References that are not required only keep their object
+// from being collected, and are not reported when the object is missing.
+type ObjectReference struct {
+	Source   string
+	OwnerID  string
+	FileID   string
+	Required bool
+}
+
+type OrphanObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	Deleted      bool      `json:"deleted"`
+}
+
+type MissingObject struct {
+	Source  string `json:"source"`
+	OwnerID string `json:"owner_id"`
+	FileID  string `json:"file_id"`
+}
+
+type StorageReport struct {
+	DryRun      bool            `json:"dry_run"`
+	GracePeriod string          `json:"grace_period"`
+	Scanned     int             `json:"scanned"`
+	InGrace     int             `json:"in_grace"`
+	Orphans     []OrphanObject  `json:"orphans"`
+	OrphanBytes int64           `json:"orphan_bytes"`
+	Deleted     int             `json:"deleted"`
+	Missing     []MissingObject `json:"missing"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	PlanScopeCreator  = "creator"
+	PlanScopePlatform = "platform"
+
+	SubscriptionActive   = "active"
+	SubscriptionPastDue  = "past_due"
+	SubscriptionCanceled = "canceled"
+	SubscriptionExpired  = "expired"
+)
+
+type Plan struct {
+	PlanID     uuid.UUID   `json:"plan_id"`
+	Name       string      `json:"name"`
+	Scope      string      `json:"scope"`
+	CreatorID  *uuid.UUID  `json:"creator_id,omitempty"`
+	Price      money.Money `json:"price"`
+	PeriodDays int         `json:"period_days"`
+	GraceDays  int         `json:"grace_days"`
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type Subscription struct {
+	SubscriptionID     uuid.UUID  `json:"subscription_id"`
+	PlanID             uuid.UUID  `json:"plan_id"`
+	UserID             uuid.UUID  `json:"user_id"`
+	Status             string     `json:"status"`
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `json:"current_period_end"`
+	CancelAtPeriodEnd  bool       `json:"cancel_at_period_end"`
+	CanceledAt         *time.Time `json:"canceled_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
@@ -7,4 +7,10 @@ type User struct {
 	Email    string    `json:"email"`
 	UserName string    `json:"user_name"`
 	Password string    `json:"password"`
+	Role     string    `json:"role"`
 }
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
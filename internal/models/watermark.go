@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	VariantA = "A"
+	VariantB = "B"
+)
+
+type WatermarkSegment struct {
+	ContentID    uuid.UUID `json:"content_id"`
+	SourceFileID string    `json:"source_file_id"`
+	Index        int       `json:"index"`
+	Variant      string    `json:"variant"`
+	FileID       string    `json:"file_id"`
+	FileSize     int64     `json:"file_size"`
+}
+
+// Watermark ties the payload embedded in a served stream to the viewer and license it was served under.
+type Watermark struct {
+	Payload   uint64     `json:"payload,string"`
+	ContentID uuid.UUID  `json:"content_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	LicenseID *uuid.UUID `json:"license_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type WatermarkMatch struct {
+	Watermark  *Watermark `json:"watermark"`
+	Method     string     `json:"method"`
+	Segments   int        `json:"segments"`
+	KnownBits  int        `json:"known_bits"`
+	Confidence float64    `json:"confidence"`
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type CategoryRepository interface {
+	GetAll() ([]*models.Category, error)
+	SetThreshold(slug string, threshold *float64) (*models.Category, error)
+}
+
+type categoryRepo struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepo{db: db}
+}
+
+func (r *categoryRepo) GetAll() ([]*models.Category, error) {
+	query := "SELECT id, slug, name, similarity_threshold FROM categories ORDER BY name"
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		var category models.Category
+		err := rows.Scan(&category.CategoryID, &category.Slug, &category.Name, &category.SimilarityThreshold)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *categoryRepo) SetThreshold(slug string, threshold *float64) (*models.Category, error) {
+	query := `UPDATE categories SET similarity_threshold = $2 WHERE slug = $1
+              RETURNING id, slug, name, similarity_threshold`
+
+	var category models.Category
+	err := r.db.QueryRow(query, slug, threshold).Scan(&category.CategoryID, &category.Slug, &category.Name,
+		&category.SimilarityThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
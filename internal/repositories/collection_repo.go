@@ -0,0 +1,234 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type CollectionRepository interface {
+	Create(collection *models.Collection) error
+	GetById(id string) (*models.Collection, error)
+	GetByCreator(creatorId string) ([]*models.Collection, error)
+	Update(collection *models.Collection) error
+	Delete(id string) error
+	GetItems(id string) ([]*models.CollectionItem, error)
+	SetItems(id string, contentIds []string) error
+	AddItem(id, contentId string) error
+	RemoveItem(id, contentId string) error
+	GetActivePurchases(id string) ([]*models.CollectionPurchase, error)
+}
+
+type collectionRepo struct {
+	db *sql.DB
+}
+
+func NewCollectionRepository(db *sql.DB) CollectionRepository {
+	return &collectionRepo{db: db}
+}
+
+const collectionColumns = `id, creator_id, title, description, price_amount, price_currency, grant_new_items,
+              created_at, updated_at`
+
+func scanCollection(row rowScanner) (*models.Collection, error) {
+	var collection models.Collection
+	var price nullMoney
+	dest := append([]any{&collection.CollectionID, &collection.CreatorID, &collection.Title, &collection.Description},
+		price.dest()...)
+	dest = append(dest, &collection.GrantNewItems, &collection.CreatedAt, &collection.UpdatedAt)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	collection.Price = price.value()
+
+	return &collection, nil
+}
+
+func (r *collectionRepo) Create(collection *models.Collection) error {
+	query := `INSERT INTO collections (id, creator_id, title, description, price_amount, price_currency, grant_new_items,
+              created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	priceAmount, priceCurrency := moneyArgs(collection.Price)
+	_, err := r.db.Exec(query, collection.CollectionID, collection.CreatorID, collection.Title,
+		collection.Description, priceAmount, priceCurrency, collection.GrantNewItems, collection.CreatedAt,
+		collection.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *collectionRepo) GetById(id string) (*models.Collection, error) {
+	query := "SELECT " + collectionColumns + " FROM collections WHERE id = $1"
+
+	return scanCollection(r.db.QueryRow(query, id))
+}
+
+func (r *collectionRepo) GetByCreator(creatorId string) ([]*models.Collection, error) {
+	query := "SELECT " + collectionColumns + " FROM collections WHERE creator_id = $1 ORDER BY created_at"
+
+	rows, err := r.db.Query(query, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []*models.Collection
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+func (r *collectionRepo) Update(collection *models.Collection) error {
+	query := `UPDATE collections SET title = $2, description = $3, price_amount = $4, price_currency = $5,
+              grant_new_items = $6, updated_at = $7
+              WHERE id = $1`
+
+	priceAmount, priceCurrency := moneyArgs(collection.Price)
+	res, err := r.db.Exec(query, collection.CollectionID, collection.Title, collection.Description, priceAmount,
+		priceCurrency, collection.GrantNewItems, collection.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (r *collectionRepo) Delete(id string) error {
+	query := "DELETE FROM collections WHERE id = $1"
+
+	res, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (r *collectionRepo) GetItems(id string) ([]*models.CollectionItem, error) {
+	query := `SELECT ci.content_id, c.title, ci.position FROM collection_items ci
+              JOIN content c ON c.id = ci.content_id
+              WHERE ci.collection_id = $1 AND c.deleted_at IS NULL AND c.similarity_status IN ($2, $3)
+              ORDER BY ci.position`
+
+	rows, err := r.db.Query(query, id, models.SimilarityPassed, models.SimilarityUnchecked)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.CollectionItem
+	for rows.Next() {
+		var item models.CollectionItem
+		if err := rows.Scan(&item.ContentID, &item.Title, &item.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *collectionRepo) SetItems(id string, contentIds []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM collection_items WHERE collection_id = $1", id); err != nil {
+		return err
+	}
+
+	for i, contentId := range contentIds {
+		_, err = tx.Exec("INSERT INTO collection_items (collection_id, content_id, position) VALUES ($1, $2, $3)",
+			id, contentId, i+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *collectionRepo) AddItem(id, contentId string) error {
+	query := `INSERT INTO collection_items (collection_id, content_id, position)
+              SELECT $1::uuid, $2::uuid, COALESCE(MAX(position), 0) + 1 FROM collection_items WHERE collection_id = $1
+              ON CONFLICT DO NOTHING`
+
+	_, err := r.db.Exec(query, id, contentId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *collectionRepo) RemoveItem(id, contentId string) error {
+	query := "DELETE FROM collection_items WHERE collection_id = $1 AND content_id = $2"
+
+	res, err := r.db.Exec(query, id, contentId)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func insertPurchase(db execer, purchase *models.CollectionPurchase) error {
+	query := `INSERT INTO collection_purchases (id, collection_id, user_id, amount, currency, expires_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := db.Exec(query, purchase.PurchaseID, purchase.CollectionID, purchase.UserID, purchase.Price.Amount,
+		purchase.Price.Currency, purchase.ExpiresAt, purchase.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *collectionRepo) GetActivePurchases(id string) ([]*models.CollectionPurchase, error) {
+	query := `SELECT id, collection_id, user_id, amount, currency, expires_at, created_at FROM collection_purchases
+              WHERE collection_id = $1 AND expires_at > NOW()`
+
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var purchases []*models.CollectionPurchase
+	for rows.Next() {
+		var purchase models.CollectionPurchase
+		err := rows.Scan(&purchase.PurchaseID, &purchase.CollectionID, &purchase.UserID, &purchase.Price.Amount,
+			&purchase.Price.Currency, &purchase.ExpiresAt, &purchase.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, &purchase)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return purchases, nil
+}
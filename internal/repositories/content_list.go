@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type contentCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    string          `json:"id"`
+}
+
+type queryArgs []any
+
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+func (r *contentRepo) List(filter *models.ContentFilter) ([]*models.Content, string, error) {
+	var args queryArgs
+	conditions := []string{"deleted_at IS NULL"}
+
+	if filter.CreatorID != "" {
+		conditions = append(conditions, "creator_id = "+args.add(filter.CreatorID))
+	}
+	if filter.CreatorID == "" || filter.CreatorID != filter.UserID {
+		conditions = append(conditions, fmt.Sprintf("similarity_status IN (%s, %s)",
+			args.add(models.SimilarityPassed), args.add(models.SimilarityUnchecked)))
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "price_currency = "+args.add(filter.Currency))
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "price_amount >= "+args.add(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "price_amount <= "+args.add(*filter.MaxPrice))
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+args.add(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+args.add(*filter.CreatedBefore))
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM content_tags ct JOIN tags t ON t.id = ct.tag_id
+              WHERE ct.content_id = content.id AND t.name = `+args.add(filter.Tag)+`)`)
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM content_categories cc JOIN categories c ON c.id = cc.category_id
+              WHERE cc.content_id = content.id AND c.slug = `+args.add(filter.Category)+`)`)
+	}
+	if filter.CollectionID != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM collection_items ci
+              WHERE ci.content_id = content.id AND ci.collection_id = `+args.add(filter.CollectionID)+`)`)
+	}
+	if filter.Purchased != nil {
+		userId := args.add(filter.UserID)
+		owned := fmt.Sprintf(`(creator_id = %[1]s OR EXISTS (SELECT 1 FROM licenses l
+              WHERE l.content_id = content.id AND l.user_id = %[1]s AND l.expires_at > NOW()) OR %[2]s)`,
+			userId, subscriptionAccess(userId, "NOW()", "content"))
+		if *filter.Purchased {
+			conditions = append(conditions, owned)
+		} else {
+			conditions = append(conditions, "NOT "+owned)
+		}
+	}
+
+	var sortExpr string
+	switch filter.Sort {
+	case "price":
+		sortExpr = "price_amount"
+	case "title":
+		sortExpr = "title"
+	case "relevance":
+		sortExpr = "ts_rank(search_vector, websearch_to_tsquery('english', " + args.add(filter.Query) + "))::float8"
+	default:
+		sortExpr = "created_at"
+	}
+
+	if filter.Query != "" {
+		conditions = append(conditions, "search_vector @@ websearch_to_tsquery('english', "+args.add(filter.Query)+")")
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Order == "desc" {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil || cursor.Sort != filter.Sort {
+			return nil, "", ErrInvalidCursor
+		}
+
+		value, err := cursorValue(filter.Sort, cursor.Value)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", sortExpr, comparison,
+			args.add(value), args.add(cursor.ID)))
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s FROM content WHERE %s ORDER BY %s %s, id %s LIMIT %s",
+		contentColumns, sortExpr, strings.Join(conditions, " AND "), sortExpr, direction, direction,
+		args.add(filter.Limit+1))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var contents []*models.Content
+	var sortValues []any
+	for rows.Next() {
+		sortValue := newSortValue(filter.Sort)
+		content, err := scanContent(rows, sortValue)
+		if err != nil {
+			return nil, "", err
+		}
+		contents = append(contents, content)
+		sortValues = append(sortValues, sortValue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(contents) <= filter.Limit {
+		return contents, "", nil
+	}
+
+	contents = contents[:filter.Limit]
+	last := contents[len(contents)-1]
+
+	nextCursor, err := encodeCursor(filter.Sort, sortValues[filter.Limit-1], last.ContentID.String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return contents, nextCursor, nil
+}
+
+func newSortValue(sort string) any {
+	switch sort {
+	case "price":
+		return new(int64)
+	case "relevance":
+		return new(float64)
+	case "title":
+		return new(string)
+	default:
+		return new(time.Time)
+	}
+}
+
+func cursorValue(sort string, raw json.RawMessage) (any, error) {
+	value := newSortValue(sort)
+	if err := json.Unmarshal(raw, value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case *int64:
+		return *v, nil
+	case *float64:
+		return *v, nil
+	case *string:
+		return *v, nil
+	case *time.Time:
+		return *v, nil
+	}
+
+	return nil, ErrInvalidCursor
+}
+
+func encodeCursor(sort string, value any, id string) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(contentCursor{Sort: sort, Value: raw, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) (*contentCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var c contentCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
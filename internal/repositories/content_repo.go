@@ -2,14 +2,30 @@ package repositories
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
 )
 
 type ContentRepository interface {
 	Create(content *models.Content) error
 	GetAll() ([]*models.Content, error)
+	List(filter *models.ContentFilter) ([]*models.Content, string, error)
 	GetById(id string) (*models.Content, error)
+	Update(content *models.Content) error
+	SoftDelete(id string, deletedAt time.Time) error
+	GetUnpurged() ([]*models.Content, error)
+	Purge(id string, purgedAt time.Time) ([]string, error)
+	GetUnchecked(limit int) ([]*models.Content, error)
+	SetSimilarity(id, fileId, status string) error
+	GetBySHA256(sha256, excludeId string) (*models.Content, error)
+	GetUnscrubbed(checkedBefore time.Time, limit int) ([]*models.Content, error)
+	SetIntegrity(id, fileId, sha256 string, corrupt bool, checkedAt time.Time) error
+	GetCorrupt() ([]*models.Content, error)
+	GetTags(id string) ([]string, error)
+	GetCategories(id string) ([]string, error)
+	GetPrices(id string) ([]money.Money, error)
 }
 
 type contentRepo struct {
@@ -20,22 +36,29 @@ func NewContentRepository(db *sql.DB) ContentRepository {
 	return &contentRepo{db: db}
 }
 
-func (r *contentRepo) Create(content *models.Content) error {
-	query := `INSERT INTO content (id, title, description, creator_id, price, created_at, updated_at, file_id, file_size)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+const contentColumns = `id, title, description, creator_id, price_amount, price_currency, created_at, updated_at,
+              file_id, file_size, mime_type, COALESCE(sha256, ''), corrupt, similarity_status, transferable, deleted_at,
+              purged_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
-	_, err := r.db.Exec(query, content.ContentID, content.Title, content.Description, content.CreatorID,
-		content.Price, content.CreatedAt, content.UpdatedAt, content.FileID, content.FileSize)
+func scanContent(row rowScanner, extra ...any) (*models.Content, error) {
+	var content models.Content
+	dest := []any{&content.ContentID, &content.Title, &content.Description, &content.CreatorID, &content.Price.Amount,
+		&content.Price.Currency, &content.CreatedAt, &content.UpdatedAt, &content.FileID, &content.FileSize,
+		&content.MimeType, &content.SHA256, &content.Corrupt, &content.Similarity, &content.Transferable, &content.DeletedAt, &content.PurgedAt}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &content, nil
 }
 
-func (r *contentRepo) GetAll() ([]*models.Content, error) {
-	query := "SELECT id, title, description, creator_id, price, created_at, updated_at, file_id, file_size FROM content"
-	rows, err := r.db.Query(query)
+func (r *contentRepo) queryContents(query string, args ...any) ([]*models.Content, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,13 +66,11 @@ func (r *contentRepo) GetAll() ([]*models.Content, error) {
 
 	var contents []*models.Content
 	for rows.Next() {
-		var content models.Content
-		err := rows.Scan(&content.ContentID, &content.Title, &content.Description, &content.CreatorID, &content.Price, &content.CreatedAt,
-			&content.UpdatedAt, &content.FileID, &content.FileSize)
+		content, err := scanContent(rows)
 		if err != nil {
 			return nil, err
 		}
-		contents = append(contents, &content)
+		contents = append(contents, content)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -59,17 +80,331 @@ func (r *contentRepo) GetAll() ([]*models.Content, error) {
 	return contents, nil
 }
 
+// Create inserts the content row together with its tags, categories and extra prices. It fails with
+// ErrQuotaExceeded or ErrItemLimitReached if the item does not fit the creator's storage tier.
+func (r *contentRepo) Create(content *models.Content) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = reserveQuota(tx, content, true); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO content (id, title, description, creator_id, price_amount, price_currency, created_at, updated_at,
+              file_id, file_size, mime_type, sha256, similarity_status, transferable)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14)`
+
+	_, err = tx.Exec(query, content.ContentID, content.Title, content.Description, content.CreatorID,
+		content.Price.Amount, content.Price.Currency, content.CreatedAt, content.UpdatedAt, content.FileID,
+		content.FileSize, content.MimeType, content.SHA256, content.Similarity, content.Transferable)
+	if err != nil {
+		return err
+	}
+
+	if err = setLabels(tx, content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *contentRepo) GetAll() ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + " FROM content WHERE deleted_at IS NULL"
+
+	return r.queryContents(query)
+}
+
 func (r *contentRepo) GetById(id string) (*models.Content, error) {
-	query := `SELECT id, title, description, creator_id, price, created_at, updated_at, file_id, file_size
-              FROM content WHERE id = $1`
+	query := "SELECT " + contentColumns + " FROM content WHERE id = $1"
 
-	var content models.Content
-	err := r.db.QueryRow(query, id).Scan(&content.ContentID, &content.Title, &content.Description,
-		&content.CreatorID, &content.Price, &content.CreatedAt, &content.UpdatedAt, &content.FileID,
-		&content.FileSize)
+	return scanContent(r.db.QueryRow(query, id))
+}
+
+// Update rewrites the content row and replaces its tags, categories and extra prices. A larger file fails with
+// ErrQuotaExceeded if it does not fit the creator's storage tier.
+func (r *contentRepo) Update(content *models.Content) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = reserveQuota(tx, content, false); err != nil {
+		return err
+	}
+
+	query := `UPDATE content SET title = $2, description = $3, price_amount = $4, price_currency = $5, file_id = $6,
+              file_size = $7, mime_type = $8, sha256 = NULLIF($9, ''), corrupt = $10, similarity_status = $11,
+              transferable = $12, updated_at = $13
+              WHERE id = $1 AND deleted_at IS NULL`
+
+	res, err := tx.Exec(query, content.ContentID, content.Title, content.Description, content.Price.Amount,
+		content.Price.Currency, content.FileID, content.FileSize, content.MimeType, content.SHA256, content.Corrupt,
+		content.Similarity, content.Transferable, content.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err = expectRows(res); err != nil {
+		return err
+	}
+
+	if err = setLabels(tx, content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *contentRepo) SoftDelete(id string, deletedAt time.Time) error {
+	query := "UPDATE content SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL"
+
+	res, err := r.db.Exec(query, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (r *contentRepo) GetUnpurged() ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + " FROM content WHERE deleted_at IS NOT NULL AND purged_at IS NULL"
+
+	return r.queryContents(query)
+}
+
+// Purge marks the content purged and deletes everything derived from its file: watermark segments, HLS renditions
+// and previews. It returns the file ids of the derived objects so they can be removed from storage.
+func (r *contentRepo) Purge(id string, purgedAt time.Time) ([]string, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return &content, nil
+	var fileIds []string
+	for _, query := range []string{
+		"DELETE FROM watermark_segments WHERE content_id = $1 RETURNING file_id",
+		"DELETE FROM rendition_segments WHERE content_id = $1 RETURNING file_id",
+		"DELETE FROM renditions WHERE content_id = $1 RETURNING init_file_id",
+		"DELETE FROM content_previews WHERE content_id = $1 RETURNING file_id",
+	} {
+		deleted, err := deleteFiles(tx, query, id)
+		if err != nil {
+			return nil, err
+		}
+		fileIds = append(fileIds, deleted...)
+	}
+
+	if _, err = tx.Exec("UPDATE content SET purged_at = $2 WHERE id = $1", id, purgedAt); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return fileIds, nil
+}
+
+func deleteFiles(tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileIds []string
+	for rows.Next() {
+		var fileId sql.NullString
+		if err := rows.Scan(&fileId); err != nil {
+			return nil, err
+		}
+		if fileId.Valid {
+			fileIds = append(fileIds, fileId.String)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fileIds, nil
+}
+
+func (r *contentRepo) GetUnchecked(limit int) ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + ` FROM content
+              WHERE deleted_at IS NULL AND similarity_status IN ($1, $2) ORDER BY created_at LIMIT $3`
+
+	return r.queryContents(query, models.SimilarityUnchecked, models.SimilarityFlagged, limit)
+}
+
+// SetSimilarity records the outcome of a check, unless the file was replaced while it ran.
+func (r *contentRepo) SetSimilarity(id, fileId, status string) error {
+	query := "UPDATE content SET similarity_status = $3 WHERE id = $1 AND file_id = $2"
+
+	res, err := r.db.Exec(query, id, fileId, status)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (r *contentRepo) GetBySHA256(sha256, excludeId string) (*models.Content, error) {
+	query := "SELECT " + contentColumns + ` FROM content
+              WHERE sha256 = $1 AND id <> $2 AND deleted_at IS NULL ORDER BY created_at LIMIT 1`
+
+	return scanContent(r.db.QueryRow(query, sha256, excludeId))
+}
+
+// GetUnscrubbed returns live content not verified since checkedBefore, never-verified content first.
+func (r *contentRepo) GetUnscrubbed(checkedBefore time.Time, limit int) ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + ` FROM content
+              WHERE deleted_at IS NULL AND (integrity_checked_at IS NULL OR integrity_checked_at < $1)
+              ORDER BY integrity_checked_at NULLS FIRST LIMIT $2`
+
+	return r.queryContents(query, checkedBefore, limit)
+}
+
+// SetIntegrity records a verification of the stored file, unless the file was replaced while it ran. A missing
+// checksum is filled in with the one just computed.
+func (r *contentRepo) SetIntegrity(id, fileId, sha256 string, corrupt bool, checkedAt time.Time) error {
+	query := `UPDATE content SET sha256 = COALESCE(sha256, NULLIF($3, '')), corrupt = $4, integrity_checked_at = $5
+              WHERE id = $1 AND file_id = $2`
+
+	res, err := r.db.Exec(query, id, fileId, sha256, corrupt, checkedAt)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (r *contentRepo) GetCorrupt() ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + " FROM content WHERE corrupt AND deleted_at IS NULL ORDER BY updated_at"
+
+	return r.queryContents(query)
+}
+
+func setLabels(db execer, content *models.Content) error {
+	id := content.ContentID
+
+	if _, err := db.Exec("DELETE FROM content_tags WHERE content_id = $1", id); err != nil {
+		return err
+	}
+
+	for _, tag := range content.Tags {
+		_, err := db.Exec("INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", tag)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.Exec(`INSERT INTO content_tags (content_id, tag_id) SELECT $1::uuid, id FROM tags WHERE name = $2
+              ON CONFLICT DO NOTHING`, id, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec("DELETE FROM content_categories WHERE content_id = $1", id); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`INSERT INTO content_categories (content_id, category_id)
+              SELECT $1::uuid, id FROM categories WHERE slug = ANY($2::text[])`, id, content.Categories)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DELETE FROM content_prices WHERE content_id = $1", id); err != nil {
+		return err
+	}
+
+	for _, price := range content.Prices {
+		_, err := db.Exec("INSERT INTO content_prices (content_id, currency, amount) VALUES ($1, $2, $3)",
+			id, price.Currency, price.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *contentRepo) GetTags(id string) ([]string, error) {
+	query := `SELECT t.name FROM tags t JOIN content_tags ct ON ct.tag_id = t.id
+              WHERE ct.content_id = $1 ORDER BY t.name`
+
+	return r.queryStrings(query, id)
+}
+
+func (r *contentRepo) GetCategories(id string) ([]string, error) {
+	query := `SELECT c.slug FROM categories c JOIN content_categories cc ON cc.category_id = c.id
+              WHERE cc.content_id = $1 ORDER BY c.slug`
+
+	return r.queryStrings(query, id)
+}
+
+func (r *contentRepo) GetPrices(id string) ([]money.Money, error) {
+	query := "SELECT amount, currency FROM content_prices WHERE content_id = $1 ORDER BY currency"
+
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []money.Money
+	for rows.Next() {
+		var price money.Money
+		if err := rows.Scan(&price.Amount, &price.Currency); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func (r *contentRepo) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func expectRows(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
 }
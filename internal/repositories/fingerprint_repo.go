@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/fingerprint"
+)
+
+type FingerprintRepository interface {
+	Save(contentId, kind string, hashes []fingerprint.Hash) error
+	Nearest(contentId, kind string, hashes []fingerprint.Hash, maxDistance int) (string, int, error)
+	Delete(contentId string) error
+}
+
+type fingerprintRepo struct {
+	db *sql.DB
+}
+
+func NewFingerprintRepository(db *sql.DB) FingerprintRepository {
+	return &fingerprintRepo{db: db}
+}
+
+func toInt64s(hashes []fingerprint.Hash) []int64 {
+	values := make([]int64, len(hashes))
+	for i, hash := range hashes {
+		values[i] = int64(hash)
+	}
+	return values
+}
+
+// Save replaces the fingerprints of a content item, so a replaced file does not keep matching under the old one.
+func (r *fingerprintRepo) Save(contentId, kind string, hashes []fingerprint.Hash) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM fingerprints WHERE content_id = $1", contentId); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO fingerprints (content_id, kind, position, hash)
+              SELECT $1, $2, position - 1, hash FROM unnest($3::bigint[]) WITH ORDINALITY AS h(hash, position)`,
+		contentId, kind, toInt64s(hashes))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Nearest finds the live content item with the most hashes within maxDistance bits of the query. Candidates come
+// from the band indexes, so pairs more than 3 bits apart are only found when one band survived intact.
+func (r *fingerprintRepo) Nearest(contentId, kind string, hashes []fingerprint.Hash, maxDistance int) (string, int, error) {
+	query := `SELECT f.content_id, COUNT(DISTINCT q.position) AS matched
+              FROM unnest($3::bigint[]) WITH ORDINALITY AS q(hash, position)
+              JOIN fingerprints f ON f.kind = $2 AND (f.band0 = ((q.hash >> 48) & 65535)::integer
+                  OR f.band1 = ((q.hash >> 32) & 65535)::integer OR f.band2 = ((q.hash >> 16) & 65535)::integer
+                  OR f.band3 = (q.hash & 65535)::integer)
+              JOIN content c ON c.id = f.content_id AND c.deleted_at IS NULL
+              WHERE f.content_id <> $1 AND bit_count((f.hash # q.hash)::bit(64)) <= $4
+              GROUP BY f.content_id ORDER BY matched DESC LIMIT 1`
+
+	var matchId string
+	var matched int
+	err := r.db.QueryRow(query, contentId, kind, toInt64s(hashes), maxDistance).Scan(&matchId, &matched)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, nil
+		}
+		return "", 0, err
+	}
+
+	return matchId, matched, nil
+}
+
+func (r *fingerprintRepo) Delete(contentId string) error {
+	_, err := r.db.Exec("DELETE FROM fingerprints WHERE content_id = $1", contentId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
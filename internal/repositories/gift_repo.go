@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type GiftRepository interface {
+	Redeem(code string, license *models.License) (*models.GiftCode, error)
+	GetByOrder(orderId string) (*models.GiftCode, error)
+	GetByPurchaser(purchaserId string) ([]*models.GiftCode, error)
+}
+
+type giftRepo struct {
+	db *sql.DB
+}
+
+func NewGiftRepository(db *sql.DB) GiftRepository {
+	return &giftRepo{db: db}
+}
+
+const giftColumns = "id, code, content_id, purchaser_id, order_id, redeemed_by, redeemed_at, expires_at, created_at"
+
+func scanGift(row rowScanner) (*models.GiftCode, error) {
+	var gift models.GiftCode
+	err := row.Scan(&gift.GiftID, &gift.Code, &gift.ContentID, &gift.PurchaserID, &gift.OrderID, &gift.RedeemedBy,
+		&gift.RedeemedAt, &gift.ExpiresAt, &gift.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gift, nil
+}
+
+func insertGift(db execer, gift *models.GiftCode) error {
+	query := `INSERT INTO gift_codes (id, code, content_id, purchaser_id, order_id, expires_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := db.Exec(query, gift.GiftID, gift.Code, gift.ContentID, gift.PurchaserID, gift.OrderID, gift.ExpiresAt,
+		gift.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Redeem claims the code for license.UserID and grants the license in one transaction. Only codes whose order is paid
+// and not refunded can be claimed. The license takes its content and order from the code.
+func (r *giftRepo) Redeem(code string, license *models.License) (*models.GiftCode, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE gift_codes g SET redeemed_by = $2, redeemed_at = $3
+              WHERE code = $1 AND redeemed_by IS NULL AND expires_at > $3
+              AND EXISTS (SELECT 1 FROM orders o WHERE o.id = g.order_id AND o.status = $4 AND o.refunded_at IS NULL)
+              RETURNING ` + giftColumns
+
+	gift, err := scanGift(tx.QueryRow(query, code, license.UserID, license.CreatedAt, models.OrderPaid))
+	if err != nil {
+		return nil, err
+	}
+
+	license.ContentID = gift.ContentID
+	license.OrderID = gift.OrderID
+	if err = insertLicenses(tx, []*models.License{license}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return gift, nil
+}
+
+func (r *giftRepo) GetByOrder(orderId string) (*models.GiftCode, error) {
+	query := "SELECT " + giftColumns + " FROM gift_codes WHERE order_id = $1"
+
+	return scanGift(r.db.QueryRow(query, orderId))
+}
+
+func (r *giftRepo) GetByPurchaser(purchaserId string) ([]*models.GiftCode, error) {
+	query := "SELECT " + giftColumns + " FROM gift_codes WHERE purchaser_id = $1 ORDER BY created_at DESC"
+
+	rows, err := r.db.Query(query, purchaserId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gifts []*models.GiftCode
+	for rows.Next() {
+		gift, err := scanGift(rows)
+		if err != nil {
+			return nil, err
+		}
+		gifts = append(gifts, gift)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return gifts, nil
+}
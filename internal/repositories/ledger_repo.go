@@ -0,0 +1,289 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+)
+
+type LedgerRepository interface {
+	GetByOrder(orderId, kind string) ([]*models.LedgerEntry, error)
+	GetBalances(creatorId string) ([]*models.Balance, error)
+	GetSales(creatorId string) ([]*models.SalesSummary, error)
+	GetStatement(creatorId string, from, to time.Time) ([]*models.StatementLine, error)
+	GetPayable() ([]*models.Payout, error)
+	CreatePayout(payout *models.Payout, entries []*models.LedgerEntry) error
+	GetPayouts(creatorId string) ([]*models.Payout, error)
+}
+
+var ErrBalanceChanged = errors.New("creator balance changed")
+
+type ledgerRepo struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) LedgerRepository {
+	return &ledgerRepo{db: db}
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertEntries(db execer, entries []*models.LedgerEntry) error {
+	query := `INSERT INTO ledger_entries (id, transaction_id, kind, account, creator_id, order_id, payout_id, amount,
+              currency, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	for _, entry := range entries {
+		_, err := db.Exec(query, entry.EntryID, entry.TransactionID, entry.Kind, entry.Account, entry.CreatorID,
+			entry.OrderID, entry.PayoutID, entry.Amount.Amount, entry.Amount.Currency, entry.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ledgerRepo) GetByOrder(orderId, kind string) ([]*models.LedgerEntry, error) {
+	query := `SELECT id, transaction_id, kind, account, creator_id, order_id, payout_id, amount, currency, created_at
+              FROM ledger_entries WHERE order_id = $1 AND kind = $2 ORDER BY created_at`
+
+	rows, err := r.db.Query(query, orderId, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.LedgerEntry
+	for rows.Next() {
+		var entry models.LedgerEntry
+		err := rows.Scan(&entry.EntryID, &entry.TransactionID, &entry.Kind, &entry.Account, &entry.CreatorID,
+			&entry.OrderID, &entry.PayoutID, &entry.Amount.Amount, &entry.Amount.Currency, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *ledgerRepo) GetBalances(creatorId string) ([]*models.Balance, error) {
+	query := `SELECT currency,
+              COALESCE(-SUM(amount) FILTER (WHERE kind <> 'payout'), 0)::bigint,
+              COALESCE(SUM(amount) FILTER (WHERE kind = 'payout'), 0)::bigint,
+              (-SUM(amount))::bigint
+              FROM ledger_entries WHERE account = 'creator' AND creator_id = $1
+              GROUP BY currency ORDER BY currency`
+
+	rows, err := r.db.Query(query, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []*models.Balance
+	for rows.Next() {
+		var currency string
+		var earned, paidOut, available int64
+		if err := rows.Scan(&currency, &earned, &paidOut, &available); err != nil {
+			return nil, err
+		}
+		balances = append(balances, &models.Balance{
+			Earned:    money.New(earned, currency),
+			PaidOut:   money.New(paidOut, currency),
+			Available: money.New(available, currency),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func (r *ledgerRepo) GetSales(creatorId string) ([]*models.SalesSummary, error) {
+	query := `SELECT o.content_id, o.collection_id, COALESCE(c.title, col.title, ''), e.currency,
+              COUNT(*) FILTER (WHERE e.kind = 'sale'),
+              COUNT(*) FILTER (WHERE e.kind = 'refund'),
+              SUM(CASE WHEN e.kind = 'sale' THEN o.amount ELSE -o.amount END)::bigint,
+              (-SUM(e.amount))::bigint
+              FROM ledger_entries e
+              JOIN orders o ON o.id = e.order_id
+              LEFT JOIN content c ON c.id = o.content_id
+              LEFT JOIN collections col ON col.id = o.collection_id
+              WHERE e.account = 'creator' AND e.creator_id = $1
+              GROUP BY o.content_id, o.collection_id, c.title, col.title, e.currency
+              ORDER BY 8 DESC`
+
+	rows, err := r.db.Query(query, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []*models.SalesSummary
+	for rows.Next() {
+		var summary models.SalesSummary
+		var currency string
+		err := rows.Scan(&summary.ContentID, &summary.CollectionID, &summary.Title, &currency, &summary.Orders,
+			&summary.Refunds, &summary.Gross.Amount, &summary.Earnings.Amount)
+		if err != nil {
+			return nil, err
+		}
+		summary.Gross.Currency = currency
+		summary.Earnings.Currency = currency
+		sales = append(sales, &summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+func (r *ledgerRepo) GetStatement(creatorId string, from, to time.Time) ([]*models.StatementLine, error) {
+	query := `SELECT e.transaction_id, MIN(e.kind), e.order_id, COALESCE(c.title, col.title, ''), e.currency,
+              COALESCE(SUM(e.amount) FILTER (WHERE e.account = 'cash' AND e.kind <> 'payout'), 0)::bigint,
+              COALESCE(-SUM(e.amount) FILTER (WHERE e.account = 'platform'), 0)::bigint,
+              COALESCE(-SUM(e.amount) FILTER (WHERE e.account = 'tax'), 0)::bigint,
+              COALESCE(-SUM(e.amount) FILTER (WHERE e.account = 'creator'), 0)::bigint,
+              MIN(e.created_at)
+              FROM ledger_entries e
+              LEFT JOIN orders o ON o.id = e.order_id
+              LEFT JOIN content c ON c.id = o.content_id
+              LEFT JOIN collections col ON col.id = o.collection_id
+              WHERE e.transaction_id IN (SELECT transaction_id FROM ledger_entries
+                  WHERE account = 'creator' AND creator_id = $1 AND created_at >= $2 AND created_at < $3)
+              GROUP BY e.transaction_id, e.order_id, c.title, col.title, e.currency
+              ORDER BY MIN(e.created_at)`
+
+	rows, err := r.db.Query(query, creatorId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*models.StatementLine
+	for rows.Next() {
+		var line models.StatementLine
+		var currency string
+		err := rows.Scan(&line.TransactionID, &line.Kind, &line.OrderID, &line.Title, &currency, &line.Gross.Amount,
+			&line.PlatformFee.Amount, &line.Tax.Amount, &line.Net.Amount, &line.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		line.Gross.Currency = currency
+		line.PlatformFee.Currency = currency
+		line.Tax.Currency = currency
+		line.Net.Currency = currency
+		lines = append(lines, &line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func (r *ledgerRepo) GetPayable() ([]*models.Payout, error) {
+	query := `SELECT creator_id, currency, (-SUM(amount))::bigint FROM ledger_entries
+              WHERE account = 'creator' GROUP BY creator_id, currency HAVING -SUM(amount) > 0`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*models.Payout
+	for rows.Next() {
+		var payout models.Payout
+		if err := rows.Scan(&payout.CreatorID, &payout.Amount.Currency, &payout.Amount.Amount); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, &payout)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payouts, nil
+}
+
+// Payout runs are serialised so a balance cannot be paid twice; the balance is re-read under the lock.
+func (r *ledgerRepo) CreatePayout(payout *models.Payout, entries []*models.LedgerEntry) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock(hashtext('payouts'))"); err != nil {
+		return err
+	}
+
+	var available int64
+	err = tx.QueryRow(`SELECT COALESCE(-SUM(amount), 0)::bigint FROM ledger_entries
+              WHERE account = 'creator' AND creator_id = $1 AND currency = $2`,
+		payout.CreatorID, payout.Amount.Currency).Scan(&available)
+	if err != nil {
+		return err
+	}
+
+	if available < payout.Amount.Amount {
+		return ErrBalanceChanged
+	}
+
+	_, err = tx.Exec("INSERT INTO payouts (id, creator_id, amount, currency, created_at) VALUES ($1, $2, $3, $4, $5)",
+		payout.PayoutID, payout.CreatorID, payout.Amount.Amount, payout.Amount.Currency, payout.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err = insertEntries(tx, entries); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ledgerRepo) GetPayouts(creatorId string) ([]*models.Payout, error) {
+	query := "SELECT id, creator_id, amount, currency, created_at FROM payouts WHERE creator_id = $1 ORDER BY created_at DESC"
+
+	rows, err := r.db.Query(query, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*models.Payout
+	for rows.Next() {
+		var payout models.Payout
+		err := rows.Scan(&payout.PayoutID, &payout.CreatorID, &payout.Amount.Amount, &payout.Amount.Currency,
+			&payout.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, &payout)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payouts, nil
+}
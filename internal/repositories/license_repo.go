@@ -4,12 +4,20 @@ import (
 	"database/sql"
 
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/gofrs/uuid"
 )
 
 type LicenseRepository interface {
 	Create(license *models.License) error
+	CreateBatch(licenses []*models.License) error
 	Get(userId, contentId string) (*models.License, error)
 	Delete(licenseId string) error
+	CountActive(contentId string) (int, error)
+	GetActiveForUser(userId string, contentIds []string) ([]*models.License, error)
+	GetById(licenseId string) (*models.License, error)
+	GetByUser(userId string) ([]*models.License, error)
+	GetByOrder(orderId string) ([]*models.License, error)
+	Transfer(licenseId, userId string) error
 }
 
 type licenseRepo struct {
@@ -20,31 +28,58 @@ func NewLicenseRepository(db *sql.DB) LicenseRepository {
 	return &licenseRepo{db: db}
 }
 
-func (r *licenseRepo) Create(license *models.License) error {
-	query := `INSERT INTO licenses (id, user_id, content_id, expires_at, created_at)
-			VALUES ($1, $2, $3, $4, $5)`
+const licenseColumns = "id, user_id, content_id, order_id, expires_at, created_at"
 
-	_, err := r.db.Exec(query, license.LicenseID, license.UserID, license.ContentID, license.ExpiresAt, license.CreatedAt)
+func scanLicense(row rowScanner) (*models.License, error) {
+	var license models.License
+	err := row.Scan(&license.LicenseID, &license.UserID, &license.ContentID, &license.OrderID, &license.ExpiresAt,
+		&license.CreatedAt)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &license, nil
 }
 
-func (r *licenseRepo) Get(userId, contentId string) (*models.License, error) {
-	query := `SELECT id, user_id, content_id, expires_at, created_at FROM licenses 
-			WHERE user_id = $1 AND content_id = $2`
+func (r *licenseRepo) Create(license *models.License) error {
+	return insertLicenses(r.db, []*models.License{license})
+}
 
-	row := r.db.QueryRow(query, userId, contentId)
+func insertLicenses(db execer, licenses []*models.License) error {
+	query := `INSERT INTO licenses (id, user_id, content_id, order_id, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`
 
-	var license models.License
-	err := row.Scan(&license.LicenseID, &license.UserID, &license.ContentID, &license.ExpiresAt, &license.CreatedAt)
+	for _, license := range licenses {
+		_, err := db.Exec(query, license.LicenseID, license.UserID, license.ContentID, license.OrderID,
+			license.ExpiresAt, license.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *licenseRepo) CreateBatch(licenses []*models.License) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	return &license, nil
+	if err = insertLicenses(tx, licenses); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the user's longest-lasting active license for the content.
+func (r *licenseRepo) Get(userId, contentId string) (*models.License, error) {
+	query := "SELECT " + licenseColumns + ` FROM licenses
+			WHERE user_id = $1 AND content_id = $2 AND expires_at > NOW() ORDER BY expires_at DESC LIMIT 1`
+
+	return scanLicense(r.db.QueryRow(query, userId, contentId))
 }
 
 func (r *licenseRepo) Delete(licenseId string) error {
@@ -57,3 +92,80 @@ func (r *licenseRepo) Delete(licenseId string) error {
 
 	return nil
 }
+
+func (r *licenseRepo) CountActive(contentId string) (int, error) {
+	query := "SELECT COUNT(*) FROM licenses WHERE content_id = $1 AND expires_at > NOW()"
+
+	var count int
+	err := r.db.QueryRow(query, contentId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *licenseRepo) GetActiveForUser(userId string, contentIds []string) ([]*models.License, error) {
+	query := "SELECT " + licenseColumns + ` FROM licenses
+			WHERE user_id = $1 AND content_id = ANY($2::uuid[]) AND expires_at > NOW()`
+
+	ids := make([]uuid.UUID, len(contentIds))
+	for i, contentId := range contentIds {
+		ids[i] = uuid.FromStringOrNil(contentId)
+	}
+
+	return r.queryLicenses(query, userId, ids)
+}
+
+func (r *licenseRepo) GetByUser(userId string) ([]*models.License, error) {
+	query := "SELECT " + licenseColumns + ` FROM licenses
+			WHERE user_id = $1 AND expires_at > NOW() ORDER BY created_at DESC`
+
+	return r.queryLicenses(query, userId)
+}
+
+func (r *licenseRepo) GetByOrder(orderId string) ([]*models.License, error) {
+	query := "SELECT " + licenseColumns + " FROM licenses WHERE order_id = $1"
+
+	return r.queryLicenses(query, orderId)
+}
+
+func (r *licenseRepo) queryLicenses(query string, args ...any) ([]*models.License, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var licenses []*models.License
+	for rows.Next() {
+		license, err := scanLicense(rows)
+		if err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, license)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return licenses, nil
+}
+
+func (r *licenseRepo) GetById(licenseId string) (*models.License, error) {
+	query := "SELECT " + licenseColumns + " FROM licenses WHERE id = $1"
+
+	return scanLicense(r.db.QueryRow(query, licenseId))
+}
+
+func (r *licenseRepo) Transfer(licenseId, userId string) error {
+	query := "UPDATE licenses SET user_id = $2 WHERE id = $1"
+
+	res, err := r.db.Exec(query, licenseId, userId)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
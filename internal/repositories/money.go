@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+)
+
+type nullMoney struct {
+	amount   sql.NullInt64
+	currency sql.NullString
+}
+
+func (n *nullMoney) dest() []any {
+	return []any{&n.amount, &n.currency}
+}
+
+func (n *nullMoney) value() *money.Money {
+	if !n.amount.Valid || !n.currency.Valid {
+		return nil
+	}
+
+	m := money.New(n.amount.Int64, n.currency.String)
+	return &m
+}
+
+func moneyArgs(m *money.Money) (any, any) {
+	if m == nil {
+		return nil, nil
+	}
+
+	return m.Amount, m.Currency
+}
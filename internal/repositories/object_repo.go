@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type ObjectRepository interface {
+	Track(fileId string, createdAt time.Time) error
+	GetPending(createdBefore time.Time) ([]string, error)
+	Release(fileIds []string) error
+	GetReferences() ([]*models.ObjectReference, error)
+	IsReferenced(fileIds []string) (map[string]bool, error)
+}
+
+type objectRepo struct {
+	db *sql.DB
+}
+
+func NewObjectRepository(db *sql.DB) ObjectRepository {
+	return &objectRepo{db: db}
+}
+
+// referencesQuery lists every row that points at a stored object. Content keeps its file until it is purged, and
+// similarity checks only protect the files they were run against.
+const referencesQuery = `SELECT 'content', id::text, file_id, TRUE FROM content WHERE purged_at IS NULL
+              UNION ALL SELECT 'watermark_segments', content_id::text, file_id, TRUE FROM watermark_segments
+              UNION ALL SELECT 'renditions', content_id::text, init_file_id, TRUE FROM renditions
+                  WHERE init_file_id IS NOT NULL
+              UNION ALL SELECT 'rendition_segments', content_id::text, file_id, TRUE FROM rendition_segments
+              UNION ALL SELECT 'content_previews', content_id::text, file_id, TRUE FROM content_previews
+              UNION ALL SELECT 'similarity_checks', content_id::text, file_id, FALSE FROM similarity_checks
+                  WHERE file_id IS NOT NULL`
+
+func (r *objectRepo) Track(fileId string, createdAt time.Time) error {
+	query := "INSERT INTO pending_objects (file_id, created_at) VALUES ($1, $2) ON CONFLICT (file_id) DO NOTHING"
+
+	_, err := r.db.Exec(query, fileId, createdAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *objectRepo) GetPending(createdBefore time.Time) ([]string, error) {
+	query := "SELECT file_id FROM pending_objects WHERE created_at < $1 ORDER BY created_at"
+
+	rows, err := r.db.Query(query, createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileIds []string
+	for rows.Next() {
+		var fileId string
+		if err := rows.Scan(&fileId); err != nil {
+			return nil, err
+		}
+		fileIds = append(fileIds, fileId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fileIds, nil
+}
+
+func (r *objectRepo) Release(fileIds []string) error {
+	if len(fileIds) == 0 {
+		return nil
+	}
+
+	_, err := r.db.Exec("DELETE FROM pending_objects WHERE file_id = ANY($1::text[])", fileIds)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *objectRepo) GetReferences() ([]*models.ObjectReference, error) {
+	rows, err := r.db.Query(referencesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []*models.ObjectReference
+	for rows.Next() {
+		var ref models.ObjectReference
+		if err := rows.Scan(&ref.Source, &ref.OwnerID, &ref.FileID, &ref.Required); err != nil {
+			return nil, err
+		}
+		refs = append(refs, &ref)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+func (r *objectRepo) IsReferenced(fileIds []string) (map[string]bool, error) {
+	query := `SELECT DISTINCT refs.file_id FROM (` + referencesQuery + `) AS refs (source, owner_id, file_id, required)
+              WHERE refs.file_id = ANY($1::text[])`
+
+	rows, err := r.db.Query(query, fileIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool, len(fileIds))
+	for rows.Next() {
+		var fileId string
+		if err := rows.Scan(&fileId); err != nil {
+			return nil, err
+		}
+		referenced[fileId] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return referenced, nil
+}
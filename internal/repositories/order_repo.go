@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type OrderRepository interface {
+	Create(order *models.Order) error
+	Fulfil(fulfilment *models.Fulfilment) error
+	Fail(id string) error
+	GetById(id string) (*models.Order, error)
+	GetByUser(userId string) ([]*models.Order, error)
+	CountCouponUses(userId, couponId string) (int, error)
+}
+
+type orderRepo struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) OrderRepository {
+	return &orderRepo{db: db}
+}
+
+const orderColumns = `id, user_id, creator_id, content_id, collection_id, list_amount, discount_amount, amount, currency,
+              coupon_id, status, COALESCE(payment_id, ''), refunded_at, created_at`
+
+func scanOrder(row rowScanner) (*models.Order, error) {
+	var order models.Order
+	var currency string
+	err := row.Scan(&order.OrderID, &order.UserID, &order.CreatorID, &order.ContentID, &order.CollectionID,
+		&order.ListPrice.Amount, &order.Discount.Amount, &order.Price.Amount, &currency, &order.CouponID,
+		&order.Status, &order.PaymentID, &order.RefundedAt, &order.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	order.ListPrice.Currency = currency
+	order.Discount.Currency = currency
+	order.Price.Currency = currency
+
+	return &order, nil
+}
+
+// Create records the order as pending, before the customer is charged for it. A coupon on the order is redeemed in
+// the same transaction, with its row locked so concurrent orders cannot exceed its limits.
+func (r *orderRepo) Create(order *models.Order) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if order.CouponID != nil {
+		if err = redeemCoupon(tx, order.CouponID.String(), order.UserID.String()); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO orders (id, user_id, creator_id, content_id, collection_id, list_amount, discount_amount, amount,
+              currency, coupon_id, status, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err = tx.Exec(query, order.OrderID, order.UserID, order.CreatorID, order.ContentID, order.CollectionID,
+		order.ListPrice.Amount, order.Discount.Amount, order.Price.Amount, order.Price.Currency, order.CouponID,
+		models.OrderPending, order.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func redeemCoupon(tx *sql.Tx, couponId, userId string) error {
+	var perUserLimit *int
+	err := tx.QueryRow("SELECT per_user_limit FROM coupons WHERE id = $1 FOR UPDATE", couponId).Scan(&perUserLimit)
+	if err != nil {
+		return err
+	}
+
+	if perUserLimit != nil {
+		var used int
+		err = tx.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = $1 AND coupon_id = $2 AND status <> $3",
+			userId, couponId, models.OrderFailed).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if used >= *perUserLimit {
+			return ErrCouponExhausted
+		}
+	}
+
+	res, err := tx.Exec("UPDATE coupons SET uses = uses + 1 WHERE id = $1 AND (max_uses IS NULL OR uses < max_uses)",
+		couponId)
+	if err != nil {
+		return err
+	}
+
+	if err := expectRows(res); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCouponExhausted
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Fulfil marks a pending order paid and records its ledger entries, collection purchase, gift code and licenses in the same
+// transaction, so a sale is never written without what it paid for.
+func (r *orderRepo) Fulfil(fulfilment *models.Fulfilment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	order := fulfilment.Order
+	res, err := tx.Exec("UPDATE orders SET status = $2, payment_id = NULLIF($3, '') WHERE id = $1 AND status = $4",
+		order.OrderID, models.OrderPaid, order.PaymentID, models.OrderPending)
+	if err != nil {
+		return err
+	}
+	if err = expectRows(res); err != nil {
+		return err
+	}
+
+	if err = insertEntries(tx, fulfilment.Entries); err != nil {
+		return err
+	}
+
+	if fulfilment.Purchase != nil {
+		if err = insertPurchase(tx, fulfilment.Purchase); err != nil {
+			return err
+		}
+	}
+
+	if fulfilment.Gift != nil {
+		if err = insertGift(tx, fulfilment.Gift); err != nil {
+			return err
+		}
+	}
+
+	if err = insertLicenses(tx, fulfilment.Licenses); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Fail marks a pending order failed and gives back the coupon use it took.
+func (r *orderRepo) Fail(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var couponId *string
+	err = tx.QueryRow("UPDATE orders SET status = $2 WHERE id = $1 AND status = $3 RETURNING coupon_id",
+		id, models.OrderFailed, models.OrderPending).Scan(&couponId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if couponId != nil {
+		_, err = tx.Exec("UPDATE coupons SET uses = uses - 1 WHERE id = $1 AND uses > 0", *couponId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *orderRepo) GetById(id string) (*models.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE id = $1"
+
+	return scanOrder(r.db.QueryRow(query, id))
+}
+
+func (r *orderRepo) GetByUser(userId string) ([]*models.Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE user_id = $1 ORDER BY created_at DESC"
+
+	rows, err := r.db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (r *orderRepo) CountCouponUses(userId, couponId string) (int, error) {
+	query := "SELECT COUNT(*) FROM orders WHERE user_id = $1 AND coupon_id = $2 AND status <> 'failed'"
+
+	var count int
+	err := r.db.QueryRow(query, userId, couponId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
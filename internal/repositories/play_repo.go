@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/gofrs/uuid"
+)
+
+type PlayRepository interface {
+	Create(play *models.Play) error
+	CountSince(userId string, contentIds []string, since time.Time) (int, error)
+}
+
+type playRepo struct {
+	db *sql.DB
+}
+
+func NewPlayRepository(db *sql.DB) PlayRepository {
+	return &playRepo{db: db}
+}
+
+func (r *playRepo) Create(play *models.Play) error {
+	query := "INSERT INTO plays (id, user_id, content_id, created_at) VALUES ($1, $2, $3, $4)"
+
+	_, err := r.db.Exec(query, play.PlayID, play.UserID, play.ContentID, play.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *playRepo) CountSince(userId string, contentIds []string, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM plays WHERE user_id = $1 AND content_id = ANY($2::uuid[]) AND created_at >= $3"
+
+	ids := make([]uuid.UUID, len(contentIds))
+	for i, contentId := range contentIds {
+		ids[i] = uuid.FromStringOrNil(contentId)
+	}
+
+	var count int
+	err := r.db.QueryRow(query, userId, ids, since).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
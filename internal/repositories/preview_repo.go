@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/gofrs/uuid"
+)
+
+type PreviewRepository interface {
+	GetPending(limit int) ([]*models.Content, error)
+	RecordFailure(content *models.Content, reason string) error
+	Set(preview *models.Preview) (string, error)
+	Get(contentId, kind string) (*models.Preview, error)
+	GetByContent(contentId string) ([]*models.Preview, error)
+	GetKinds(contentIds []string) (map[string][]string, error)
+}
+
+type previewRepo struct {
+	db *sql.DB
+}
+
+func NewPreviewRepository(db *sql.DB) PreviewRepository {
+	return &previewRepo{db: db}
+}
+
+const previewColumns = "content_id, kind, file_id, mime_type, file_size, source_file_id, created_at"
+
+func scanPreview(row rowScanner) (*models.Preview, error) {
+	var preview models.Preview
+	err := row.Scan(&preview.ContentID, &preview.Kind, &preview.FileID, &preview.MimeType, &preview.FileSize,
+		&preview.SourceFileID, &preview.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preview, nil
+}
+
+// GetPending returns listed video content missing a thumbnail or trailer, counting generated previews of a replaced
+// file as missing. Content whose previews failed recently is skipped until its backoff has passed.
+func (r *previewRepo) GetPending(limit int) ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + ` FROM content c
+              WHERE deleted_at IS NULL AND purged_at IS NULL AND mime_type LIKE 'video/%'
+              AND similarity_status IN ($2, $3) AND (
+                  SELECT COUNT(*) FROM content_previews p
+                  WHERE p.content_id = c.id AND (p.source_file_id IS NULL OR p.source_file_id = c.file_id)) < 2
+              AND ` + notBackingOff(jobPreview) + " ORDER BY created_at LIMIT $1"
+
+	rows, err := r.db.Query(query, limit, models.SimilarityPassed, models.SimilarityUnchecked)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contents []*models.Content
+	for rows.Next() {
+		content, err := scanContent(rows)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+func (r *previewRepo) RecordFailure(content *models.Content, reason string) error {
+	return recordFailure(r.db, jobPreview, content, reason)
+}
+
+// Set stores a preview and returns the file id of the one it replaced, if any.
+func (r *previewRepo) Set(preview *models.Preview) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var stale string
+	err = tx.QueryRow("DELETE FROM content_previews WHERE content_id = $1 AND kind = $2 RETURNING file_id",
+		preview.ContentID, preview.Kind).Scan(&stale)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	_, err = tx.Exec("INSERT INTO content_previews ("+previewColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		preview.ContentID, preview.Kind, preview.FileID, preview.MimeType, preview.FileSize, preview.SourceFileID,
+		preview.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return stale, nil
+}
+
+func (r *previewRepo) Get(contentId, kind string) (*models.Preview, error) {
+	query := "SELECT " + previewColumns + " FROM content_previews WHERE content_id = $1 AND kind = $2"
+
+	return scanPreview(r.db.QueryRow(query, contentId, kind))
+}
+
+func (r *previewRepo) GetByContent(contentId string) ([]*models.Preview, error) {
+	query := "SELECT " + previewColumns + " FROM content_previews WHERE content_id = $1"
+
+	rows, err := r.db.Query(query, contentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var previews []*models.Preview
+	for rows.Next() {
+		preview, err := scanPreview(rows)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, preview)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return previews, nil
+}
+
+func (r *previewRepo) GetKinds(contentIds []string) (map[string][]string, error) {
+	kinds := make(map[string][]string)
+	if len(contentIds) == 0 {
+		return kinds, nil
+	}
+
+	ids := make([]uuid.UUID, len(contentIds))
+	for i, contentId := range contentIds {
+		ids[i] = uuid.FromStringOrNil(contentId)
+	}
+
+	rows, err := r.db.Query("SELECT content_id::text, kind FROM content_previews WHERE content_id = ANY($1::uuid[])", ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contentId, kind string
+		if err := rows.Scan(&contentId, &kind); err != nil {
+			return nil, err
+		}
+		kinds[contentId] = append(kinds[contentId], kind)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return kinds, nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type PricingRepository interface {
+	CreateCoupon(coupon *models.Coupon) error
+	GetCouponByCode(code string) (*models.Coupon, error)
+	GetCouponsByCreator(createdBy string) ([]*models.Coupon, error)
+	CreateSale(sale *models.Sale) error
+	GetActiveSale(contentId, currency string, at time.Time) (*models.Sale, error)
+}
+
+var ErrCouponExhausted = errors.New("coupon usage limit reached")
+
+type pricingRepo struct {
+	db *sql.DB
+}
+
+func NewPricingRepository(db *sql.DB) PricingRepository {
+	return &pricingRepo{db: db}
+}
+
+const couponColumns = `id, code, created_by, creator_id, content_id, kind, percent, amount, currency, starts_at,
+              expires_at, max_uses, per_user_limit, uses, created_at`
+
+func scanCoupon(row rowScanner) (*models.Coupon, error) {
+	var coupon models.Coupon
+	var amount nullMoney
+	dest := append([]any{&coupon.CouponID, &coupon.Code, &coupon.CreatedBy, &coupon.CreatorID, &coupon.ContentID,
+		&coupon.Kind, &coupon.Percent}, amount.dest()...)
+	dest = append(dest, &coupon.StartsAt, &coupon.ExpiresAt, &coupon.MaxUses, &coupon.PerUserLimit, &coupon.Uses,
+		&coupon.CreatedAt)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	coupon.Amount = amount.value()
+
+	return &coupon, nil
+}
+
+func (r *pricingRepo) CreateCoupon(coupon *models.Coupon) error {
+	query := `INSERT INTO coupons (id, code, created_by, creator_id, content_id, kind, percent, amount, currency,
+              starts_at, expires_at, max_uses, per_user_limit, uses, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+
+	amount, currency := moneyArgs(coupon.Amount)
+	_, err := r.db.Exec(query, coupon.CouponID, coupon.Code, coupon.CreatedBy, coupon.CreatorID, coupon.ContentID,
+		coupon.Kind, coupon.Percent, amount, currency, coupon.StartsAt, coupon.ExpiresAt, coupon.MaxUses, coupon.PerUserLimit,
+		coupon.Uses, coupon.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *pricingRepo) GetCouponByCode(code string) (*models.Coupon, error) {
+	query := "SELECT " + couponColumns + " FROM coupons WHERE code = $1"
+
+	return scanCoupon(r.db.QueryRow(query, code))
+}
+
+func (r *pricingRepo) GetCouponsByCreator(createdBy string) ([]*models.Coupon, error) {
+	query := "SELECT " + couponColumns + " FROM coupons WHERE created_by = $1 ORDER BY created_at DESC"
+
+	rows, err := r.db.Query(query, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var coupons []*models.Coupon
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return coupons, nil
+}
+
+func (r *pricingRepo) CreateSale(sale *models.Sale) error {
+	query := `INSERT INTO content_sales (id, content_id, amount, currency, starts_at, ends_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.Exec(query, sale.SaleID, sale.ContentID, sale.SalePrice.Amount, sale.SalePrice.Currency,
+		sale.StartsAt, sale.EndsAt, sale.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *pricingRepo) GetActiveSale(contentId, currency string, at time.Time) (*models.Sale, error) {
+	query := `SELECT id, content_id, amount, currency, starts_at, ends_at, created_at FROM content_sales
+              WHERE content_id = $1 AND currency = $2 AND starts_at <= $3 AND ends_at > $3 ORDER BY amount LIMIT 1`
+
+	var sale models.Sale
+	err := r.db.QueryRow(query, contentId, currency, at).Scan(&sale.SaleID, &sale.ContentID, &sale.SalePrice.Amount,
+		&sale.SalePrice.Currency, &sale.StartsAt, &sale.EndsAt, &sale.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sale, nil
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+const (
+	jobWatermark = "watermark"
+	jobRendition = "rendition"
+	jobPreview   = "preview"
+)
+
+// notBackingOff filters out content whose current file failed the job recently. Each failure doubles the wait, from
+// 15 minutes up to a day.
+func notBackingOff(job string) string {
+	return fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM processing_failures f
+                  WHERE f.content_id = c.id AND f.job = '%s' AND f.source_file_id = c.file_id AND f.retry_at > NOW())`,
+		job)
+}
+
+func recordFailure(db execer, job string, content *models.Content, reason string) error {
+	query := `INSERT INTO processing_failures (content_id, job, source_file_id, attempts, last_error, failed_at, retry_at)
+              VALUES ($1, $2, $3, 1, $4, NOW(), NOW() + INTERVAL '15 minutes')
+              ON CONFLICT (content_id, job) DO UPDATE SET
+                  attempts = CASE WHEN processing_failures.source_file_id = EXCLUDED.source_file_id
+                      THEN processing_failures.attempts + 1 ELSE 1 END,
+                  retry_at = NOW() + LEAST(INTERVAL '1 day', INTERVAL '15 minutes' * POWER(2,
+                      CASE WHEN processing_failures.source_file_id = EXCLUDED.source_file_id
+                      THEN processing_failures.attempts ELSE 0 END)),
+                  source_file_id = EXCLUDED.source_file_id,
+                  last_error = EXCLUDED.last_error,
+                  failed_at = EXCLUDED.failed_at`
+
+	_, err := db.Exec(query, content.ContentID, job, content.FileID, reason)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
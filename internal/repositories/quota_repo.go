@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type QuotaRepository interface {
+	GetTier(userId string) (*models.StorageTier, string, error)
+	GetUsage(userId string) (int64, int, error)
+	GetTiers() ([]*models.StorageTier, error)
+	SetTier(userId, tier string) error
+}
+
+var (
+	ErrUnknownTier      = errors.New("unknown storage tier")
+	ErrQuotaExceeded    = errors.New("storage quota exceeded")
+	ErrItemLimitReached = errors.New("content item limit reached")
+)
+
+type quotaRepo struct {
+	db *sql.DB
+}
+
+func NewQuotaRepository(db *sql.DB) QuotaRepository {
+	return &quotaRepo{db: db}
+}
+
+// GetTier returns the user's storage tier along with their role.
+func (r *quotaRepo) GetTier(userId string) (*models.StorageTier, string, error) {
+	query := `SELECT u.role, t.name, t.max_bytes, t.max_items, t.max_file_size
+              FROM users u JOIN storage_tiers t ON t.name = u.storage_tier WHERE u.id = $1`
+
+	var tier models.StorageTier
+	var role string
+	err := r.db.QueryRow(query, userId).Scan(&role, &tier.Name, &tier.MaxBytes, &tier.MaxItems, &tier.MaxFileSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &tier, role, nil
+}
+
+func (r *quotaRepo) GetUsage(userId string) (int64, int, error) {
+	query := "SELECT COALESCE(SUM(file_size), 0), COUNT(*) FROM content WHERE creator_id = $1 AND deleted_at IS NULL"
+
+	var bytes int64
+	var items int
+	if err := r.db.QueryRow(query, userId).Scan(&bytes, &items); err != nil {
+		return 0, 0, err
+	}
+
+	return bytes, items, nil
+}
+
+func (r *quotaRepo) GetTiers() ([]*models.StorageTier, error) {
+	query := "SELECT name, max_bytes, max_items, max_file_size FROM storage_tiers ORDER BY max_bytes NULLS LAST"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []*models.StorageTier
+	for rows.Next() {
+		var tier models.StorageTier
+		if err := rows.Scan(&tier.Name, &tier.MaxBytes, &tier.MaxItems, &tier.MaxFileSize); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, &tier)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tiers, nil
+}
+
+func (r *quotaRepo) SetTier(userId, tier string) error {
+	res, err := r.db.Exec("UPDATE users SET storage_tier = $2 WHERE id = $1", userId, tier)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrUnknownTier
+		}
+		return err
+	}
+
+	return expectRows(res)
+}
+
+// reserveQuota checks that storing content, as a new item or in place of its current file, keeps the creator within
+// their tier. It locks the creator's row until tx ends, so concurrent uploads by one creator are checked one at a time
+// against usage that includes each other. Call it before writing the content row.
+func reserveQuota(tx *sql.Tx, content *models.Content, newItem bool) error {
+	query := `SELECT u.role, t.max_bytes, t.max_items
+              FROM users u JOIN storage_tiers t ON t.name = u.storage_tier WHERE u.id = $1 FOR UPDATE OF u`
+
+	var role string
+	var maxBytes *int64
+	var maxItems *int
+	if err := tx.QueryRow(query, content.CreatorID).Scan(&role, &maxBytes, &maxItems); err != nil {
+		return err
+	}
+
+	if role == models.RoleAdmin {
+		return nil
+	}
+
+	if !newItem {
+		var current int64
+		err := tx.QueryRow("SELECT file_size FROM content WHERE id = $1", content.ContentID).Scan(&current)
+		if err != nil {
+			return err
+		}
+
+		// Shrinking or keeping a file frees space, so it is allowed even over a quota lowered since.
+		if content.FileSize <= current {
+			return nil
+		}
+	}
+
+	usage := `SELECT COALESCE(SUM(file_size), 0), COUNT(*) FROM content
+              WHERE creator_id = $1 AND deleted_at IS NULL AND id <> $2`
+
+	var used int64
+	var items int
+	if err := tx.QueryRow(usage, content.CreatorID, content.ContentID).Scan(&used, &items); err != nil {
+		return err
+	}
+
+	if newItem && maxItems != nil && items >= *maxItems {
+		return ErrItemLimitReached
+	}
+	if maxBytes != nil && used+content.FileSize > *maxBytes {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type RefundRepository interface {
+	Create(refund *models.Refund) error
+	GetById(id string) (*models.Refund, error)
+	GetByUser(userId string) ([]*models.Refund, error)
+	GetPending(creatorId string) ([]*models.Refund, error)
+	Reject(id, decidedBy string, at time.Time) error
+	Claim(refund *models.Refund, at time.Time) error
+	Complete(reversal *models.Reversal, at time.Time) error
+	Reopen(refund *models.Refund) error
+}
+
+var ErrRefundExists = errors.New("refund already open for order")
+
+type refundRepo struct {
+	db *sql.DB
+}
+
+func NewRefundRepository(db *sql.DB) RefundRepository {
+	return &refundRepo{db: db}
+}
+
+const refundColumns = "id, order_id, user_id, creator_id, reason, status, decided_by, decided_at, created_at"
+
+func scanRefund(row rowScanner) (*models.Refund, error) {
+	var refund models.Refund
+	err := row.Scan(&refund.RefundID, &refund.OrderID, &refund.UserID, &refund.CreatorID, &refund.Reason,
+		&refund.Status, &refund.DecidedBy, &refund.DecidedAt, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &refund, nil
+}
+
+func (r *refundRepo) queryRefunds(query string, args ...any) ([]*models.Refund, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*models.Refund
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+func (r *refundRepo) Create(refund *models.Refund) error {
+	query := `INSERT INTO refunds (id, order_id, user_id, creator_id, reason, status, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.Exec(query, refund.RefundID, refund.OrderID, refund.UserID, refund.CreatorID, refund.Reason,
+		refund.Status, refund.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrRefundExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundRepo) GetById(id string) (*models.Refund, error) {
+	query := "SELECT " + refundColumns + " FROM refunds WHERE id = $1"
+
+	return scanRefund(r.db.QueryRow(query, id))
+}
+
+func (r *refundRepo) GetByUser(userId string) ([]*models.Refund, error) {
+	query := "SELECT " + refundColumns + " FROM refunds WHERE user_id = $1 ORDER BY created_at DESC"
+
+	return r.queryRefunds(query, userId)
+}
+
+func (r *refundRepo) GetPending(creatorId string) ([]*models.Refund, error) {
+	query := "SELECT " + refundColumns + ` FROM refunds
+              WHERE status = 'pending' AND ($1 = '' OR creator_id::text = $1) ORDER BY created_at`
+
+	return r.queryRefunds(query, creatorId)
+}
+
+func (r *refundRepo) Reject(id, decidedBy string, at time.Time) error {
+	query := `UPDATE refunds SET status = 'rejected', decided_by = $2, decided_at = $3
+              WHERE id = $1 AND status = 'pending'`
+
+	res, err := r.db.Exec(query, id, decidedBy, at)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+// Claim takes a pending refund for payout, so it is only ever paid out once.
+func (r *refundRepo) Claim(refund *models.Refund, at time.Time) error {
+	query := `UPDATE refunds SET status = 'refunding', decided_by = $2, decided_at = $3
+              WHERE id = $1 AND status = 'pending'`
+
+	res, err := r.db.Exec(query, refund.RefundID, refund.DecidedBy, at)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+// Complete marks a claimed refund and its order refunded, revokes what the order granted and records the reversing
+// ledger entries in the same transaction, so an order is never refunded while the buyer keeps access or the creator
+// keeps the earnings.
+func (r *refundRepo) Complete(reversal *models.Reversal, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	refund, order := reversal.Refund, reversal.Order
+	res, err := tx.Exec("UPDATE refunds SET status = 'refunded' WHERE id = $1 AND status = 'refunding'", refund.RefundID)
+	if err != nil {
+		return err
+	}
+	if err = expectRows(res); err != nil {
+		return err
+	}
+
+	res, err = tx.Exec("UPDATE orders SET refunded_at = $2 WHERE id = $1 AND refunded_at IS NULL", order.OrderID, at)
+	if err != nil {
+		return err
+	}
+	if err = expectRows(res); err != nil {
+		return err
+	}
+
+	if order.CollectionID != nil {
+		_, err = tx.Exec("UPDATE collection_purchases SET expires_at = $2 WHERE id = $1 AND expires_at > $2",
+			order.OrderID, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec("DELETE FROM licenses WHERE order_id = $1", order.OrderID); err != nil {
+		return err
+	}
+
+	// Content the buyer still holds through another order, a gift or a transfer keeps its session keys.
+	ids := make([]uuid.UUID, len(reversal.ContentIDs))
+	for i, contentId := range reversal.ContentIDs {
+		ids[i] = uuid.FromStringOrNil(contentId)
+	}
+	_, err = tx.Exec(`DELETE FROM session_keys sk WHERE sk.user_id = $1 AND sk.content_id = ANY($2::uuid[])
+              AND NOT EXISTS (SELECT 1 FROM licenses l
+                  WHERE l.user_id = sk.user_id AND l.content_id = sk.content_id AND l.expires_at > $3)`,
+		order.UserID, ids, at)
+	if err != nil {
+		return err
+	}
+
+	if err = insertEntries(tx, reversal.Entries); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *refundRepo) Reopen(refund *models.Refund) error {
+	query := `UPDATE refunds SET status = 'pending', decided_by = NULL, decided_at = NULL
+              WHERE id = $1 AND status = 'refunding'`
+
+	_, err := r.db.Exec(query, refund.RefundID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type RenditionRepository interface {
+	GetPending(limit int) ([]*models.Content, error)
+	RecordFailure(content *models.Content, reason string) error
+	Set(rendition *models.Rendition) ([]string, error)
+	Get(contentId string) (*models.Rendition, error)
+}
+
+type renditionRepo struct {
+	db *sql.DB
+}
+
+func NewRenditionRepository(db *sql.DB) RenditionRepository {
+	return &renditionRepo{db: db}
+}
+
+// GetPending returns video content without a watermarked rendition of its current file. Imported renditions are
+// included so they are replaced once the file can be packaged here.
+func (r *renditionRepo) GetPending(limit int) ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + ` FROM content c
+              WHERE deleted_at IS NULL AND purged_at IS NULL AND mime_type LIKE 'video/%' AND NOT EXISTS (
+                  SELECT 1 FROM renditions rd
+                  WHERE rd.content_id = c.id AND rd.source_file_id = c.file_id AND rd.watermarked)
+              AND ` + notBackingOff(jobRendition) + " ORDER BY created_at LIMIT $1"
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contents []*models.Content
+	for rows.Next() {
+		content, err := scanContent(rows)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+func (r *renditionRepo) RecordFailure(content *models.Content, reason string) error {
+	return recordFailure(r.db, jobRendition, content, reason)
+}
+
+// Set replaces the rendition of a content item and returns the file ids of everything it replaced.
+func (r *renditionRepo) Set(rendition *models.Rendition) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT file_id FROM rendition_segments WHERE content_id = $1
+              UNION ALL SELECT init_file_id FROM renditions WHERE content_id = $1 AND init_file_id IS NOT NULL`,
+		rendition.ContentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for rows.Next() {
+		var fileId string
+		if err := rows.Scan(&fileId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stale = append(stale, fileId)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec("DELETE FROM renditions WHERE content_id = $1", rendition.ContentID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO renditions (content_id, source_file_id, format, method, encryption_key, init_file_id,
+              target_duration, watermarked, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		rendition.ContentID, rendition.SourceFileID, rendition.Format, rendition.Method, rendition.Key,
+		rendition.InitFileID, rendition.TargetDuration, rendition.Watermarked, rendition.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO rendition_segments (content_id, idx, variant, file_id, duration, file_size)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+
+	for _, segment := range rendition.Segments {
+		_, err = tx.Exec(query, rendition.ContentID, segment.Index, segment.Variant, segment.FileID, segment.Duration,
+			segment.FileSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+func (r *renditionRepo) Get(contentId string) (*models.Rendition, error) {
+	query := `SELECT content_id, source_file_id, format, method, encryption_key, init_file_id, target_duration,
+              watermarked, created_at
+              FROM renditions WHERE content_id = $1`
+
+	var rendition models.Rendition
+	err := r.db.QueryRow(query, contentId).Scan(&rendition.ContentID, &rendition.SourceFileID, &rendition.Format,
+		&rendition.Method, &rendition.Key, &rendition.InitFileID, &rendition.TargetDuration, &rendition.Watermarked,
+		&rendition.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT idx, variant, file_id, duration, file_size FROM rendition_segments
+              WHERE content_id = $1 ORDER BY idx, variant`, contentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var segment models.RenditionSegment
+		err := rows.Scan(&segment.Index, &segment.Variant, &segment.FileID, &segment.Duration, &segment.FileSize)
+		if err != nil {
+			return nil, err
+		}
+		rendition.Segments = append(rendition.Segments, &segment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &rendition, nil
+}
@@ -10,6 +10,7 @@ type SessionKeyRepository interface {
 	Create(sessionKey *models.SessionKey) error
 	Get(userId, contentId string) (*models.SessionKey, error)
 	Delete(keyId string) error
+	DeleteForContent(userId, contentId string) error
 }
 
 type sessionKeyRepo struct {
@@ -57,3 +58,14 @@ func (r *sessionKeyRepo) Delete(keyId string) error {
 
 	return nil
 }
+
+func (r *sessionKeyRepo) DeleteForContent(userId, contentId string) error {
+	query := "DELETE FROM session_keys WHERE user_id = $1 AND content_id = $2"
+
+	_, err := r.db.Exec(query, userId, contentId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type SimilarityRepository interface {
+	CreateCheck(check *models.SimilarityCheck) error
+	GetChecks(contentId string) ([]*models.SimilarityCheck, error)
+	CreateDispute(dispute *models.Dispute) error
+	GetDispute(id string) (*models.Dispute, error)
+	GetDisputesByCreator(creatorId string) ([]*models.Dispute, error)
+	GetOpenDisputes() ([]*models.Dispute, error)
+	ResolveDispute(dispute *models.Dispute, contentStatus string) error
+}
+
+var ErrDisputeExists = errors.New("dispute already open for content")
+
+type similarityRepo struct {
+	db *sql.DB
+}
+
+func NewSimilarityRepository(db *sql.DB) SimilarityRepository {
+	return &similarityRepo{db: db}
+}
+
+func (r *similarityRepo) CreateCheck(check *models.SimilarityCheck) error {
+	query := `INSERT INTO similarity_checks (content_id, file_id, matched_id, score, similar, exempt, model_version,
+              threshold, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+
+	return r.db.QueryRow(query, check.ContentID, check.FileID, check.MatchedID, check.Score, check.Similar,
+		check.Exempt, check.ModelVersion, check.Threshold, check.CreatedAt).Scan(&check.CheckID)
+}
+
+func (r *similarityRepo) GetChecks(contentId string) ([]*models.SimilarityCheck, error) {
+	query := `SELECT id, content_id, file_id, matched_id, score, similar, exempt, model_version, threshold, created_at
+              FROM similarity_checks WHERE content_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, contentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []*models.SimilarityCheck
+	for rows.Next() {
+		var check models.SimilarityCheck
+		err := rows.Scan(&check.CheckID, &check.ContentID, &check.FileID, &check.MatchedID, &check.Score, &check.Similar,
+			&check.Exempt, &check.ModelVersion, &check.Threshold, &check.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, &check)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
+
+const disputeColumns = "id, content_id, check_id, creator_id, reason, status, note, decided_by, decided_at, created_at"
+
+func scanDispute(row rowScanner) (*models.Dispute, error) {
+	var dispute models.Dispute
+	err := row.Scan(&dispute.DisputeID, &dispute.ContentID, &dispute.CheckID, &dispute.CreatorID, &dispute.Reason,
+		&dispute.Status, &dispute.Note, &dispute.DecidedBy, &dispute.DecidedAt, &dispute.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dispute, nil
+}
+
+func (r *similarityRepo) queryDisputes(query string, args ...any) ([]*models.Dispute, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var disputes []*models.Dispute
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, dispute)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return disputes, nil
+}
+
+func (r *similarityRepo) CreateDispute(dispute *models.Dispute) error {
+	query := `INSERT INTO similarity_disputes (id, content_id, check_id, creator_id, reason, status, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.Exec(query, dispute.DisputeID, dispute.ContentID, dispute.CheckID, dispute.CreatorID, dispute.Reason,
+		dispute.Status, dispute.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDisputeExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *similarityRepo) GetDispute(id string) (*models.Dispute, error) {
+	query := "SELECT " + disputeColumns + " FROM similarity_disputes WHERE id = $1"
+
+	return scanDispute(r.db.QueryRow(query, id))
+}
+
+func (r *similarityRepo) GetDisputesByCreator(creatorId string) ([]*models.Dispute, error) {
+	query := "SELECT " + disputeColumns + " FROM similarity_disputes WHERE creator_id = $1 ORDER BY created_at DESC"
+
+	return r.queryDisputes(query, creatorId)
+}
+
+func (r *similarityRepo) GetOpenDisputes() ([]*models.Dispute, error) {
+	query := "SELECT " + disputeColumns + " FROM similarity_disputes WHERE status = 'open' ORDER BY created_at"
+
+	return r.queryDisputes(query)
+}
+
+// ResolveDispute closes an open dispute and moves its content to contentStatus in one transaction.
+func (r *similarityRepo) ResolveDispute(dispute *models.Dispute, contentStatus string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE similarity_disputes SET status = $2, note = $3, decided_by = $4, decided_at = $5
+              WHERE id = $1 AND status = 'open'`, dispute.DisputeID, dispute.Status, dispute.Note, dispute.DecidedBy,
+		dispute.DecidedAt)
+	if err != nil {
+		return err
+	}
+	if err = expectRows(res); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE content SET similarity_status = $2, updated_at = $3 WHERE id = $1",
+		dispute.ContentID, contentStatus, dispute.DecidedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
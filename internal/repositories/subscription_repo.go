@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type SubscriptionRepository interface {
+	CreatePlan(plan *models.Plan) error
+	GetPlan(id string) (*models.Plan, error)
+	GetPlans(creatorId string) ([]*models.Plan, error)
+	Create(subscription *models.Subscription) error
+	GetById(id string) (*models.Subscription, error)
+	GetByUser(userId string) ([]*models.Subscription, error)
+	Update(subscription *models.Subscription) error
+	GetDue(at time.Time) ([]*models.Subscription, error)
+	HasAccess(userId, contentId string, at time.Time) (bool, error)
+	GetAccessible(userId string, contentIds []string, at time.Time) ([]string, error)
+}
+
+var ErrSubscriptionExists = errors.New("user already has a current subscription to the plan")
+
+type subscriptionRepo struct {
+	db *sql.DB
+}
+
+func NewSubscriptionRepository(db *sql.DB) SubscriptionRepository {
+	return &subscriptionRepo{db: db}
+}
+
+const planColumns = `id, name, scope, creator_id, price_amount, price_currency, period_days, grace_days, active,
+              created_at`
+
+const subscriptionColumns = `id, plan_id, user_id, status, current_period_start, current_period_end,
+              cancel_at_period_end, canceled_at, created_at`
+
+// Active and past due subscriptions stay entitled for the plan's grace period unless set to cancel.
+func subscriptionAccess(userId, at, contentAlias string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM subscriptions s JOIN plans p ON p.id = s.plan_id
+              WHERE s.user_id = %[1]s AND s.status IN ('active', 'past_due')
+              AND (p.scope = 'platform' OR p.creator_id = %[3]s.creator_id)
+              AND s.current_period_end + make_interval(days => CASE WHEN s.cancel_at_period_end THEN 0 ELSE p.grace_days END) > %[2]s)`,
+		userId, at, contentAlias)
+}
+
+func (r *subscriptionRepo) CreatePlan(plan *models.Plan) error {
+	query := `INSERT INTO plans (id, name, scope, creator_id, price_amount, price_currency, period_days, grace_days,
+              active, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.Exec(query, plan.PlanID, plan.Name, plan.Scope, plan.CreatorID, plan.Price.Amount,
+		plan.Price.Currency, plan.PeriodDays, plan.GraceDays, plan.Active, plan.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanPlan(row rowScanner) (*models.Plan, error) {
+	var plan models.Plan
+	err := row.Scan(&plan.PlanID, &plan.Name, &plan.Scope, &plan.CreatorID, &plan.Price.Amount, &plan.Price.Currency,
+		&plan.PeriodDays, &plan.GraceDays, &plan.Active, &plan.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+func (r *subscriptionRepo) GetPlan(id string) (*models.Plan, error) {
+	query := "SELECT " + planColumns + " FROM plans WHERE id = $1"
+
+	return scanPlan(r.db.QueryRow(query, id))
+}
+
+func (r *subscriptionRepo) GetPlans(creatorId string) ([]*models.Plan, error) {
+	query := "SELECT " + planColumns + " FROM plans WHERE active AND ($1 = '' OR creator_id::text = $1) ORDER BY price_amount"
+
+	rows, err := r.db.Query(query, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []*models.Plan
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+func scanSubscription(row rowScanner) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := row.Scan(&subscription.SubscriptionID, &subscription.PlanID, &subscription.UserID, &subscription.Status,
+		&subscription.CurrentPeriodStart, &subscription.CurrentPeriodEnd, &subscription.CancelAtPeriodEnd,
+		&subscription.CanceledAt, &subscription.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+func (r *subscriptionRepo) querySubscriptions(query string, args ...any) ([]*models.Subscription, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*models.Subscription
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *subscriptionRepo) Create(subscription *models.Subscription) error {
+	query := `INSERT INTO subscriptions (id, plan_id, user_id, status, current_period_start, current_period_end,
+              cancel_at_period_end, canceled_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.Exec(query, subscription.SubscriptionID, subscription.PlanID, subscription.UserID,
+		subscription.Status, subscription.CurrentPeriodStart, subscription.CurrentPeriodEnd,
+		subscription.CancelAtPeriodEnd, subscription.CanceledAt, subscription.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrSubscriptionExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepo) GetById(id string) (*models.Subscription, error) {
+	query := "SELECT " + subscriptionColumns + " FROM subscriptions WHERE id = $1"
+
+	return scanSubscription(r.db.QueryRow(query, id))
+}
+
+func (r *subscriptionRepo) GetByUser(userId string) ([]*models.Subscription, error) {
+	query := "SELECT " + subscriptionColumns + " FROM subscriptions WHERE user_id = $1 ORDER BY created_at DESC"
+
+	return r.querySubscriptions(query, userId)
+}
+
+func (r *subscriptionRepo) Update(subscription *models.Subscription) error {
+	query := `UPDATE subscriptions SET status = $2, current_period_start = $3, current_period_end = $4,
+              cancel_at_period_end = $5, canceled_at = $6 WHERE id = $1`
+
+	res, err := r.db.Exec(query, subscription.SubscriptionID, subscription.Status, subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd, subscription.CancelAtPeriodEnd, subscription.CanceledAt)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (r *subscriptionRepo) GetDue(at time.Time) ([]*models.Subscription, error) {
+	query := "SELECT " + subscriptionColumns + ` FROM subscriptions
+              WHERE status IN ('active', 'past_due') AND current_period_end <= $1`
+
+	return r.querySubscriptions(query, at)
+}
+
+func (r *subscriptionRepo) HasAccess(userId, contentId string, at time.Time) (bool, error) {
+	query := "SELECT " + subscriptionAccess("$1", "$2", "c") + " FROM content c WHERE c.id = $3"
+
+	var ok bool
+	err := r.db.QueryRow(query, userId, at, contentId).Scan(&ok)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return ok, nil
+}
+
+func (r *subscriptionRepo) GetAccessible(userId string, contentIds []string, at time.Time) ([]string, error) {
+	query := "SELECT c.id::text FROM content c WHERE c.id = ANY($3::uuid[]) AND " + subscriptionAccess("$1", "$2", "c")
+
+	ids := make([]uuid.UUID, len(contentIds))
+	for i, contentId := range contentIds {
+		ids[i] = uuid.FromStringOrNil(contentId)
+	}
+
+	rows, err := r.db.Query(query, userId, at, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accessible []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		accessible = append(accessible, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accessible, nil
+}
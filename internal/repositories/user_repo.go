@@ -20,9 +20,9 @@ func NewUserRepository(db *sql.DB) UserRepository {
 }
 
 func (r *userRepo) Create(user *models.User) error {
-	query := "INSERT INTO users (id, email, name, password) VALUES ($1, $2, $3, $4)"
+	query := "INSERT INTO users (id, email, name, password, role) VALUES ($1, $2, $3, $4, $5)"
 
-	_, err := r.db.Exec(query, user.UserID, user.Email, user.UserName, user.Password)
+	_, err := r.db.Exec(query, user.UserID, user.Email, user.UserName, user.Password, user.Role)
 	if err != nil {
 		return err
 	}
@@ -32,9 +32,9 @@ func (r *userRepo) Create(user *models.User) error {
 
 func (r *userRepo) GetByEmail(email string) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, name, email, password FROM users WHERE email = $1`
+	query := `SELECT id, name, email, password, role FROM users WHERE email = $1`
 
-	err := r.db.QueryRow(query, email).Scan(&user.UserID, &user.UserName, &user.Email, &user.Password)
+	err := r.db.QueryRow(query, email).Scan(&user.UserID, &user.UserName, &user.Email, &user.Password, &user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+)
+
+type WatermarkRepository interface {
+	GetPending(limit int) ([]*models.Content, error)
+	RecordFailure(content *models.Content, reason string) error
+	SetSegments(contentId, sourceFileId string, segments []*models.WatermarkSegment) ([]string, error)
+	GetSegments(contentId, sourceFileId string) ([]*models.WatermarkSegment, error)
+	CreateMark(mark *models.Watermark) (*models.Watermark, error)
+	GetMarks(contentId string) ([]*models.Watermark, error)
+}
+
+type watermarkRepo struct {
+	db *sql.DB
+}
+
+func NewWatermarkRepository(db *sql.DB) WatermarkRepository {
+	return &watermarkRepo{db: db}
+}
+
+func (r *watermarkRepo) GetPending(limit int) ([]*models.Content, error) {
+	query := "SELECT " + contentColumns + ` FROM content c
+              WHERE deleted_at IS NULL AND purged_at IS NULL AND mime_type LIKE 'video/%' AND NOT EXISTS (
+                  SELECT 1 FROM watermark_segments s WHERE s.content_id = c.id AND s.source_file_id = c.file_id)
+              AND ` + notBackingOff(jobWatermark) + " ORDER BY created_at LIMIT $1"
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contents []*models.Content
+	for rows.Next() {
+		content, err := scanContent(rows)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+func (r *watermarkRepo) RecordFailure(content *models.Content, reason string) error {
+	return recordFailure(r.db, jobWatermark, content, reason)
+}
+
+// SetSegments replaces the segments of a content item and returns the file ids of the segments it replaced.
+func (r *watermarkRepo) SetSegments(contentId, sourceFileId string, segments []*models.WatermarkSegment) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("DELETE FROM watermark_segments WHERE content_id = $1 RETURNING file_id", contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for rows.Next() {
+		var fileId string
+		if err := rows.Scan(&fileId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stale = append(stale, fileId)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO watermark_segments (content_id, source_file_id, idx, variant, file_id, file_size)
+              VALUES ($1, $2, $3, $4, $5, $6)`
+
+	for _, segment := range segments {
+		_, err = tx.Exec(query, contentId, sourceFileId, segment.Index, segment.Variant, segment.FileID, segment.FileSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+func (r *watermarkRepo) GetSegments(contentId, sourceFileId string) ([]*models.WatermarkSegment, error) {
+	query := `SELECT content_id, source_file_id, idx, variant, file_id, file_size FROM watermark_segments
+              WHERE content_id = $1 AND source_file_id = $2 ORDER BY idx, variant`
+
+	rows, err := r.db.Query(query, contentId, sourceFileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []*models.WatermarkSegment
+	for rows.Next() {
+		var segment models.WatermarkSegment
+		err := rows.Scan(&segment.ContentID, &segment.SourceFileID, &segment.Index, &segment.Variant, &segment.FileID,
+			&segment.FileSize)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, &segment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func scanWatermark(row rowScanner) (*models.Watermark, error) {
+	var mark models.Watermark
+	var payload int64
+	err := row.Scan(&payload, &mark.ContentID, &mark.UserID, &mark.LicenseID, &mark.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	mark.Payload = uint64(payload)
+
+	return &mark, nil
+}
+
+// CreateMark stores the mark, or returns the stored one if its payload has been handed out before. Payloads are
+// derived from the license or viewer they are served to, so an existing payload is the same mark.
+func (r *watermarkRepo) CreateMark(mark *models.Watermark) (*models.Watermark, error) {
+	query := `INSERT INTO watermarks (payload, content_id, user_id, license_id, created_at)
+              VALUES ($1, $2, $3, $4, $5)
+              ON CONFLICT (payload) DO UPDATE SET payload = watermarks.payload
+              RETURNING payload, content_id, user_id, license_id, created_at`
+
+	return scanWatermark(r.db.QueryRow(query, int64(mark.Payload), mark.ContentID, mark.UserID, mark.LicenseID,
+		mark.CreatedAt))
+}
+
+func (r *watermarkRepo) GetMarks(contentId string) ([]*models.Watermark, error) {
+	query := "SELECT payload, content_id, user_id, license_id, created_at FROM watermarks WHERE content_id = $1"
+
+	rows, err := r.db.Query(query, contentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var marks []*models.Watermark
+	for rows.Next() {
+		mark, err := scanWatermark(rows)
+		if err != nil {
+			return nil, err
+		}
+		marks = append(marks, mark)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return marks, nil
+}
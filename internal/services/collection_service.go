@@ -0,0 +1,253 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/gofrs/uuid"
+)
+
+type CollectionService interface {
+	Create(collection *models.Collection) error
+	Get(id string) (*models.Collection, error)
+	ListByCreator(creatorId string) ([]*models.Collection, error)
+	Update(userId, collectionId string, update *models.CollectionUpdate) (*models.Collection, error)
+	Delete(userId, collectionId string) error
+	SetItems(userId, collectionId string, contentIds []string) error
+	AddItem(userId, collectionId, contentId string) error
+	RemoveItem(userId, collectionId, contentId string) error
+}
+
+var (
+	ErrCollectionNotFound   = errors.New("collection not found")
+	ErrCollectionNotForSale = errors.New("collection is not for sale")
+	ErrAlreadyOwned         = errors.New("you already own everything in this collection")
+)
+
+type collectionService struct {
+	collectionRepo repositories.CollectionRepository
+	contentRepo    repositories.ContentRepository
+	licenseService LicenseService
+}
+
+func NewCollectionService(collectionRepo repositories.CollectionRepository, contentRepo repositories.ContentRepository,
+	licenseService LicenseService) CollectionService {
+	return &collectionService{collectionRepo: collectionRepo, contentRepo: contentRepo, licenseService: licenseService}
+}
+
+func (s *collectionService) Create(collection *models.Collection) error {
+	if collection.Title == "" {
+		return errors.New("collection title cannot be empty")
+	}
+	if collection.Price != nil {
+		if err := normalizeMoney(collection.Price); err != nil {
+			return err
+		}
+	}
+
+	collectionId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	collection.CollectionID = collectionId
+	collection.CreatedAt = time.Now()
+	collection.UpdatedAt = time.Now()
+
+	return s.collectionRepo.Create(collection)
+}
+
+func (s *collectionService) Get(id string) (*models.Collection, error) {
+	collection, err := s.collectionRepo.GetById(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+
+	collection.Items, err = s.collectionRepo.GetItems(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+func (s *collectionService) ListByCreator(creatorId string) ([]*models.Collection, error) {
+	return s.collectionRepo.GetByCreator(creatorId)
+}
+
+func (s *collectionService) getOwned(userId, collectionId string) (*models.Collection, error) {
+	collection, err := s.collectionRepo.GetById(collectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+
+	if collection.CreatorID.String() != userId {
+		return nil, ErrNotCreator
+	}
+
+	return collection, nil
+}
+
+func (s *collectionService) Update(userId, collectionId string, update *models.CollectionUpdate) (*models.Collection, error) {
+	collection, err := s.getOwned(userId, collectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Title != nil {
+		if *update.Title == "" {
+			return nil, errors.New("collection title cannot be empty")
+		}
+		collection.Title = *update.Title
+	}
+	if update.Description != nil {
+		collection.Description = *update.Description
+	}
+	if update.Price != nil {
+		if err := normalizeMoney(update.Price); err != nil {
+			return nil, err
+		}
+		collection.Price = update.Price
+	}
+	if update.GrantNewItems != nil {
+		collection.GrantNewItems = *update.GrantNewItems
+	}
+	collection.UpdatedAt = time.Now()
+
+	if err = s.collectionRepo.Update(collection); err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+func (s *collectionService) Delete(userId, collectionId string) error {
+	if _, err := s.getOwned(userId, collectionId); err != nil {
+		return err
+	}
+
+	return s.collectionRepo.Delete(collectionId)
+}
+
+func (s *collectionService) SetItems(userId, collectionId string, contentIds []string) error {
+	collection, err := s.getOwned(userId, collectionId)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(contentIds))
+	for _, contentId := range contentIds {
+		if seen[contentId] {
+			return errors.New("collection cannot contain the same content twice")
+		}
+		seen[contentId] = true
+
+		if err := s.checkContentOwner(userId, contentId); err != nil {
+			return err
+		}
+	}
+
+	if err := s.collectionRepo.SetItems(collectionId, contentIds); err != nil {
+		return err
+	}
+
+	return s.grantToBuyers(collection, contentIds)
+}
+
+func (s *collectionService) AddItem(userId, collectionId, contentId string) error {
+	collection, err := s.getOwned(userId, collectionId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkContentOwner(userId, contentId); err != nil {
+		return err
+	}
+
+	if err := s.collectionRepo.AddItem(collectionId, contentId); err != nil {
+		return err
+	}
+
+	return s.grantToBuyers(collection, []string{contentId})
+}
+
+func (s *collectionService) RemoveItem(userId, collectionId, contentId string) error {
+	if _, err := s.getOwned(userId, collectionId); err != nil {
+		return err
+	}
+
+	err := s.collectionRepo.RemoveItem(collectionId, contentId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrContentNotFound
+	}
+
+	return err
+}
+
+func (s *collectionService) checkContentOwner(userId, contentId string) error {
+	content, err := s.contentRepo.GetById(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrContentNotFound
+		}
+		return err
+	}
+
+	if content.DeletedAt != nil {
+		return ErrContentNotFound
+	}
+
+	if content.CreatorID.String() != userId {
+		return ErrNotCreator
+	}
+	if !content.Listed() {
+		return ErrContentUnderReview
+	}
+
+	return nil
+}
+
+func (s *collectionService) grantToBuyers(collection *models.Collection, contentIds []string) error {
+	if !collection.GrantNewItems || len(contentIds) == 0 {
+		return nil
+	}
+
+	purchases, err := s.collectionRepo.GetActivePurchases(collection.CollectionID.String())
+	if err != nil {
+		return err
+	}
+
+	for _, purchase := range purchases {
+		userId := purchase.UserID.String()
+
+		owned, err := s.licenseService.Licensed(userId, contentIds)
+		if err != nil {
+			return err
+		}
+
+		var missing []string
+		for _, contentId := range contentIds {
+			if !owned[contentId] {
+				missing = append(missing, contentId)
+			}
+		}
+
+		if len(missing) == 0 {
+			continue
+		}
+
+		if err := s.licenseService.GenerateBatch(userId, &purchase.PurchaseID, missing, purchase.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
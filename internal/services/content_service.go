@@ -1,148 +1,887 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
-	"net/http"
-	"path/filepath"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/similarity"
 	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
 	"github.com/gofrs/uuid"
 )
 
 type ContentService interface {
-	Create(content *models.Content, file io.Reader, fileExt string, fileSize int64) (string, bool, float64, error)
-	Get(id string) (*models.Content, []byte, error)
-	List() ([]*models.Content, error)
+	Create(content *models.Content, file io.Reader, fileExt string, fileSize int64) (*models.SimilarityCheck, bool, error)
+	Get(id string) (*models.Content, io.ReadSeekCloser, error)
+	GetMetadata(id string) (*models.Content, error)
+	List(filter *models.ContentFilter) ([]*models.Content, string, error)
+	Update(userId, contentId string, update *models.ContentUpdate) (*models.Content, error)
+	ReplaceFile(userId, contentId string, file io.Reader, fileExt string, fileSize int64) (*models.SimilarityCheck, bool, error)
+	Delete(userId, contentId string) error
+	PurgeDeleted() error
+	RecheckUnchecked() error
+	ScrubChecksums() error
+	ListCorrupt() ([]*models.Content, error)
+	ListCategories() ([]*models.Category, error)
+	SetCategoryThreshold(slug string, threshold *float64) (*models.Category, error)
 }
 
+var (
+	ErrContentNotFound    = errors.New("content not found")
+	ErrNotCreator         = errors.New("only the creator can modify this content")
+	ErrInvalidFilter      = errors.New("invalid content filter")
+	ErrUnknownCategory    = errors.New("unknown category")
+	ErrInvalidTag         = errors.New("invalid tag")
+	ErrInvalidThreshold   = errors.New("similarity threshold must be greater than 0 and at most 1")
+	ErrContentUnderReview = errors.New("content is under review")
+	ErrContentCorrupt     = errors.New("stored file failed its integrity check")
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+	recheckBatchSize = 20
+	scrubBatchSize   = 50
+	maxTagLength     = 64
+	// scrubInterval is how long a verified file goes before the scrubber hashes it again.
+	scrubInterval = 7 * 24 * time.Hour
+	// exactMatchVersion marks checks settled by an identical checksum without asking the similarity service.
+	exactMatchVersion = "sha256"
+	// sniffLen is as much of a file as content type detection looks at.
+	sniffLen = 512
+)
+
 type contentService struct {
-	contentRepo        repositories.ContentRepository
-	storage            *storage.FileStorage
-	similarityCheckURL string
+	contentRepo     repositories.ContentRepository
+	licenseRepo     repositories.LicenseRepository
+	categoryRepo    repositories.CategoryRepository
+	storage         storage.Storage
+	similarityRepo  repositories.SimilarityRepository
+	fingerprintRepo repositories.FingerprintRepository
+	similarity      similarity.Checker
+	policy          similarity.UnavailablePolicy
+	quota           QuotaService
+	// similarityThreshold is the score at or above which a match counts as a duplicate.
+	similarityThreshold float64
 }
 
-func NewContentService(contentRepo repositories.ContentRepository, storage *storage.FileStorage, similarityCheckURL string) ContentService {
-	return &contentService{contentRepo: contentRepo, storage: storage, similarityCheckURL: similarityCheckURL}
+func NewContentService(contentRepo repositories.ContentRepository, licenseRepo repositories.LicenseRepository,
+	categoryRepo repositories.CategoryRepository, similarityRepo repositories.SimilarityRepository,
+	fingerprintRepo repositories.FingerprintRepository, storage storage.Storage, similarity similarity.Checker,
+	policy similarity.UnavailablePolicy, similarityThreshold float64, quota QuotaService) ContentService {
+	return &contentService{contentRepo: contentRepo, licenseRepo: licenseRepo, categoryRepo: categoryRepo,
+		similarityRepo: similarityRepo, fingerprintRepo: fingerprintRepo, storage: storage, similarity: similarity,
+		policy: policy, similarityThreshold: similarityThreshold, quota: quota}
 }
 
-func (s *contentService) Create(content *models.Content, file io.Reader, fileExt string, fileSize int64) (string, bool, float64, error) {
+func (s *contentService) Create(content *models.Content, file io.Reader, fileExt string, fileSize int64) (*models.SimilarityCheck, bool, error) {
 	if content.Title == "" {
-		return "", false, 0, errors.New("content title cannot be empty")
+		return nil, false, errors.New("content title cannot be empty")
 	}
-	if content.Price < 0 {
-		return "", false, 0, errors.New("content price cannot be negative")
+	if err := normalizePrices(content); err != nil {
+		return nil, false, err
+	}
+
+	tags, err := normalizeTags(content.Tags)
+	if err != nil {
+		return nil, false, err
+	}
+	content.Tags = tags
+	if err := s.validateCategories(content.Categories); err != nil {
+		return nil, false, err
 	}
 
 	contentId, err := uuid.NewV4()
 	if err != nil {
-		return "", false, 0, err
+		return nil, false, err
 	}
 	content.ContentID = contentId
 
-	fileBytes, err := io.ReadAll(file)
+	spooled, err := spool(file)
 	if err != nil {
-		return "", false, 0, err
+		return nil, false, err
 	}
+	defer spooled.remove()
+	content.SHA256 = spooled.sha256
 
-	
-	url := s.similarityCheckURL + "/compare-video-bytes"
-	log.Println(url)
-	payload := &bytes.Buffer{}
-	writer := multipart.NewWriter(payload)
+	if err := s.quota.Check(content.CreatorID.String(), spooled.size, 0, true); err != nil {
+		return nil, false, err
+	}
 
-	part1, err := writer.CreateFormFile("file", filepath.Base("file.mp4"))
+	fileType, err := mediatype.Detect(spooled.header, fileExt)
 	if err != nil {
-		return "", false, 0, err
+		return nil, false, err
 	}
 
-	if _, err = part1.Write(fileBytes); err != nil {
-		return "", false, 0, err
+	check, fingerprints, status, err := s.checkSimilarity(content, spooled.file, fileType)
+	if err != nil {
+		return nil, false, err
 	}
 
-	if err = writer.WriteField("file_id", contentId.String()); err != nil {
-		return "", false, 0, err
+	// Rejected uploads are kept, unlisted, so the creator can dispute the match and have them published.
+	if check != nil && check.Similar {
+		status = models.SimilarityRejected
 	}
 
-	if err = writer.Close(); err != nil {
-		return "", false, 0, err
+	if _, err := spooled.file.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	fileId, err := storage.Upload(context.Background(), s.storage, spooled.file, fileType.Extension, fileSize, fileType.MIME)
+	if err != nil {
+		return nil, false, err
 	}
 
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", url, payload)
+	content.FileID = fileId
+	content.FileSize = fileSize
+	content.MimeType = fileType.MIME
+	content.Similarity = status
+
+	err = s.contentRepo.Create(content)
 	if err != nil {
-		return "", false, 0, err
+		s.storage.Delete(context.Background(), fileId)
+		return nil, false, reservationError(err)
+	}
+	s.saveFingerprints(contentId.String(), fingerprints)
+
+	if check == nil {
+		return nil, true, nil
+	}
+
+	check.FileID = &fileId
+	if err = s.similarityRepo.CreateCheck(check); err != nil {
+		return nil, false, err
 	}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	res, err := client.Do(req)
+	return check, !check.Similar, nil
+}
+
+// checkSimilarity also returns the similarity status the file starts in and any fingerprints to save once the file
+// is stored. The check is nil when none ran: kinds of file without a similarity backend count as unique, and an
+// unreachable service is handled according to the configured policy.
+func (s *contentService) checkSimilarity(content *models.Content, file io.ReadSeeker, fileType mediatype.Type) (*models.SimilarityCheck, *similarity.Fingerprints, string, error) {
+	contentId := content.ContentID.String()
+
+	result, err := s.similarityResult(content, file, fileType)
+	switch {
+	case err == nil:
+		check, err := s.assess(content, result)
+		return check, result.Fingerprints, models.SimilarityPassed, err
+	case errors.Is(err, similarity.ErrUnsupportedKind):
+		return nil, nil, models.SimilarityPassed, nil
+	case errors.Is(err, similarity.ErrUnavailable) && s.policy == similarity.QueueWhenUnavailable:
+		log.Printf("accepting %s unchecked: %v\n", contentId, err)
+		return nil, nil, models.SimilarityUnchecked, nil
+	case errors.Is(err, similarity.ErrUnavailable) && s.policy == similarity.FlagWhenUnavailable:
+		log.Printf("accepting %s flagged: %v\n", contentId, err)
+		return nil, nil, models.SimilarityFlagged, nil
+	default:
+		return nil, nil, "", err
+	}
+}
+
+// saveFingerprints indexes the hashes of a file that has been stored. A failure only weakens later checks against
+// this file, so it is logged rather than failing the upload.
+func (s *contentService) saveFingerprints(contentId string, fingerprints *similarity.Fingerprints) {
+	if fingerprints == nil {
+		return
+	}
+
+	if err := s.fingerprintRepo.Save(contentId, fingerprints.Kind, fingerprints.Hashes); err != nil {
+		log.Printf("failed to save fingerprints for content %s: %v\n", contentId, err)
+	}
+}
+
+// similarityResult settles byte-identical copies of stored content by checksum, and asks the similarity service
+// about everything else.
+func (s *contentService) similarityResult(content *models.Content, file io.ReadSeeker, fileType mediatype.Type) (*similarity.Result, error) {
+	if content.SHA256 != "" {
+		match, err := s.contentRepo.GetBySHA256(content.SHA256, content.ContentID.String())
+		if err == nil {
+			return &similarity.Result{MatchID: match.ContentID.String(), Similarity: 1, Similar: true,
+				ModelVersion: exactMatchVersion}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return s.similarity.Check(context.Background(), file, content.ContentID.String(), fileType)
+}
+
+// assess decides whether a score is a match using the platform's threshold rather than the service's own verdict.
+// Matches against the uploader's own content are exempt, since that is usually a re-upload of an improved version.
+func (s *contentService) assess(content *models.Content, result *similarity.Result) (*models.SimilarityCheck, error) {
+	threshold, err := s.threshold(content.Categories)
 	if err != nil {
-		return "", false, 0, err
+		return nil, err
+	}
+
+	check := &models.SimilarityCheck{
+		ContentID:    content.ContentID,
+		Score:        result.Similarity,
+		ModelVersion: result.ModelVersion,
+		Threshold:    &threshold,
+		CreatedAt:    time.Now(),
+	}
+
+	if result.MatchID == "" {
+		return check, nil
+	}
+	check.MatchedID = &result.MatchID
+
+	if result.Similarity < threshold {
+		return check, nil
+	}
+
+	if matchId, err := uuid.FromString(result.MatchID); err == nil {
+		matched, err := s.contentRepo.GetById(matchId.String())
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil && matched.CreatorID == content.CreatorID {
+			check.Exempt = true
+			return check, nil
+		}
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	check.Similar = true
+	return check, nil
+}
+
+// threshold returns the strictest override among the content's categories, or the global threshold if none of them
+// has one.
+func (s *contentService) threshold(slugs []string) (float64, error) {
+	if len(slugs) == 0 {
+		return s.similarityThreshold, nil
+	}
+
+	categories, err := s.categoryRepo.GetAll()
 	if err != nil {
-		return "", false, 0, err
+		return 0, err
 	}
-	var resp struct {
-		VideoID       string  `json:"video_id"`
-		MaxSimilarity float64 `json:"max_similarity"`
-		Similar       bool    `json:"similar"`
+
+	var override *float64
+	for _, category := range categories {
+		if category.SimilarityThreshold == nil || !slices.Contains(slugs, category.Slug) {
+			continue
+		}
+		if override == nil || *category.SimilarityThreshold < *override {
+			override = category.SimilarityThreshold
+		}
 	}
 
-	if err = json.Unmarshal(body, &resp); err != nil {
-		return "", false, 0, err
+	if override == nil {
+		return s.similarityThreshold, nil
 	}
 
-	if resp.Similar {
-		return resp.VideoID, false, resp.MaxSimilarity, nil
+	return *override, nil
+}
+
+// RecheckUnchecked runs the similarity check that was skipped while the service was down. Unique content is
+// cleared, duplicates are moved to review, and the run stops early if the service is still unreachable.
+func (s *contentService) RecheckUnchecked() error {
+	contents, err := s.contentRepo.GetUnchecked(recheckBatchSize)
+	if err != nil {
+		return err
 	}
 
-	fileReader := bytes.NewReader(fileBytes)
-	fileId, err := s.storage.UploadFile(context.Background(), fileReader, fileExt, fileSize)
+	for _, content := range contents {
+		err := s.recheck(content)
+		if errors.Is(err, similarity.ErrUnavailable) {
+			return nil
+		}
+		if err != nil {
+			log.Printf("failed to recheck content %s: %v\n", content.ContentID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *contentService) recheck(content *models.Content) error {
+	contentId := content.ContentID.String()
+
+	file, err := s.storage.Get(context.Background(), content.FileID)
+	if err != nil {
+		return err
+	}
+	spooled, err := spool(file)
+	file.Close()
 	if err != nil {
-		return "", false, 0, err
+		return err
 	}
+	defer spooled.remove()
 
-	content.FileID = fileId
-	content.FileSize = fileSize
+	content.Categories, err = s.contentRepo.GetCategories(contentId)
+	if err != nil {
+		return err
+	}
 
-	err = s.contentRepo.Create(content)
+	result, err := s.similarityResult(content, spooled.file, mediatype.Lookup(content.MimeType))
+	if err != nil && !errors.Is(err, similarity.ErrUnsupportedKind) {
+		return err
+	}
+
+	status := models.SimilarityPassed
+	if result != nil {
+		check, err := s.assess(content, result)
+		if err != nil {
+			return err
+		}
+
+		check.FileID = &content.FileID
+		if err := s.similarityRepo.CreateCheck(check); err != nil {
+			return err
+		}
+
+		if check.Similar {
+			status = models.SimilarityReview
+			log.Printf("content %s matches %s (%.2f), moved to review\n", contentId, result.MatchID, result.Similarity)
+		}
+	}
+
+	err = s.contentRepo.SetSimilarity(contentId, content.FileID, status)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The file was replaced while it was being checked.
+		return nil
+	}
 	if err != nil {
-		return "", false, 0, err
+		return err
+	}
+
+	if result != nil {
+		s.saveFingerprints(contentId, result.Fingerprints)
 	}
 
-	return resp.VideoID, true, resp.MaxSimilarity, nil
+	return nil
 }
 
-func (s *contentService) List() ([]*models.Content, error) {
-	return s.contentRepo.GetAll()
+func (s *contentService) List(filter *models.ContentFilter) ([]*models.Content, string, error) {
+	if filter.Sort == "" {
+		filter.Sort = "created_at"
+	}
+	if filter.Order == "" {
+		filter.Order = "desc"
+	}
+
+	switch filter.Sort {
+	case "created_at", "price", "title":
+	case "relevance":
+		if filter.Query == "" {
+			return nil, "", fmt.Errorf("%w: relevance sort requires a search query", ErrInvalidFilter)
+		}
+	default:
+		return nil, "", fmt.Errorf("%w: unknown sort %q", ErrInvalidFilter, filter.Sort)
+	}
+
+	switch filter.Order {
+	case "asc", "desc":
+	default:
+		return nil, "", fmt.Errorf("%w: unknown order %q", ErrInvalidFilter, filter.Order)
+	}
+
+	if filter.Currency != "" {
+		currency, err := money.NormalizeCurrency(filter.Currency)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		filter.Currency = currency
+	} else if filter.MinPrice != nil || filter.MaxPrice != nil {
+		filter.Currency = money.DefaultCurrency
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+
+	contents, nextCursor, err := s.contentRepo.List(filter)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		return nil, "", err
+	}
+
+	return contents, nextCursor, nil
 }
 
-func (s *contentService) Get(id string) (*models.Content, []byte, error) {
+func (s *contentService) GetMetadata(id string) (*models.Content, error) {
 	content, err := s.contentRepo.GetById(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContentNotFound
+		}
+		return nil, err
+	}
+
+	if content.PurgedAt != nil {
+		return nil, ErrContentNotFound
+	}
+
+	content.Tags, err = s.contentRepo.GetTags(id)
+	if err != nil {
+		return nil, err
+	}
+
+	content.Categories, err = s.contentRepo.GetCategories(id)
+	if err != nil {
+		return nil, err
+	}
+
+	content.Prices, err = s.contentRepo.GetPrices(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// Get opens the stored file of a content item for streaming. Objects are read with ranged requests as the stream is
+// consumed, so serving a range never loads the whole file. Checksums are verified by the scrubber rather than on every
+// read; content it has flagged is refused.
+func (s *contentService) Get(id string) (*models.Content, io.ReadSeekCloser, error) {
+	content, err := s.GetMetadata(id)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	file, err := s.storage.DownloadFile(context.Background(), content.FileID)
+	if content.Corrupt {
+		return nil, nil, ErrContentCorrupt
+	}
+
+	ctx := context.Background()
+
+	info, err := s.storage.Stat(ctx, content.FileID)
 	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("content %s is missing its file %s\n", id, content.FileID)
+			if err := s.contentRepo.SetIntegrity(id, content.FileID, content.SHA256, true, time.Now()); err != nil {
+				log.Printf("failed to mark content %s corrupt: %v\n", id, err)
+			}
+			return nil, nil, ErrContentCorrupt
+		}
 		return nil, nil, err
 	}
+
+	return content, storage.NewReader(ctx, s.storage, []storage.Part{{Key: content.FileID, Size: info.Size}}), nil
+}
+
+func (s *contentService) getOwned(userId, contentId string) (*models.Content, error) {
+	content, err := s.GetMetadata(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if content.DeletedAt != nil {
+		return nil, ErrContentNotFound
+	}
+
+	if content.CreatorID.String() != userId {
+		return nil, ErrNotCreator
+	}
+
+	return content, nil
+}
+
+func (s *contentService) Update(userId, contentId string, update *models.ContentUpdate) (*models.Content, error) {
+	content, err := s.getOwned(userId, contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Title != nil {
+		if *update.Title == "" {
+			return nil, errors.New("content title cannot be empty")
+		}
+		content.Title = *update.Title
+	}
+	if update.Description != nil {
+		content.Description = *update.Description
+	}
+	if update.Price != nil {
+		content.Price = *update.Price
+	}
+	if update.Prices != nil {
+		content.Prices = *update.Prices
+	}
+	if err := normalizePrices(content); err != nil {
+		return nil, err
+	}
+	if update.Transferable != nil {
+		content.Transferable = *update.Transferable
+	}
+	if update.Tags != nil {
+		content.Tags, err = normalizeTags(*update.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if update.Categories != nil {
+		if err := s.validateCategories(*update.Categories); err != nil {
+			return nil, err
+		}
+		content.Categories = *update.Categories
+	}
+	content.UpdatedAt = time.Now()
+
+	err = s.contentRepo.Update(content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContentNotFound
+		}
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func (s *contentService) ReplaceFile(userId, contentId string, file io.Reader, fileExt string, fileSize int64) (*models.SimilarityCheck, bool, error) {
+	content, err := s.getOwned(userId, contentId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	spooled, err := spool(file)
+	if err != nil {
+		return nil, false, err
+	}
+	defer spooled.remove()
+	content.SHA256 = spooled.sha256
+
+	if err := s.quota.Check(userId, spooled.size, content.FileSize, false); err != nil {
+		return nil, false, err
+	}
+
+	fileType, err := mediatype.Detect(spooled.header, fileExt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	check, fingerprints, status, err := s.checkSimilarity(content, spooled.file, fileType)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if check != nil && check.Similar {
+		if err := s.similarityRepo.CreateCheck(check); err != nil {
+			return nil, false, err
+		}
+		return check, false, nil
+	}
+
+	if _, err := spooled.file.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	fileId, err := storage.Upload(context.Background(), s.storage, spooled.file, fileType.Extension, fileSize,
+		fileType.MIME)
+	if err != nil {
+		return nil, false, err
+	}
+
+	oldFileId := content.FileID
+	content.FileID = fileId
+	content.FileSize = fileSize
+	content.MimeType = fileType.MIME
+	content.Similarity = status
+	content.Corrupt = false
+	content.UpdatedAt = time.Now()
+
+	err = s.contentRepo.Update(content)
+	if err != nil {
+		s.storage.Delete(context.Background(), fileId)
+		return nil, false, reservationError(err)
+	}
+	s.saveFingerprints(contentId, fingerprints)
+
+	if err = s.storage.Delete(context.Background(), oldFileId); err != nil {
+		log.Printf("failed to delete replaced file %s: %v\n", oldFileId, err)
+	}
+
+	if check == nil {
+		return nil, true, nil
+	}
+
+	check.FileID = &fileId
+	if err = s.similarityRepo.CreateCheck(check); err != nil {
+		return nil, false, err
+	}
+
+	return check, true, nil
+}
+
+func (s *contentService) Delete(userId, contentId string) error {
+	content, err := s.getOwned(userId, contentId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = s.contentRepo.SoftDelete(contentId, now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrContentNotFound
+		}
+		return err
+	}
+	content.DeletedAt = &now
+
+	// The delete has already committed; anything purge leaves behind is retried by PurgeDeleted.
+	if err := s.purge(content); err != nil {
+		log.Printf("failed to purge content %s: %v\n", contentId, err)
+	}
+
+	return nil
+}
+
+func (s *contentService) PurgeDeleted() error {
+	contents, err := s.contentRepo.GetUnpurged()
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		if err := s.purge(content); err != nil {
+			log.Printf("failed to purge content %s: %v\n", content.ContentID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *contentService) purge(content *models.Content) error {
+	active, err := s.licenseRepo.CountActive(content.ContentID.String())
+	if err != nil {
+		return err
+	}
+
+	if active > 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	err = s.storage.Delete(ctx, content.FileID)
+	if err != nil {
+		return err
+	}
+
+	derived, err := s.contentRepo.Purge(content.ContentID.String(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := s.fingerprintRepo.Delete(content.ContentID.String()); err != nil {
+		log.Printf("failed to delete fingerprints of purged content %s: %v\n", content.ContentID, err)
+	}
+
+	// The rows are gone, so anything left behind here is unreferenced and removed by the storage collector.
+	for _, fileId := range derived {
+		if err := s.storage.Delete(ctx, fileId); err != nil {
+			log.Printf("failed to delete object %s of purged content %s: %v\n", fileId, content.ContentID, err)
+		}
+	}
+
+	return nil
+}
+
+// ScrubChecksums re-hashes stored files that have not been verified recently. Files whose hash no longer matches, or
+// that are missing from storage, are marked corrupt and logged. Content uploaded before checksums were recorded has
+// its checksum filled in on the first pass.
+func (s *contentService) ScrubChecksums() error {
+	contents, err := s.contentRepo.GetUnscrubbed(time.Now().Add(-scrubInterval), scrubBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		if err := s.scrub(content); err != nil {
+			log.Printf("failed to scrub content %s: %v\n", content.ContentID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *contentService) scrub(content *models.Content) error {
+	contentId := content.ContentID.String()
+
+	sum, err := s.hashStored(content.FileID)
+	corrupt := false
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		log.Printf("content %s is missing its file %s\n", contentId, content.FileID)
+		corrupt = true
+	case err != nil:
+		return err
+	case content.SHA256 != "" && sum != content.SHA256:
+		log.Printf("content %s failed its checksum: stored %s, computed %s\n", contentId, content.SHA256, sum)
+		corrupt = true
+	}
+
+	err = s.contentRepo.SetIntegrity(contentId, content.FileID, sum, corrupt, time.Now())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// spooledFile is an upload copied to disk, so the checks and storage can each read it without holding it in memory.
+type spooledFile struct {
+	file   *os.File
+	size   int64
+	sha256 string
+	// header is the start of the file, enough to sniff its type.
+	header []byte
+}
+
+func spool(r io.Reader) (*spooledFile, error) {
+	file, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledFile{file: file}
+
+	hash := sha256.New()
+	spooled.size, err = io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		spooled.remove()
+		return nil, err
+	}
+	spooled.sha256 = hex.EncodeToString(hash.Sum(nil))
+
+	header := make([]byte, sniffLen)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		spooled.remove()
+		return nil, err
+	}
+	spooled.header = header[:n]
+
+	return spooled, nil
+}
+
+func (f *spooledFile) remove() {
+	f.file.Close()
+	os.Remove(f.file.Name())
+}
+
+func (s *contentService) hashStored(fileId string) (string, error) {
+	file, err := s.storage.Get(context.Background(), fileId)
+	if err != nil {
+		return "", err
+	}
 	defer file.Close()
 
-	fileContent, err := io.ReadAll(file)
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *contentService) ListCorrupt() ([]*models.Content, error) {
+	return s.contentRepo.GetCorrupt()
+}
+
+func (s *contentService) ListCategories() ([]*models.Category, error) {
+	return s.categoryRepo.GetAll()
+}
+
+// SetCategoryThreshold overrides the similarity threshold for content in a category. A nil threshold falls back to
+// the global one.
+func (s *contentService) SetCategoryThreshold(slug string, threshold *float64) (*models.Category, error) {
+	if threshold != nil && !similarity.ValidThreshold(*threshold) {
+		return nil, ErrInvalidThreshold
+	}
+
+	category, err := s.categoryRepo.SetThreshold(slug, threshold)
 	if err != nil {
-		return nil, nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCategory, slug)
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *contentService) validateCategories(slugs []string) error {
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		known[category.Slug] = true
+	}
+
+	for _, slug := range slugs {
+		if !known[slug] {
+			return fmt.Errorf("%w: %s", ErrUnknownCategory, slug)
+		}
+	}
+
+	return nil
+}
+
+func normalizePrices(content *models.Content) error {
+	if err := normalizeMoney(&content.Price); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{content.Price.Currency: true}
+	for i := range content.Prices {
+		price := &content.Prices[i]
+		if err := normalizeMoney(price); err != nil {
+			return err
+		}
+		if seen[price.Currency] {
+			return fmt.Errorf("duplicate price for currency %s", price.Currency)
+		}
+		seen[price.Currency] = true
+	}
+
+	return nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, tag, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
 	}
 
-	return content, fileContent, nil
+	return normalized, nil
 }
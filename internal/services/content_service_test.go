@@ -0,0 +1,583 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/fingerprint"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/similarity"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+	"github.com/gofrs/uuid"
+)
+
+type fakeContentRepo struct {
+	repositories.ContentRepository
+	contents map[uuid.UUID]*models.Content
+	writeErr error
+}
+
+func (r *fakeContentRepo) Create(content *models.Content) error {
+	if r.writeErr != nil {
+		return r.writeErr
+	}
+	copied := *content
+	r.contents[content.ContentID] = &copied
+	return nil
+}
+
+func (r *fakeContentRepo) GetById(id string) (*models.Content, error) {
+	content, ok := r.contents[uuid.FromStringOrNil(id)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *content
+	return &copied, nil
+}
+
+func (r *fakeContentRepo) Update(content *models.Content) error {
+	if r.writeErr != nil {
+		return r.writeErr
+	}
+	if _, ok := r.contents[content.ContentID]; !ok {
+		return sql.ErrNoRows
+	}
+	copied := *content
+	r.contents[content.ContentID] = &copied
+	return nil
+}
+
+func (r *fakeContentRepo) GetBySHA256(sha256, excludeId string) (*models.Content, error) {
+	for _, content := range r.contents {
+		if content.SHA256 == sha256 && content.ContentID.String() != excludeId && content.DeletedAt == nil {
+			copied := *content
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeContentRepo) SoftDelete(id string, deletedAt time.Time) error {
+	content, ok := r.contents[uuid.FromStringOrNil(id)]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	content.DeletedAt = &deletedAt
+	return nil
+}
+
+func (r *fakeContentRepo) Purge(id string, purgedAt time.Time) ([]string, error) {
+	content, ok := r.contents[uuid.FromStringOrNil(id)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	content.PurgedAt = &purgedAt
+	return nil, nil
+}
+
+func (r *fakeContentRepo) GetUnpurged() ([]*models.Content, error) {
+	var contents []*models.Content
+	for _, content := range r.contents {
+		if content.DeletedAt != nil && content.PurgedAt == nil {
+			copied := *content
+			contents = append(contents, &copied)
+		}
+	}
+	return contents, nil
+}
+
+func (r *fakeContentRepo) GetUnscrubbed(checkedBefore time.Time, limit int) ([]*models.Content, error) {
+	var contents []*models.Content
+	for _, content := range r.contents {
+		copied := *content
+		contents = append(contents, &copied)
+	}
+	return contents, nil
+}
+
+func (r *fakeContentRepo) SetIntegrity(id, fileId, sha256 string, corrupt bool, checkedAt time.Time) error {
+	content, ok := r.contents[uuid.FromStringOrNil(id)]
+	if !ok || content.FileID != fileId {
+		return sql.ErrNoRows
+	}
+	content.SHA256 = sha256
+	content.Corrupt = corrupt
+	return nil
+}
+
+func (r *fakeContentRepo) GetTags(id string) ([]string, error)        { return nil, nil }
+func (r *fakeContentRepo) GetCategories(id string) ([]string, error)  { return nil, nil }
+func (r *fakeContentRepo) GetPrices(id string) ([]money.Money, error) { return nil, nil }
+
+type fakeLicenseRepo struct {
+	repositories.LicenseRepository
+	active int
+}
+
+func (r *fakeLicenseRepo) CountActive(contentId string) (int, error) {
+	return r.active, nil
+}
+
+type fakeCategoryRepo struct {
+	repositories.CategoryRepository
+}
+
+func (fakeCategoryRepo) GetAll() ([]*models.Category, error) { return nil, nil }
+
+type fakeSimilarityRepo struct {
+	repositories.SimilarityRepository
+	checks []*models.SimilarityCheck
+}
+
+func (r *fakeSimilarityRepo) CreateCheck(check *models.SimilarityCheck) error {
+	r.checks = append(r.checks, check)
+	return nil
+}
+
+type fakeFingerprintRepo struct {
+	repositories.FingerprintRepository
+	saved map[string][]fingerprint.Hash
+}
+
+func (r *fakeFingerprintRepo) Save(contentId, kind string, hashes []fingerprint.Hash) error {
+	r.saved[contentId] = hashes
+	return nil
+}
+
+func (r *fakeFingerprintRepo) Delete(contentId string) error {
+	delete(r.saved, contentId)
+	return nil
+}
+
+type fakeQuota struct {
+	QuotaService
+}
+
+func (fakeQuota) Check(userId string, size, replacing int64, newItem bool) error { return nil }
+
+type contentTest struct {
+	service      ContentService
+	contents     *fakeContentRepo
+	licenses     *fakeLicenseRepo
+	checks       *fakeSimilarityRepo
+	fingerprints *fakeFingerprintRepo
+	checker      *similarity.Fake
+	storage      *storage.Memory
+}
+
+func newContentTest(policy similarity.UnavailablePolicy) *contentTest {
+	test := &contentTest{
+		contents:     &fakeContentRepo{contents: map[uuid.UUID]*models.Content{}},
+		licenses:     &fakeLicenseRepo{},
+		checks:       &fakeSimilarityRepo{},
+		fingerprints: &fakeFingerprintRepo{saved: map[string][]fingerprint.Hash{}},
+		checker:      similarity.NewFake(),
+		storage:      storage.NewMemory(),
+	}
+	test.service = NewContentService(test.contents, test.licenses, fakeCategoryRepo{}, test.checks, test.fingerprints,
+		test.storage, test.checker, policy, 0.9, fakeQuota{})
+
+	return test
+}
+
+// pngFile is enough of a PNG for type detection.
+var pngFile = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func (c *contentTest) upload(t *testing.T, creatorId uuid.UUID, file []byte) (*models.Content, *models.SimilarityCheck, bool, error) {
+	t.Helper()
+
+	content := &models.Content{Title: "upload", CreatorID: creatorId}
+	check, accepted, err := c.service.Create(content, bytes.NewReader(file), ".png", int64(len(file)))
+	return content, check, accepted, err
+}
+
+func (c *contentTest) objects(t *testing.T) int {
+	t.Helper()
+
+	var count int
+	err := c.storage.Walk(context.Background(), func(storage.ObjectInfo) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return count
+}
+
+func TestCreateUniqueContent(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+	hashes := []fingerprint.Hash{1, 2, 3}
+	test.checker.SetResult(similarity.Result{Similarity: 0.1, ModelVersion: "test",
+		Fingerprints: &similarity.Fingerprints{Kind: similarity.KindVisual, Hashes: hashes}})
+
+	content, check, accepted, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if !accepted || check == nil || check.Similar {
+		t.Fatalf("accepted = %v, check = %+v; want an accepted upload with a passing check", accepted, check)
+	}
+	if got := test.contents.contents[content.ContentID].Similarity; got != models.SimilarityPassed {
+		t.Errorf("similarity = %q, want %q", got, models.SimilarityPassed)
+	}
+	if len(test.checks.checks) != 1 {
+		t.Errorf("stored %d checks, want 1", len(test.checks.checks))
+	}
+	if got := test.fingerprints.saved[content.ContentID.String()]; len(got) != len(hashes) {
+		t.Errorf("saved fingerprints = %v, want %v", got, hashes)
+	}
+	if calls := test.checker.Calls(); len(calls) != 1 || calls[0] != content.ContentID.String() {
+		t.Errorf("checker calls = %v, want [%s]", calls, content.ContentID)
+	}
+}
+
+func TestCreateRejectsDuplicateOfOtherCreator(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+
+	original, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+	if err != nil {
+		t.Fatalf("Create original: %v", err)
+	}
+
+	test.checker.SetResult(similarity.Result{MatchID: original.ContentID.String(), Similarity: 0.95})
+	copied, check, accepted, err := test.upload(t, uuid.Must(uuid.NewV4()), append(pngFile, 'x'))
+	if err != nil {
+		t.Fatalf("Create copy: %v", err)
+	}
+
+	if accepted || check == nil || !check.Similar {
+		t.Fatalf("accepted = %v, check = %+v; want a rejected upload", accepted, check)
+	}
+	if got := test.contents.contents[copied.ContentID].Similarity; got != models.SimilarityRejected {
+		t.Errorf("similarity = %q, want %q", got, models.SimilarityRejected)
+	}
+}
+
+func TestCreateExemptsOwnDuplicate(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	original, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create original: %v", err)
+	}
+
+	test.checker.SetResult(similarity.Result{MatchID: original.ContentID.String(), Similarity: 0.95})
+	_, check, accepted, err := test.upload(t, creatorId, append(pngFile, 'x'))
+	if err != nil {
+		t.Fatalf("Create copy: %v", err)
+	}
+
+	if !accepted || check == nil || !check.Exempt || check.Similar {
+		t.Errorf("accepted = %v, check = %+v; want an exempt match", accepted, check)
+	}
+}
+
+func TestCreateSettlesExactCopiesByChecksum(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+
+	if _, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile); err != nil {
+		t.Fatalf("Create original: %v", err)
+	}
+
+	_, check, accepted, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+	if err != nil {
+		t.Fatalf("Create copy: %v", err)
+	}
+
+	if accepted || check == nil || check.Score != 1 {
+		t.Errorf("accepted = %v, check = %+v; want an exact match", accepted, check)
+	}
+	if calls := test.checker.Calls(); len(calls) != 1 {
+		t.Errorf("checker called %d times, want only for the original", len(calls))
+	}
+}
+
+func TestCreateWhenSimilarityUnavailable(t *testing.T) {
+	tests := []struct {
+		policy     similarity.UnavailablePolicy
+		wantErr    bool
+		wantStatus string
+	}{
+		{similarity.RejectWhenUnavailable, true, ""},
+		{similarity.QueueWhenUnavailable, false, models.SimilarityUnchecked},
+		{similarity.FlagWhenUnavailable, false, models.SimilarityFlagged},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			test := newContentTest(tt.policy)
+			test.checker.SetError(similarity.ErrUnavailable)
+
+			content, check, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+			if tt.wantErr {
+				if !errors.Is(err, similarity.ErrUnavailable) {
+					t.Fatalf("err = %v, want %v", err, similarity.ErrUnavailable)
+				}
+				if len(test.contents.contents) != 0 || test.objects(t) != 0 {
+					t.Errorf("rejected upload left %d rows and %d objects", len(test.contents.contents), test.objects(t))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if check != nil {
+				t.Errorf("check = %+v, want none", check)
+			}
+			if got := test.contents.contents[content.ContentID].Similarity; got != tt.wantStatus {
+				t.Errorf("similarity = %q, want %q", got, tt.wantStatus)
+			}
+			if len(test.fingerprints.saved) != 0 {
+				t.Errorf("saved fingerprints for an unchecked upload")
+			}
+		})
+	}
+}
+
+func TestReplaceFileRejectedKeepsFingerprints(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+	creatorId := uuid.Must(uuid.NewV4())
+	original := []fingerprint.Hash{1}
+	test.checker.SetResult(similarity.Result{Fingerprints: &similarity.Fingerprints{Kind: similarity.KindVisual,
+		Hashes: original}})
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	test.checker.SetResult(similarity.Result{MatchID: uuid.Must(uuid.NewV4()).String(), Similarity: 0.99,
+		Fingerprints: &similarity.Fingerprints{Kind: similarity.KindVisual, Hashes: []fingerprint.Hash{2}}})
+	_, accepted, err := test.service.ReplaceFile(creatorId.String(), content.ContentID.String(),
+		bytes.NewReader(append(pngFile, 'x')), ".png", int64(len(pngFile)+1))
+	if err != nil {
+		t.Fatalf("ReplaceFile: %v", err)
+	}
+
+	if accepted {
+		t.Fatalf("replacement accepted, want it rejected")
+	}
+	if got := test.fingerprints.saved[content.ContentID.String()]; len(got) != 1 || got[0] != original[0] {
+		t.Errorf("fingerprints = %v, want the original %v", got, original)
+	}
+}
+
+func TestReplaceFileSwapsStoredObject(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	oldFileId := test.contents.contents[content.ContentID].FileID
+
+	replacement := append(pngFile, 'x')
+	_, accepted, err := test.service.ReplaceFile(creatorId.String(), content.ContentID.String(),
+		bytes.NewReader(replacement), ".png", int64(len(replacement)))
+	if err != nil || !accepted {
+		t.Fatalf("ReplaceFile = %v, %v; want an accepted replacement", accepted, err)
+	}
+
+	stored := test.contents.contents[content.ContentID]
+	if _, err := test.storage.Stat(context.Background(), oldFileId); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("replaced object %s still stored: %v", oldFileId, err)
+	}
+
+	body, err := test.storage.Get(context.Background(), stored.FileID)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", stored.FileID, err)
+	}
+	defer body.Close()
+
+	data, _ := io.ReadAll(body)
+	if !bytes.Equal(data, replacement) || stored.FileSize != int64(len(replacement)) {
+		t.Errorf("stored %d bytes with size %d, want the %d byte replacement", len(data), stored.FileSize,
+			len(replacement))
+	}
+	if test.objects(t) != 1 {
+		t.Errorf("%d objects stored, want 1", test.objects(t))
+	}
+}
+
+func TestPurgeDeletesStoredObject(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	test.licenses.active = 1
+	if err := test.service.Delete(creatorId.String(), content.ContentID.String()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := test.service.PurgeDeleted(); err != nil {
+		t.Fatalf("PurgeDeleted: %v", err)
+	}
+	if test.objects(t) != 1 {
+		t.Fatalf("purged content that still has active licenses")
+	}
+
+	test.licenses.active = 0
+	if err := test.service.PurgeDeleted(); err != nil {
+		t.Fatalf("PurgeDeleted: %v", err)
+	}
+	if test.objects(t) != 0 || test.contents.contents[content.ContentID].PurgedAt == nil {
+		t.Errorf("content not purged: %d objects left", test.objects(t))
+	}
+}
+
+func TestScrubFlagsTamperedObject(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+
+	content, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := test.service.ScrubChecksums(); err != nil {
+		t.Fatalf("ScrubChecksums: %v", err)
+	}
+	if test.contents.contents[content.ContentID].Corrupt {
+		t.Fatalf("intact content flagged as corrupt")
+	}
+
+	fileId := test.contents.contents[content.ContentID].FileID
+	tampered := append(pngFile, 'x')
+	if err := test.storage.Put(context.Background(), fileId, bytes.NewReader(tampered), int64(len(tampered)), ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if err := test.service.ScrubChecksums(); err != nil {
+		t.Fatalf("ScrubChecksums: %v", err)
+	}
+	if !test.contents.contents[content.ContentID].Corrupt {
+		t.Errorf("tampered content not flagged as corrupt")
+	}
+}
+
+func TestGetStreamsStoredObject(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+
+	content, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	_, file, err := test.service.Get(content.ContentID.String())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(8, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(data, pngFile[8:]) {
+		t.Errorf("read %q, want %q", data, pngFile[8:])
+	}
+}
+
+func TestCreateSpoolsUpload(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	test := newContentTest(similarity.RejectWhenUnavailable)
+
+	file := append(bytes.Clone(pngFile), bytes.Repeat([]byte{0xAB}, 1<<20)...)
+	content, _, accepted, err := test.upload(t, uuid.Must(uuid.NewV4()), file)
+	if err != nil || !accepted {
+		t.Fatalf("Create: accepted = %v, err = %v", accepted, err)
+	}
+
+	sum := sha256.Sum256(file)
+	if content.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 = %s, want %x", content.SHA256, sum)
+	}
+
+	_, stored, err := test.service.Get(content.ContentID.String())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer stored.Close()
+	data, err := io.ReadAll(stored)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(data, file) {
+		t.Errorf("stored %d bytes, want the %d uploaded", len(data), len(file))
+	}
+
+	if spooled, _ := filepath.Glob(filepath.Join(tmp, "upload-*")); len(spooled) != 0 {
+		t.Errorf("left %v behind", spooled)
+	}
+}
+
+func TestGetRefusesCorruptContent(t *testing.T) {
+	test := newContentTest(similarity.RejectWhenUnavailable)
+
+	flagged, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	test.contents.contents[flagged.ContentID].Corrupt = true
+
+	if _, _, err := test.service.Get(flagged.ContentID.String()); !errors.Is(err, ErrContentCorrupt) {
+		t.Errorf("Get of flagged content: err = %v, want %v", err, ErrContentCorrupt)
+	}
+
+	missing, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), append(pngFile, 'x'))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := test.storage.Delete(context.Background(), test.contents.contents[missing.ContentID].FileID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, _, err := test.service.Get(missing.ContentID.String()); !errors.Is(err, ErrContentCorrupt) {
+		t.Errorf("Get of content without a file: err = %v, want %v", err, ErrContentCorrupt)
+	}
+	if !test.contents.contents[missing.ContentID].Corrupt {
+		t.Errorf("content without a file not flagged as corrupt")
+	}
+}
+
+func TestCreateOverQuotaAtInsert(t *testing.T) {
+	tests := []struct {
+		repoErr error
+		want    error
+	}{
+		{repositories.ErrQuotaExceeded, ErrQuotaExceeded},
+		{repositories.ErrItemLimitReached, ErrItemLimitReached},
+	}
+
+	for _, tt := range tests {
+		test := newContentTest(similarity.RejectWhenUnavailable)
+		test.contents.writeErr = tt.repoErr
+
+		if _, _, _, err := test.upload(t, uuid.Must(uuid.NewV4()), pngFile); !errors.Is(err, tt.want) {
+			t.Errorf("Create: err = %v, want %v", err, tt.want)
+		}
+		if test.objects(t) != 0 {
+			t.Errorf("upload refused by the quota left %d objects stored", test.objects(t))
+		}
+	}
+}
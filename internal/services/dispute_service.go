@@ -0,0 +1,179 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/gofrs/uuid"
+)
+
+type DisputeService interface {
+	ListChecks(userId, contentId string, isAdmin bool) ([]*models.SimilarityCheck, error)
+	Open(userId, contentId, reason string) (*models.Dispute, error)
+	ListByCreator(userId string) ([]*models.Dispute, error)
+	ListOpen() ([]*models.Dispute, error)
+	Approve(adminId, disputeId, note string) (*models.Dispute, error)
+	Deny(adminId, disputeId, note string) (*models.Dispute, error)
+}
+
+var (
+	ErrDisputeNotFound = errors.New("dispute not found")
+	ErrNotDisputable   = errors.New("only content matched as a duplicate can be disputed")
+	ErrMissingReason   = errors.New("dispute reason cannot be empty")
+	ErrDisputeOpen     = errors.New("a dispute is already open for this content")
+	ErrDisputeClosed   = errors.New("dispute has already been decided")
+)
+
+type disputeService struct {
+	similarityRepo repositories.SimilarityRepository
+	contentRepo    repositories.ContentRepository
+	clock          clock.Clock
+}
+
+func NewDisputeService(similarityRepo repositories.SimilarityRepository, contentRepo repositories.ContentRepository,
+	clock clock.Clock) DisputeService {
+	return &disputeService{similarityRepo: similarityRepo, contentRepo: contentRepo, clock: clock}
+}
+
+func (s *disputeService) getContent(contentId string) (*models.Content, error) {
+	content, err := s.contentRepo.GetById(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContentNotFound
+		}
+		return nil, err
+	}
+
+	if content.DeletedAt != nil {
+		return nil, ErrContentNotFound
+	}
+
+	return content, nil
+}
+
+func (s *disputeService) ListChecks(userId, contentId string, isAdmin bool) ([]*models.SimilarityCheck, error) {
+	content, err := s.getContent(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin && content.CreatorID.String() != userId {
+		return nil, ErrNotCreator
+	}
+
+	return s.similarityRepo.GetChecks(contentId)
+}
+
+func (s *disputeService) Open(userId, contentId, reason string) (*models.Dispute, error) {
+	content, err := s.getContent(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if content.CreatorID.String() != userId {
+		return nil, ErrNotCreator
+	}
+	if content.Similarity != models.SimilarityRejected && content.Similarity != models.SimilarityReview {
+		return nil, ErrNotDisputable
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrMissingReason
+	}
+
+	disputeId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	dispute := &models.Dispute{
+		DisputeID: disputeId,
+		ContentID: content.ContentID,
+		CreatorID: content.CreatorID,
+		Reason:    reason,
+		Status:    models.DisputeOpen,
+		CreatedAt: s.clock.Now(),
+	}
+
+	checks, err := s.similarityRepo.GetChecks(contentId)
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range checks {
+		if check.Similar {
+			dispute.CheckID = &check.CheckID
+			break
+		}
+	}
+
+	if err := s.similarityRepo.CreateDispute(dispute); err != nil {
+		if errors.Is(err, repositories.ErrDisputeExists) {
+			return nil, ErrDisputeOpen
+		}
+		return nil, err
+	}
+
+	return dispute, nil
+}
+
+func (s *disputeService) ListByCreator(userId string) ([]*models.Dispute, error) {
+	return s.similarityRepo.GetDisputesByCreator(userId)
+}
+
+func (s *disputeService) ListOpen() ([]*models.Dispute, error) {
+	return s.similarityRepo.GetOpenDisputes()
+}
+
+// Approve upholds the dispute and publishes the content. Denying it leaves the content unlisted.
+func (s *disputeService) Approve(adminId, disputeId, note string) (*models.Dispute, error) {
+	return s.resolve(adminId, disputeId, note, models.DisputeApproved)
+}
+
+func (s *disputeService) Deny(adminId, disputeId, note string) (*models.Dispute, error) {
+	return s.resolve(adminId, disputeId, note, models.DisputeDenied)
+}
+
+func (s *disputeService) resolve(adminId, disputeId, note, status string) (*models.Dispute, error) {
+	dispute, err := s.similarityRepo.GetDispute(disputeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, err
+	}
+
+	if dispute.Status != models.DisputeOpen {
+		return nil, ErrDisputeClosed
+	}
+
+	content, err := s.getContent(dispute.ContentID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	contentStatus := content.Similarity
+	if status == models.DisputeApproved {
+		contentStatus = models.SimilarityPassed
+	}
+
+	decidedBy := uuid.FromStringOrNil(adminId)
+	now := s.clock.Now()
+	dispute.Status = status
+	dispute.Note = strings.TrimSpace(note)
+	dispute.DecidedBy = &decidedBy
+	dispute.DecidedAt = &now
+
+	if err := s.similarityRepo.ResolveDispute(dispute, contentStatus); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDisputeClosed
+		}
+		return nil, err
+	}
+
+	return dispute, nil
+}
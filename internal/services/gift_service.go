@@ -0,0 +1,124 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/gofrs/uuid"
+)
+
+type GiftService interface {
+	Redeem(userId, code string, expiresAt time.Time) (*models.GiftCode, error)
+	ListByPurchaser(userId string) ([]*models.GiftCode, error)
+	Transfer(userId, licenseId, recipientEmail string) error
+}
+
+var (
+	ErrInvalidGiftCode   = errors.New("gift code is invalid, expired or already redeemed")
+	ErrLicenseNotFound   = errors.New("license not found")
+	ErrNotTransferable   = errors.New("content does not allow license transfers")
+	ErrRecipientNotFound = errors.New("recipient not found")
+)
+
+const giftCodeValidity = 90 * 24 * time.Hour
+
+type giftService struct {
+	giftRepo       repositories.GiftRepository
+	contentRepo    repositories.ContentRepository
+	licenseRepo    repositories.LicenseRepository
+	sessionKeyRepo repositories.SessionKeyRepository
+	userRepo       repositories.UserRepository
+}
+
+func NewGiftService(giftRepo repositories.GiftRepository, contentRepo repositories.ContentRepository,
+	licenseRepo repositories.LicenseRepository, sessionKeyRepo repositories.SessionKeyRepository,
+	userRepo repositories.UserRepository) GiftService {
+	return &giftService{giftRepo: giftRepo, contentRepo: contentRepo, licenseRepo: licenseRepo,
+		sessionKeyRepo: sessionKeyRepo, userRepo: userRepo}
+}
+
+func (s *giftService) Redeem(userId, code string, expiresAt time.Time) (*models.GiftCode, error) {
+	licenseId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	license := &models.License{
+		LicenseID: licenseId,
+		UserID:    uuid.FromStringOrNil(userId),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	gift, err := s.giftRepo.Redeem(code, license)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidGiftCode
+		}
+		return nil, err
+	}
+
+	return gift, nil
+}
+
+func (s *giftService) ListByPurchaser(userId string) ([]*models.GiftCode, error) {
+	return s.giftRepo.GetByPurchaser(userId)
+}
+
+func (s *giftService) Transfer(userId, licenseId, recipientEmail string) error {
+	license, err := s.licenseRepo.GetById(licenseId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrLicenseNotFound
+		}
+		return err
+	}
+
+	if license.UserID.String() != userId || !license.ExpiresAt.After(time.Now()) {
+		return ErrLicenseNotFound
+	}
+
+	content, err := s.contentRepo.GetById(license.ContentID.String())
+	if err != nil {
+		return err
+	}
+
+	if !content.Listed() {
+		return ErrContentUnderReview
+	}
+	if !content.Transferable {
+		return ErrNotTransferable
+	}
+
+	recipient, err := s.userRepo.GetByEmail(recipientEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecipientNotFound
+		}
+		return err
+	}
+
+	if recipient.UserID == license.UserID {
+		return errors.New("cannot transfer a license to yourself")
+	}
+
+	if err = s.licenseRepo.Transfer(licenseId, recipient.UserID.String()); err != nil {
+		return err
+	}
+
+	return s.sessionKeyRepo.DeleteForContent(userId, license.ContentID.String())
+}
+
+func generateGiftCode() (string, error) {
+	randomBytes := make([]byte, 10)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.EncodeToString(randomBytes), nil
+}
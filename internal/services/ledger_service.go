@@ -0,0 +1,216 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+type LedgerService interface {
+	SaleEntries(order *models.Order) ([]*models.LedgerEntry, error)
+	RefundEntries(order *models.Order) ([]*models.LedgerEntry, error)
+	Balance(creatorId string) ([]*models.Balance, error)
+	Sales(creatorId string) ([]*models.SalesSummary, error)
+	Statement(creatorId string, month time.Time) ([]*models.StatementLine, error)
+	ListPayouts(creatorId string) ([]*models.Payout, error)
+	RunPayouts() ([]*models.Payout, error)
+}
+
+type ledgerService struct {
+	ledgerRepo repositories.LedgerRepository
+	split      models.RevenueSplit
+	clock      clock.Clock
+}
+
+func NewLedgerService(ledgerRepo repositories.LedgerRepository, split models.RevenueSplit, clock clock.Clock) LedgerService {
+	return &ledgerService{ledgerRepo: ledgerRepo, split: split, clock: clock}
+}
+
+// RefundEntries reverses the entries written for the sale, so the original split applies even if the policy changed.
+// The entries are written with the refund.
+func (s *ledgerService) RefundEntries(order *models.Order) ([]*models.LedgerEntry, error) {
+	sale, err := s.ledgerRepo.GetByOrder(order.OrderID.String(), models.LedgerSale)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sale) == 0 {
+		return nil, nil
+	}
+
+	transactionId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	entries := make([]*models.LedgerEntry, len(sale))
+	for i, entry := range sale {
+		entryId, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+
+		entries[i] = &models.LedgerEntry{
+			EntryID:       entryId,
+			TransactionID: transactionId,
+			Kind:          models.LedgerRefund,
+			Account:       entry.Account,
+			CreatorID:     entry.CreatorID,
+			OrderID:       entry.OrderID,
+			Amount:        money.New(-entry.Amount.Amount, entry.Amount.Currency),
+			CreatedAt:     now,
+		}
+	}
+
+	return entries, nil
+}
+
+// SaleEntries divides the order price between tax, platform fee and creator share. The entries are written with the
+// order. Tax is withheld from the gross first and the platform fee is taken from what remains.
+func (s *ledgerService) SaleEntries(order *models.Order) ([]*models.LedgerEntry, error) {
+	if order.Price.IsZero() {
+		return nil, nil
+	}
+
+	gross := order.Price
+	tax := gross.MulRatio(s.split.TaxBps, 10000)
+	afterTax, err := gross.Sub(tax)
+	if err != nil {
+		return nil, err
+	}
+	fee := afterTax.MulRatio(s.split.PlatformFeeBps, 10000)
+	share, err := afterTax.Sub(fee)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	orderId := order.OrderID
+
+	var entries []*models.LedgerEntry
+	for _, leg := range []struct {
+		account string
+		amount  int64
+	}{
+		{models.AccountCash, gross.Amount},
+		{models.AccountTax, -tax.Amount},
+		{models.AccountPlatform, -fee.Amount},
+		{models.AccountCreator, -share.Amount},
+	} {
+		entryId, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &models.LedgerEntry{
+			EntryID:       entryId,
+			TransactionID: transactionId,
+			Kind:          models.LedgerSale,
+			Account:       leg.account,
+			OrderID:       &orderId,
+			Amount:        money.New(leg.amount, gross.Currency),
+			CreatedAt:     now,
+		}
+		if leg.account == models.AccountCreator {
+			entry.CreatorID = order.CreatorID
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (s *ledgerService) Balance(creatorId string) ([]*models.Balance, error) {
+	return s.ledgerRepo.GetBalances(creatorId)
+}
+
+func (s *ledgerService) Sales(creatorId string) ([]*models.SalesSummary, error) {
+	return s.ledgerRepo.GetSales(creatorId)
+}
+
+func (s *ledgerService) Statement(creatorId string, month time.Time) ([]*models.StatementLine, error) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return s.ledgerRepo.GetStatement(creatorId, from, from.AddDate(0, 1, 0))
+}
+
+func (s *ledgerService) ListPayouts(creatorId string) ([]*models.Payout, error) {
+	return s.ledgerRepo.GetPayouts(creatorId)
+}
+
+func (s *ledgerService) RunPayouts() ([]*models.Payout, error) {
+	payable, err := s.ledgerRepo.GetPayable()
+	if err != nil {
+		return nil, err
+	}
+
+	var paid []*models.Payout
+	for _, payout := range payable {
+		if err := s.payout(payout); err != nil {
+			log.Printf("failed to pay out %s to creator %s: %v\n", payout.Amount, payout.CreatorID, err)
+			continue
+		}
+		paid = append(paid, payout)
+	}
+
+	return paid, nil
+}
+
+func (s *ledgerService) payout(payout *models.Payout) error {
+	payoutId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	transactionId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	payout.PayoutID = payoutId
+	payout.CreatedAt = s.clock.Now()
+	creatorId := payout.CreatorID
+
+	creatorEntryId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	cashEntryId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	entries := []*models.LedgerEntry{
+		{
+			EntryID:       creatorEntryId,
+			TransactionID: transactionId,
+			Kind:          models.LedgerPayout,
+			Account:       models.AccountCreator,
+			CreatorID:     &creatorId,
+			PayoutID:      &payoutId,
+			Amount:        payout.Amount,
+			CreatedAt:     payout.CreatedAt,
+		},
+		{
+			EntryID:       cashEntryId,
+			TransactionID: transactionId,
+			Kind:          models.LedgerPayout,
+			Account:       models.AccountCash,
+			PayoutID:      &payoutId,
+			Amount:        money.New(-payout.Amount.Amount, payout.Amount.Currency),
+			CreatedAt:     payout.CreatedAt,
+		},
+	}
+
+	return s.ledgerRepo.CreatePayout(payout, entries)
+}
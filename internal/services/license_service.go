@@ -7,43 +7,67 @@ import (
 
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
 	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
 	"github.com/gofrs/uuid"
 )
 
 type LicenseService interface {
 	Generate(userId, contentId string, expiresAt time.Time) error
+	GenerateBatch(userId string, orderId *uuid.UUID, contentIds []string, expiresAt time.Time) error
+	Prepare(userId string, orderId *uuid.UUID, contentIds []string, expiresAt time.Time) ([]*models.License, error)
 	Verify(userId, contentId string) bool
+	VerifyBatch(userId string, contentIds []string) (map[string]bool, error)
+	Licensed(userId string, contentIds []string) (map[string]bool, error)
+	ListByUser(userId string) ([]*models.License, error)
 	Revoke(licenseId string) error
 }
 
 type licenseService struct {
-	licenseRepo repositories.LicenseRepository
+	licenseRepo      repositories.LicenseRepository
+	subscriptionRepo repositories.SubscriptionRepository
+	clock            clock.Clock
 }
 
-func NewLicenseService(licenseRepo repositories.LicenseRepository) LicenseService {
-	return &licenseService{licenseRepo: licenseRepo}
+func NewLicenseService(licenseRepo repositories.LicenseRepository, subscriptionRepo repositories.SubscriptionRepository,
+	clock clock.Clock) LicenseService {
+	return &licenseService{licenseRepo: licenseRepo, subscriptionRepo: subscriptionRepo, clock: clock}
 }
 
 func (s *licenseService) Generate(userId, contentId string, expiresAt time.Time) error {
-	licenseId, err := uuid.NewV4()
+	return s.GenerateBatch(userId, nil, []string{contentId}, expiresAt)
+}
+
+// GenerateBatch grants licenses to the content. orderId is the order they were paid for by, if any, so a refund can
+// revoke them.
+func (s *licenseService) GenerateBatch(userId string, orderId *uuid.UUID, contentIds []string, expiresAt time.Time) error {
+	licenses, err := s.Prepare(userId, orderId, contentIds, expiresAt)
 	if err != nil {
 		return err
 	}
-	
-	license := &models.License{
-		LicenseID: licenseId,
-		UserID:    uuid.FromStringOrNil(userId),
-		ContentID: uuid.FromStringOrNil(contentId),
-		ExpiresAt: expiresAt,
-		CreatedAt: time.Now(),
-	}
 
-	err = s.licenseRepo.Create(license)
-	if err != nil {
-		return err
+	return s.licenseRepo.CreateBatch(licenses)
+}
+
+// Prepare builds licenses without storing them, for callers that write them as part of a larger transaction.
+func (s *licenseService) Prepare(userId string, orderId *uuid.UUID, contentIds []string, expiresAt time.Time) ([]*models.License, error) {
+	licenses := make([]*models.License, len(contentIds))
+	for i, contentId := range contentIds {
+		licenseId, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+
+		licenses[i] = &models.License{
+			LicenseID: licenseId,
+			UserID:    uuid.FromStringOrNil(userId),
+			ContentID: uuid.FromStringOrNil(contentId),
+			OrderID:   orderId,
+			ExpiresAt: expiresAt,
+			CreatedAt: s.clock.Now(),
+		}
 	}
 
-	return nil
+	return licenses, nil
 }
 
 func (s *licenseService) Verify(userId, contentId string) bool {
@@ -54,15 +78,60 @@ func (s *licenseService) Verify(userId, contentId string) bool {
 		}
 	}
 
-	if license == nil {
-		return false
+	if license != nil && license.ExpiresAt.After(s.clock.Now()) {
+		return true
 	}
 
-	if license.ExpiresAt.Before(time.Now()) {
+	subscribed, err := s.subscriptionRepo.HasAccess(userId, contentId, s.clock.Now())
+	if err != nil {
 		return false
 	}
 
-	return true
+	return subscribed
+}
+
+func (s *licenseService) VerifyBatch(userId string, contentIds []string) (map[string]bool, error) {
+	entitled, err := s.Licensed(userId, contentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contentIds) == 0 {
+		return entitled, nil
+	}
+
+	accessible, err := s.subscriptionRepo.GetAccessible(userId, contentIds, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, contentId := range accessible {
+		entitled[contentId] = true
+	}
+
+	return entitled, nil
+}
+
+func (s *licenseService) Licensed(userId string, contentIds []string) (map[string]bool, error) {
+	licensed := make(map[string]bool, len(contentIds))
+	if len(contentIds) == 0 {
+		return licensed, nil
+	}
+
+	licenses, err := s.licenseRepo.GetActiveForUser(userId, contentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, license := range licenses {
+		licensed[license.ContentID.String()] = true
+	}
+
+	return licensed, nil
+}
+
+func (s *licenseService) ListByUser(userId string) ([]*models.License, error) {
+	return s.licenseRepo.GetByUser(userId)
 }
 
 func (s *licenseService) Revoke(licenseId string) error {
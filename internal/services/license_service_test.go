@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/gofrs/uuid"
+)
+
+// entitlementDB counts queries, since round trips rather than in-memory work dominate checking entitlements.
+type entitlementDB struct {
+	queries  int
+	licensed map[string]*models.License
+	plan     map[string]bool
+}
+
+func (db *entitlementDB) query() {
+	db.queries++
+}
+
+type countingLicenseRepo struct {
+	repositories.LicenseRepository
+	db *entitlementDB
+}
+
+func (r countingLicenseRepo) Get(userId, contentId string) (*models.License, error) {
+	r.db.query()
+	license, ok := r.db.licensed[contentId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return license, nil
+}
+
+func (r countingLicenseRepo) GetActiveForUser(userId string, contentIds []string) ([]*models.License, error) {
+	r.db.query()
+	var licenses []*models.License
+	for _, contentId := range contentIds {
+		if license, ok := r.db.licensed[contentId]; ok {
+			licenses = append(licenses, license)
+		}
+	}
+	return licenses, nil
+}
+
+type countingSubscriptionRepo struct {
+	repositories.SubscriptionRepository
+	db *entitlementDB
+}
+
+func (r countingSubscriptionRepo) HasAccess(userId, contentId string, at time.Time) (bool, error) {
+	r.db.query()
+	return r.db.plan[contentId], nil
+}
+
+func (r countingSubscriptionRepo) GetAccessible(userId string, contentIds []string, at time.Time) ([]string, error) {
+	r.db.query()
+	var accessible []string
+	for _, contentId := range contentIds {
+		if r.db.plan[contentId] {
+			accessible = append(accessible, contentId)
+		}
+	}
+	return accessible, nil
+}
+
+// newEntitlements builds a listing of n items of which a third are licensed and another third are in a plan the user
+// subscribes to.
+func newEntitlements(tb testing.TB, n int) (LicenseService, *entitlementDB, []string) {
+	tb.Helper()
+
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := &entitlementDB{licensed: make(map[string]*models.License), plan: make(map[string]bool)}
+
+	contentIds := make([]string, n)
+	for i := range contentIds {
+		contentId := uuid.Must(uuid.NewV4())
+		contentIds[i] = contentId.String()
+
+		switch i % 3 {
+		case 0:
+			db.licensed[contentIds[i]] = &models.License{ContentID: contentId, ExpiresAt: clk.Now().Add(time.Hour)}
+		case 1:
+			db.plan[contentIds[i]] = true
+		}
+	}
+
+	service := NewLicenseService(countingLicenseRepo{db: db}, countingSubscriptionRepo{db: db}, clk)
+	return service, db, contentIds
+}
+
+func TestVerifyBatchMatchesVerify(t *testing.T) {
+	service, _, contentIds := newEntitlements(t, 30)
+	userId := uuid.Must(uuid.NewV4()).String()
+
+	entitled, err := service.VerifyBatch(userId, contentIds)
+	if err != nil {
+		t.Fatalf("VerifyBatch: %v", err)
+	}
+
+	for i, contentId := range contentIds {
+		if got, want := entitled[contentId], service.Verify(userId, contentId); got != want {
+			t.Errorf("item %d: VerifyBatch = %v, Verify = %v", i, got, want)
+		}
+	}
+}
+
+// TestVerifyBatchQueries checks that a listing costs VerifyBatch one license and one subscription query however long it
+// is, where Verify costs a query per item and a second for each item the user holds no license to.
+func TestVerifyBatchQueries(t *testing.T) {
+	userId := uuid.Must(uuid.NewV4()).String()
+
+	for _, n := range []int{1, 30, 3000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			service, db, contentIds := newEntitlements(t, n)
+
+			if _, err := service.VerifyBatch(userId, contentIds); err != nil {
+				t.Fatalf("VerifyBatch: %v", err)
+			}
+			if db.queries != 2 {
+				t.Errorf("VerifyBatch made %d queries, want 2", db.queries)
+			}
+
+			db.queries = 0
+			for _, contentId := range contentIds {
+				service.Verify(userId, contentId)
+			}
+			if want := 2*n - len(db.licensed); db.queries != want {
+				t.Errorf("Verify made %d queries, want %d", db.queries, want)
+			}
+		})
+	}
+}
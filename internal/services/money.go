@@ -0,0 +1,17 @@
+package services
+
+import "github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+
+func normalizeMoney(m *money.Money) error {
+	if m.Currency == "" {
+		m.Currency = money.DefaultCurrency
+	}
+
+	currency, err := money.NormalizeCurrency(m.Currency)
+	if err != nil {
+		return err
+	}
+	m.Currency = currency
+
+	return m.Validate()
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/packager"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/watermark"
+)
+
+type PackagingService interface {
+	PackageMissing() error
+	Import(userId, contentId string, playlist []byte, files map[string][]byte) (*models.Rendition, *models.SimilarityCheck, error)
+	Manifest(content *models.Content, userId string) ([]byte, error)
+	Init(content *models.Content, userId string) ([]byte, error)
+	Segment(content *models.Content, userId string, index int) ([]byte, error)
+	Key(userId, contentId, sessionId string) ([]byte, error)
+}
+
+var (
+	ErrRenditionNotFound = errors.New("content has not been packaged")
+	ErrNotWatermarked    = errors.New("content has not been packaged with a watermark yet")
+	ErrInvalidSession    = errors.New("session is invalid or has expired")
+	ErrMissingSegment    = errors.New("playlist references a file that was not uploaded")
+)
+
+const packagingBatchSize = 5
+
+type packagingService struct {
+	renditionRepo     repositories.RenditionRepository
+	contentRepo       repositories.ContentRepository
+	contentService    ContentService
+	sessionKeyService SessionKeyService
+	watermarkService  WatermarkService
+	storage           storage.Storage
+	clock             clock.Clock
+}
+
+func NewPackagingService(renditionRepo repositories.RenditionRepository, contentRepo repositories.ContentRepository,
+	contentService ContentService, sessionKeyService SessionKeyService, watermarkService WatermarkService,
+	storage storage.Storage, clock clock.Clock) PackagingService {
+	return &packagingService{renditionRepo: renditionRepo, contentRepo: contentRepo, contentService: contentService,
+		sessionKeyService: sessionKeyService, watermarkService: watermarkService, storage: storage, clock: clock}
+}
+
+// PackageMissing transcodes content that has no watermarked rendition for its current file. Without ffmpeg nothing
+// is packaged and only imported renditions exist, which viewers are not served.
+func (s *packagingService) PackageMissing() error {
+	if !packager.Available() {
+		return nil
+	}
+
+	contents, err := s.renditionRepo.GetPending(packagingBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		if err := s.transcode(content); err != nil {
+			log.Printf("failed to package content %s: %v\n", content.ContentID, err)
+			if err := s.renditionRepo.RecordFailure(content, err.Error()); err != nil {
+				log.Printf("failed to record failure for content %s: %v\n", content.ContentID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// variantSource is one packaged variant of a rendition and where its files are read from.
+type variantSource struct {
+	variant  string
+	playlist *packager.Playlist
+	read     func(uri string) ([]byte, error)
+}
+
+// transcode packages the stored file twice, once plain and once with the watermark's B filter, so segments can be
+// picked per viewer the same way the watermarked download is assembled.
+func (s *packagingService) transcode(content *models.Content) error {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "package-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "source"+filepath.Ext(content.FileID))
+	data, err := s.download(content.FileID)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		return err
+	}
+
+	filters := map[string]string{models.VariantA: "", models.VariantB: watermark.VariantBFilter}
+
+	var sources []variantSource
+	for _, variant := range []string{models.VariantA, models.VariantB} {
+		out := filepath.Join(dir, variant)
+		if err := os.Mkdir(out, 0o700); err != nil {
+			return err
+		}
+
+		playlistPath, err := packager.PackageHLS(ctx, src, out, filters[variant])
+		if err != nil {
+			return err
+		}
+
+		playlistFile, err := os.Open(playlistPath)
+		if err != nil {
+			return err
+		}
+		playlist, err := packager.ParsePlaylist(playlistFile)
+		playlistFile.Close()
+		if err != nil {
+			return err
+		}
+
+		sources = append(sources, variantSource{variant: variant, playlist: playlist, read: func(uri string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(out, filepath.Base(uri)))
+		}})
+	}
+
+	if len(sources[0].playlist.Segments) != len(sources[1].playlist.Segments) {
+		return fmt.Errorf("variant segment counts differ: %d and %d",
+			len(sources[0].playlist.Segments), len(sources[1].playlist.Segments))
+	}
+
+	_, err = s.store(content, sources)
+	return err
+}
+
+// Import packages creator-supplied clear HLS segments, for content that was segmented before upload. The segments
+// are joined and replace the content's file first, so they pass the same quota and similarity checks as any upload
+// and the rendition is tied to the file that was checked. A rejected import returns the check and no rendition.
+// Imported renditions carry no watermark, so only the creator is served them until the file is packaged here.
+func (s *packagingService) Import(userId, contentId string, playlistData []byte, files map[string][]byte) (*models.Rendition, *models.SimilarityCheck, error) {
+	playlist, err := packager.ParsePlaylist(bytes.NewReader(playlistData))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	read := func(uri string) ([]byte, error) {
+		data, ok := files[filepath.Base(uri)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingSegment, uri)
+		}
+		return data, nil
+	}
+
+	// fMP4 segments follow their init section to form a fragmented MP4; MPEG-TS segments join as they are.
+	ext := ".ts"
+	uris := make([]string, 0, len(playlist.Segments)+1)
+	if playlist.InitURI != "" {
+		ext = ".mp4"
+		uris = append(uris, playlist.InitURI)
+	}
+	for _, segment := range playlist.Segments {
+		uris = append(uris, segment.URI)
+	}
+
+	var media bytes.Buffer
+	for _, uri := range uris {
+		data, err := read(uri)
+		if err != nil {
+			return nil, nil, err
+		}
+		media.Write(data)
+	}
+
+	check, accepted, err := s.contentService.ReplaceFile(userId, contentId, bytes.NewReader(media.Bytes()), ext,
+		int64(media.Len()))
+	if err != nil || !accepted {
+		return nil, check, err
+	}
+
+	content, err := s.contentService.GetMetadata(contentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rendition, err := s.store(content, []variantSource{{variant: models.VariantA, playlist: playlist, read: read}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rendition, check, nil
+}
+
+// store encrypts every media segment of each variant under one fresh key and uploads the init section and segments.
+// The init section of the first variant serves them all. A rendition with both variants is marked watermarked.
+func (s *packagingService) store(content *models.Content, sources []variantSource) (*models.Rendition, error) {
+	ctx := context.Background()
+
+	key, err := packager.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	rendition := &models.Rendition{
+		ContentID:    content.ContentID,
+		SourceFileID: content.FileID,
+		Format:       models.FormatHLS,
+		Method:       models.EncryptionAES128,
+		Key:          key,
+		Watermarked:  len(sources) == 2,
+		CreatedAt:    s.clock.Now(),
+	}
+
+	var uploaded []string
+	cleanup := func() {
+		for _, fileId := range uploaded {
+			s.storage.Delete(ctx, fileId)
+		}
+	}
+
+	if initURI := sources[0].playlist.InitURI; initURI != "" {
+		data, err := sources[0].read(initURI)
+		if err != nil {
+			return nil, err
+		}
+
+		fileId, err := storage.Upload(ctx, s.storage, bytes.NewReader(data), ".mp4", int64(len(data)), "video/mp4")
+		if err != nil {
+			return nil, err
+		}
+		uploaded = append(uploaded, fileId)
+		rendition.InitFileID = &fileId
+	}
+
+	for _, source := range sources {
+		rendition.TargetDuration = max(rendition.TargetDuration, source.playlist.TargetDuration())
+
+		for i, segment := range source.playlist.Segments {
+			data, err := source.read(segment.URI)
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+
+			encrypted, err := packager.EncryptSegment(key, uint64(i), data)
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+
+			fileId, err := storage.Upload(ctx, s.storage, bytes.NewReader(encrypted), filepath.Ext(segment.URI),
+				int64(len(encrypted)), "")
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+			uploaded = append(uploaded, fileId)
+
+			rendition.Segments = append(rendition.Segments, &models.RenditionSegment{
+				Index:    i,
+				Variant:  source.variant,
+				FileID:   fileId,
+				Duration: segment.Duration,
+				FileSize: int64(len(encrypted)),
+			})
+		}
+	}
+
+	stale, err := s.renditionRepo.Set(rendition)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	for _, fileId := range stale {
+		if err := s.storage.Delete(ctx, fileId); err != nil {
+			log.Printf("failed to delete rendition file %s: %v\n", fileId, err)
+		}
+	}
+
+	return rendition, nil
+}
+
+// current returns the rendition of the content's current file that userId may be served. Viewers other than the
+// creator are only served watermarked renditions.
+func (s *packagingService) current(content *models.Content, userId string) (*models.Rendition, error) {
+	rendition, err := s.renditionRepo.Get(content.ContentID.String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRenditionNotFound
+		}
+		return nil, err
+	}
+
+	if rendition.SourceFileID != content.FileID {
+		return nil, ErrRenditionNotFound
+	}
+	if !rendition.Watermarked && content.CreatorID.String() != userId {
+		return nil, ErrNotWatermarked
+	}
+
+	return rendition, nil
+}
+
+// Manifest renders the playlist for one viewer; its key URI carries their session so the key endpoint can refuse
+// anyone else, and stops working once the session expires or is revoked.
+func (s *packagingService) Manifest(content *models.Content, userId string) ([]byte, error) {
+	rendition, err := s.current(content, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	contentId := content.ContentID.String()
+	session, err := s.sessionKeyService.Issue(userId, contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := &packager.MediaPlaylist{
+		TargetDuration: rendition.TargetDuration,
+		KeyURI:         fmt.Sprintf("/content/hls/%s/key?session=%s", contentId, session.KeyID),
+	}
+	if rendition.InitFileID != nil {
+		playlist.InitURI = fmt.Sprintf("/content/hls/%s/init.mp4", contentId)
+	}
+	for _, segment := range rendition.Segments {
+		if segment.Variant != models.VariantA {
+			continue
+		}
+		playlist.SegmentURIs = append(playlist.SegmentURIs, fmt.Sprintf("/content/hls/%s/segment/%d", contentId, segment.Index))
+		playlist.Durations = append(playlist.Durations, segment.Duration)
+	}
+
+	var buf bytes.Buffer
+	if err := playlist.Write(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *packagingService) download(fileId string) ([]byte, error) {
+	file, err := s.storage.Get(context.Background(), fileId)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+func (s *packagingService) Init(content *models.Content, userId string) ([]byte, error) {
+	rendition, err := s.current(content, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if rendition.InitFileID == nil {
+		return nil, ErrRenditionNotFound
+	}
+
+	return s.download(*rendition.InitFileID)
+}
+
+// Segment returns the variant of a segment that carries the viewer's watermark bit, as Open does for downloads. The
+// choice is made here rather than in the manifest so a viewer cannot pick variants by editing URIs.
+func (s *packagingService) Segment(content *models.Content, userId string, index int) ([]byte, error) {
+	rendition, err := s.current(content, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := models.VariantA
+	if rendition.Watermarked && content.CreatorID.String() != userId {
+		payload, err := s.watermarkService.Payload(content, userId)
+		if err != nil {
+			return nil, err
+		}
+		if watermark.Bit(payload, index) == 1 {
+			variant = models.VariantB
+		}
+	}
+
+	for _, segment := range rendition.Segments {
+		if segment.Index == index && segment.Variant == variant {
+			return s.download(segment.FileID)
+		}
+	}
+
+	return nil, ErrRenditionNotFound
+}
+
+func (s *packagingService) Key(userId, contentId, sessionId string) ([]byte, error) {
+	if !s.sessionKeyService.Verify(userId, contentId, sessionId) {
+		return nil, ErrInvalidSession
+	}
+
+	rendition, err := s.renditionRepo.Get(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRenditionNotFound
+		}
+		return nil, err
+	}
+
+	return rendition.Key, nil
+}
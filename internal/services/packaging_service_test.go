@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/similarity"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/watermark"
+	"github.com/gofrs/uuid"
+)
+
+type fakeRenditionRepo struct {
+	repositories.RenditionRepository
+	renditions map[uuid.UUID]*models.Rendition
+}
+
+func (r *fakeRenditionRepo) Set(rendition *models.Rendition) ([]string, error) {
+	r.renditions[rendition.ContentID] = rendition
+	return nil, nil
+}
+
+func (r *fakeRenditionRepo) Get(contentId string) (*models.Rendition, error) {
+	rendition, ok := r.renditions[uuid.FromStringOrNil(contentId)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return rendition, nil
+}
+
+type fakeWatermarkService struct {
+	WatermarkService
+	payload uint64
+}
+
+func (s fakeWatermarkService) Payload(content *models.Content, userId string) (uint64, error) {
+	return s.payload, nil
+}
+
+type packagingTest struct {
+	*contentTest
+	service    PackagingService
+	renditions *fakeRenditionRepo
+}
+
+func newPackagingTest(payload uint64) *packagingTest {
+	test := &packagingTest{
+		contentTest: newContentTest(similarity.RejectWhenUnavailable),
+		renditions:  &fakeRenditionRepo{renditions: map[uuid.UUID]*models.Rendition{}},
+	}
+	test.service = NewPackagingService(test.renditions, test.contents, test.contentTest.service, nil,
+		fakeWatermarkService{payload: payload}, test.storage, clock.NewFake(time.Now()))
+
+	return test
+}
+
+// tsImport builds a playlist of n MPEG-TS segments and their files.
+func tsImport(n int) ([]byte, map[string][]byte, []byte) {
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:4\n"
+	files := make(map[string][]byte)
+	var joined []byte
+	for i := range n {
+		name := fmt.Sprintf("seg%d.ts", i)
+		data := []byte{0x47, 0x00, 0x11, byte(i)}
+		playlist += fmt.Sprintf("#EXTINF:4.000,\n%s\n", name)
+		files[name] = data
+		joined = append(joined, data...)
+	}
+	playlist += "#EXT-X-ENDLIST\n"
+
+	return []byte(playlist), files, joined
+}
+
+func TestImportReplacesCheckedFile(t *testing.T) {
+	test := newPackagingTest(0)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	playlist, files, joined := tsImport(3)
+	rendition, _, err := test.service.Import(creatorId.String(), content.ContentID.String(), playlist, files)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	stored := test.contents.contents[content.ContentID]
+	if rendition.SourceFileID != stored.FileID || rendition.Watermarked {
+		t.Errorf("rendition source = %s watermarked = %v, want the new file %s unwatermarked", rendition.SourceFileID,
+			rendition.Watermarked, stored.FileID)
+	}
+	if stored.MimeType != "video/mp2t" || stored.FileSize != int64(len(joined)) {
+		t.Errorf("stored %s of %d bytes, want the %d joined segments", stored.MimeType, stored.FileSize, len(joined))
+	}
+	if calls := test.checker.Calls(); len(calls) != 2 {
+		t.Errorf("checker called %d times, want the upload and the import checked", len(calls))
+	}
+
+	viewer := uuid.Must(uuid.NewV4()).String()
+	if _, err := test.service.Segment(stored, viewer, 0); !errors.Is(err, ErrNotWatermarked) {
+		t.Errorf("viewer Segment: err = %v, want %v", err, ErrNotWatermarked)
+	}
+	if _, err := test.service.Segment(stored, creatorId.String(), 0); err != nil {
+		t.Errorf("creator Segment: %v", err)
+	}
+}
+
+func TestImportRejectedBySimilarity(t *testing.T) {
+	test := newPackagingTest(0)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	original := test.contents.contents[content.ContentID].FileID
+
+	test.checker.SetResult(similarity.Result{MatchID: uuid.Must(uuid.NewV4()).String(), Similarity: 0.99})
+	playlist, files, _ := tsImport(2)
+	rendition, check, err := test.service.Import(creatorId.String(), content.ContentID.String(), playlist, files)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	if rendition != nil || check == nil || !check.Similar {
+		t.Errorf("rendition = %v, check = %+v; want a rejected import", rendition, check)
+	}
+	if len(test.renditions.renditions) != 0 || test.contents.contents[content.ContentID].FileID != original {
+		t.Errorf("rejected import replaced the file or stored a rendition")
+	}
+}
+
+func TestImportRequiresEverySegment(t *testing.T) {
+	test := newPackagingTest(0)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	playlist, files, _ := tsImport(2)
+	delete(files, "seg1.ts")
+	if _, _, err := test.service.Import(creatorId.String(), content.ContentID.String(), playlist, files); !errors.Is(err, ErrMissingSegment) {
+		t.Errorf("Import: err = %v, want %v", err, ErrMissingSegment)
+	}
+}
+
+func TestSegmentFollowsViewerWatermark(t *testing.T) {
+	const payload = 0b0110
+	test := newPackagingTest(payload)
+	creatorId := uuid.Must(uuid.NewV4())
+
+	content, _, _, err := test.upload(t, creatorId, pngFile)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	stored := test.contents.contents[content.ContentID]
+
+	rendition := &models.Rendition{ContentID: content.ContentID, SourceFileID: stored.FileID, Watermarked: true}
+	for i := range 4 {
+		for _, variant := range []string{models.VariantA, models.VariantB} {
+			fileId := fmt.Sprintf("%s%d.m4s", variant, i)
+			if err := test.storage.Put(context.Background(), fileId, strings.NewReader(fileId), 0, ""); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			rendition.Segments = append(rendition.Segments, &models.RenditionSegment{Index: i, Variant: variant,
+				FileID: fileId})
+		}
+	}
+	test.renditions.renditions[content.ContentID] = rendition
+
+	viewer := uuid.Must(uuid.NewV4()).String()
+	for i := range 4 {
+		want := models.VariantA
+		if watermark.Bit(payload, i) == 1 {
+			want = models.VariantB
+		}
+
+		data, err := test.service.Segment(stored, viewer, i)
+		if err != nil {
+			t.Fatalf("Segment(%d): %v", i, err)
+		}
+		if !bytes.HasPrefix(data, []byte(want)) {
+			t.Errorf("segment %d served %q, want variant %s", i, data, want)
+		}
+
+		creator, err := test.service.Segment(stored, creatorId.String(), i)
+		if err != nil || !bytes.HasPrefix(creator, []byte(models.VariantA)) {
+			t.Errorf("creator segment %d = %q, %v; want variant A", i, creator, err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/preview"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+)
+
+type PreviewService interface {
+	GenerateMissing() error
+	Upload(userId, contentId, kind string, file io.Reader, fileExt string) (*models.Preview, error)
+	Get(contentId, kind string) (*models.Preview, []byte, error)
+	GetKinds(contentIds []string) (map[string][]string, error)
+}
+
+var ErrPreviewNotFound = errors.New("preview not found")
+
+const previewBatchSize = 5
+
+// previewKinds lists which kinds of file each preview may be.
+var previewKinds = map[string][]mediatype.Kind{
+	models.PreviewThumbnail: {mediatype.KindImage},
+	models.PreviewTrailer:   {mediatype.KindVideo, mediatype.KindAudio},
+}
+
+type previewService struct {
+	previewRepo repositories.PreviewRepository
+	contentRepo repositories.ContentRepository
+	storage     storage.Storage
+	clock       clock.Clock
+}
+
+func NewPreviewService(previewRepo repositories.PreviewRepository, contentRepo repositories.ContentRepository,
+	storage storage.Storage, clock clock.Clock) PreviewService {
+	return &previewService{previewRepo: previewRepo, contentRepo: contentRepo, storage: storage, clock: clock}
+}
+
+// GenerateMissing cuts a thumbnail and trailer from video content that lacks them. Previews the creator uploaded
+// are never replaced.
+func (s *previewService) GenerateMissing() error {
+	if !preview.Available() {
+		return nil
+	}
+
+	contents, err := s.previewRepo.GetPending(previewBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		if err := s.generate(content); err != nil {
+			log.Printf("failed to generate previews for content %s: %v\n", content.ContentID, err)
+			if err := s.previewRepo.RecordFailure(content, err.Error()); err != nil {
+				log.Printf("failed to record failure for content %s: %v\n", content.ContentID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *previewService) generate(content *models.Content) error {
+	ctx := context.Background()
+
+	existing, err := s.previewRepo.GetByContent(content.ContentID.String())
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		current[p.Kind] = p.SourceFileID == nil || *p.SourceFileID == content.FileID
+	}
+
+	dir, err := os.MkdirTemp("", "preview-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := s.storage.Get(ctx, content.FileID)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	src := filepath.Join(dir, "source"+filepath.Ext(content.FileID))
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		return err
+	}
+
+	generators := []struct {
+		kind     string
+		mimeType string
+		render   func(ctx context.Context, src, dst string) error
+	}{
+		{models.PreviewThumbnail, "image/jpeg", preview.Thumbnail},
+		{models.PreviewTrailer, "video/mp4", preview.Trailer},
+	}
+
+	for _, g := range generators {
+		if current[g.kind] {
+			continue
+		}
+
+		fileType := mediatype.Lookup(g.mimeType)
+		dst := filepath.Join(dir, g.kind+fileType.Extension)
+		if err := g.render(ctx, src, dst); err != nil {
+			return err
+		}
+
+		out, err := os.ReadFile(dst)
+		if err != nil {
+			return err
+		}
+
+		sourceFileId := content.FileID
+		if _, err := s.store(content, g.kind, fileType, out, &sourceFileId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *previewService) store(content *models.Content, kind string, fileType mediatype.Type, data []byte,
+	sourceFileId *string) (*models.Preview, error) {
+	ctx := context.Background()
+
+	fileId, err := storage.Upload(ctx, s.storage, bytes.NewReader(data), fileType.Extension, int64(len(data)), fileType.MIME)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &models.Preview{
+		ContentID:    content.ContentID,
+		Kind:         kind,
+		FileID:       fileId,
+		MimeType:     fileType.MIME,
+		FileSize:     int64(len(data)),
+		SourceFileID: sourceFileId,
+		CreatedAt:    s.clock.Now(),
+	}
+
+	stale, err := s.previewRepo.Set(p)
+	if err != nil {
+		s.storage.Delete(ctx, fileId)
+		return nil, err
+	}
+
+	if stale != "" {
+		if err := s.storage.Delete(ctx, stale); err != nil {
+			log.Printf("failed to delete replaced preview %s: %v\n", stale, err)
+		}
+	}
+
+	return p, nil
+}
+
+func (s *previewService) Upload(userId, contentId, kind string, file io.Reader, fileExt string) (*models.Preview, error) {
+	allowed, ok := previewKinds[kind]
+	if !ok {
+		return nil, ErrPreviewNotFound
+	}
+
+	content, err := s.contentRepo.GetById(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContentNotFound
+		}
+		return nil, err
+	}
+
+	if content.DeletedAt != nil {
+		return nil, ErrContentNotFound
+	}
+	if content.CreatorID.String() != userId {
+		return nil, ErrNotCreator
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	fileType, err := mediatype.Detect(data, fileExt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range allowed {
+		if fileType.Kind == k {
+			return s.store(content, kind, fileType, data, nil)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: a %s cannot be %s", mediatype.ErrUnsupported, kind, fileType.MIME)
+}
+
+// Get returns a preview without any license check. Previews are public, so they are refused for content that is
+// deleted or not listed while its similarity check is rejected or under review.
+func (s *previewService) Get(contentId, kind string) (*models.Preview, []byte, error) {
+	content, err := s.contentRepo.GetById(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrContentNotFound
+		}
+		return nil, nil, err
+	}
+
+	if content.DeletedAt != nil || !content.Listed() {
+		return nil, nil, ErrContentNotFound
+	}
+
+	p, err := s.previewRepo.Get(contentId, kind)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrPreviewNotFound
+		}
+		return nil, nil, err
+	}
+
+	file, err := s.storage.Get(context.Background(), p.FileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, data, nil
+}
+
+func (s *previewService) GetKinds(contentIds []string) (map[string][]string, error) {
+	return s.previewRepo.GetKinds(contentIds)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+	"github.com/gofrs/uuid"
+)
+
+type fakePreviewRepo struct {
+	repositories.PreviewRepository
+	previews map[string]*models.Preview
+}
+
+func (r *fakePreviewRepo) Get(contentId, kind string) (*models.Preview, error) {
+	preview, ok := r.previews[contentId+"/"+kind]
+	if !ok {
+		return nil, errors.New("unexpected preview lookup")
+	}
+	return preview, nil
+}
+
+func TestPreviewGetRefusesUnlistedContent(t *testing.T) {
+	contents := &fakeContentRepo{contents: map[uuid.UUID]*models.Content{}}
+	previews := &fakePreviewRepo{previews: map[string]*models.Preview{}}
+	store := storage.NewMemory()
+	service := NewPreviewService(previews, contents, store, clock.NewFake(time.Now()))
+
+	tests := []struct {
+		similarity string
+		wantErr    error
+	}{
+		{models.SimilarityPassed, nil},
+		{models.SimilarityUnchecked, nil},
+		{models.SimilarityFlagged, ErrContentNotFound},
+		{models.SimilarityReview, ErrContentNotFound},
+		{models.SimilarityRejected, ErrContentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.similarity, func(t *testing.T) {
+			contentId := uuid.Must(uuid.NewV4())
+			contents.contents[contentId] = &models.Content{ContentID: contentId, Similarity: tt.similarity}
+
+			fileId := contentId.String() + ".jpg"
+			if err := store.Put(context.Background(), fileId, bytes.NewReader([]byte("jpeg")), 4, ""); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			previews.previews[contentId.String()+"/"+models.PreviewThumbnail] = &models.Preview{ContentID: contentId,
+				Kind: models.PreviewThumbnail, FileID: fileId}
+
+			_, data, err := service.Get(contentId.String(), models.PreviewThumbnail)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get: err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && string(data) != "jpeg" {
+				t.Errorf("Get = %q, want the stored thumbnail", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+type PricingService interface {
+	CreateCoupon(coupon *models.Coupon, isAdmin bool) error
+	ListCoupons(userId string) ([]*models.Coupon, error)
+	CreateSale(userId string, sale *models.Sale) error
+	QuoteContent(userId string, content *models.Content, currency, couponCode string) (*models.Quote, error)
+	QuoteCollection(userId string, collection *models.Collection, currency, couponCode string) (*models.Quote, error)
+}
+
+var (
+	ErrInvalidCoupon    = errors.New("coupon is invalid or does not apply")
+	ErrCouponExhausted  = errors.New("coupon usage limit reached")
+	ErrCouponCodeTaken  = errors.New("coupon code already exists")
+	ErrPriceUnavailable = errors.New("not priced in the requested currency")
+)
+
+type pricingService struct {
+	pricingRepo repositories.PricingRepository
+	contentRepo repositories.ContentRepository
+	orderRepo   repositories.OrderRepository
+}
+
+func NewPricingService(pricingRepo repositories.PricingRepository, contentRepo repositories.ContentRepository,
+	orderRepo repositories.OrderRepository) PricingService {
+	return &pricingService{pricingRepo: pricingRepo, contentRepo: contentRepo, orderRepo: orderRepo}
+}
+
+func (s *pricingService) CreateCoupon(coupon *models.Coupon, isAdmin bool) error {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if coupon.Code == "" {
+		return errors.New("coupon code cannot be empty")
+	}
+
+	switch coupon.Kind {
+	case models.CouponPercent:
+		if coupon.Percent == nil || *coupon.Percent <= 0 || *coupon.Percent > 100 {
+			return errors.New("percent coupon must be between 1 and 100")
+		}
+		coupon.Amount = nil
+	case models.CouponFixed:
+		if coupon.Amount == nil {
+			return errors.New("fixed coupon must have an amount")
+		}
+		if err := normalizeMoney(coupon.Amount); err != nil {
+			return err
+		}
+		if coupon.Amount.IsZero() {
+			return errors.New("fixed coupon amount must be positive")
+		}
+		coupon.Percent = nil
+	default:
+		return errors.New("coupon kind must be percent or fixed")
+	}
+
+	if coupon.MaxUses != nil && *coupon.MaxUses <= 0 {
+		return errors.New("coupon max uses must be positive")
+	}
+	if coupon.PerUserLimit != nil && *coupon.PerUserLimit <= 0 {
+		return errors.New("coupon per-user limit must be positive")
+	}
+
+	if !isAdmin {
+		creatorId := coupon.CreatedBy
+		coupon.CreatorID = &creatorId
+	}
+
+	if coupon.ContentID != nil {
+		content, err := s.contentRepo.GetById(coupon.ContentID.String())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrContentNotFound
+			}
+			return err
+		}
+
+		if coupon.CreatorID != nil && content.CreatorID != *coupon.CreatorID {
+			return ErrNotCreator
+		}
+	}
+
+	if _, err := s.pricingRepo.GetCouponByCode(coupon.Code); err == nil {
+		return ErrCouponCodeTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	couponId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	coupon.CouponID = couponId
+	coupon.Uses = 0
+	coupon.CreatedAt = time.Now()
+	if coupon.StartsAt.IsZero() {
+		coupon.StartsAt = coupon.CreatedAt
+	}
+
+	return s.pricingRepo.CreateCoupon(coupon)
+}
+
+func (s *pricingService) ListCoupons(userId string) ([]*models.Coupon, error) {
+	return s.pricingRepo.GetCouponsByCreator(userId)
+}
+
+func (s *pricingService) CreateSale(userId string, sale *models.Sale) error {
+	content, err := s.contentRepo.GetById(sale.ContentID.String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrContentNotFound
+		}
+		return err
+	}
+
+	if content.CreatorID.String() != userId {
+		return ErrNotCreator
+	}
+
+	if err := normalizeMoney(&sale.SalePrice); err != nil {
+		return err
+	}
+
+	listPrice, err := s.priceIn(content, sale.SalePrice.Currency)
+	if err != nil {
+		return err
+	}
+
+	if sale.SalePrice.Amount >= listPrice.Amount {
+		return errors.New("sale price must be below the list price")
+	}
+	if !sale.EndsAt.After(sale.StartsAt) {
+		return errors.New("sale must end after it starts")
+	}
+
+	saleId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	sale.SaleID = saleId
+	sale.CreatedAt = time.Now()
+
+	return s.pricingRepo.CreateSale(sale)
+}
+
+func (s *pricingService) QuoteContent(userId string, content *models.Content, currency, couponCode string) (*models.Quote, error) {
+	listPrice, err := s.priceIn(content, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &models.Quote{ListPrice: listPrice, BasePrice: listPrice}
+
+	sale, err := s.pricingRepo.GetActiveSale(content.ContentID.String(), listPrice.Currency, time.Now())
+	if err == nil {
+		quote.BasePrice = sale.SalePrice
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return s.applyCoupon(quote, userId, couponCode, func(coupon *models.Coupon) bool {
+		if coupon.ContentID != nil && *coupon.ContentID != content.ContentID {
+			return false
+		}
+		return coupon.CreatorID == nil || *coupon.CreatorID == content.CreatorID
+	})
+}
+
+func (s *pricingService) QuoteCollection(userId string, collection *models.Collection, currency, couponCode string) (*models.Quote, error) {
+	if collection.Price == nil {
+		return nil, ErrCollectionNotForSale
+	}
+
+	if currency != "" && !strings.EqualFold(currency, collection.Price.Currency) {
+		return nil, ErrPriceUnavailable
+	}
+
+	quote := &models.Quote{ListPrice: *collection.Price, BasePrice: *collection.Price}
+
+	return s.applyCoupon(quote, userId, couponCode, func(coupon *models.Coupon) bool {
+		if coupon.ContentID != nil {
+			return false
+		}
+		return coupon.CreatorID == nil || *coupon.CreatorID == collection.CreatorID
+	})
+}
+
+func (s *pricingService) applyCoupon(quote *models.Quote, userId, couponCode string, applies func(*models.Coupon) bool) (*models.Quote, error) {
+	quote.Price = quote.BasePrice
+
+	if couponCode != "" {
+		coupon, err := s.validCoupon(userId, couponCode)
+		if err != nil {
+			return nil, err
+		}
+
+		if !applies(coupon) {
+			return nil, ErrInvalidCoupon
+		}
+
+		switch coupon.Kind {
+		case models.CouponPercent:
+			quote.Price = quote.BasePrice.Percent(100 - *coupon.Percent)
+		case models.CouponFixed:
+			price, err := quote.BasePrice.Sub(*coupon.Amount)
+			if err != nil {
+				return nil, ErrInvalidCoupon
+			}
+			if price.Amount < 0 {
+				price.Amount = 0
+			}
+			quote.Price = price
+		}
+		quote.Coupon = coupon
+	}
+
+	discount, err := quote.ListPrice.Sub(quote.Price)
+	if err != nil {
+		return nil, err
+	}
+	quote.Discount = discount
+
+	return quote, nil
+}
+
+func (s *pricingService) priceIn(content *models.Content, currency string) (money.Money, error) {
+	if currency == "" {
+		return content.Price, nil
+	}
+
+	currency, err := money.NormalizeCurrency(currency)
+	if err != nil {
+		return money.Money{}, ErrPriceUnavailable
+	}
+
+	if content.Price.Currency == currency {
+		return content.Price, nil
+	}
+
+	prices, err := s.contentRepo.GetPrices(content.ContentID.String())
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	for _, price := range prices {
+		if price.Currency == currency {
+			return price, nil
+		}
+	}
+
+	return money.Money{}, ErrPriceUnavailable
+}
+
+func (s *pricingService) validCoupon(userId, code string) (*models.Coupon, error) {
+	coupon, err := s.pricingRepo.GetCouponByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCoupon
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if coupon.StartsAt.After(now) || (coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(now)) {
+		return nil, ErrInvalidCoupon
+	}
+
+	if coupon.MaxUses != nil && coupon.Uses >= *coupon.MaxUses {
+		return nil, ErrCouponExhausted
+	}
+
+	if coupon.PerUserLimit != nil {
+		used, err := s.orderRepo.CountCouponUses(userId, coupon.CouponID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if used >= *coupon.PerUserLimit {
+			return nil, ErrCouponExhausted
+		}
+	}
+
+	return coupon, nil
+}
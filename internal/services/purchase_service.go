@@ -0,0 +1,315 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/payment"
+	"github.com/gofrs/uuid"
+)
+
+type PurchaseService interface {
+	QuoteContent(userId, contentId, currency, couponCode string) (*models.Quote, error)
+	QuoteCollection(userId, collectionId, currency, couponCode string) (*models.Quote, error)
+	PurchaseContent(userId, contentId, currency, couponCode string, expiresAt time.Time) (*models.Order, error)
+	PurchaseCollection(userId, collectionId, currency, couponCode string, expiresAt time.Time) (*models.Order, error)
+	PurchaseGift(userId, contentId, currency, couponCode string) (*models.GiftCode, error)
+}
+
+type purchaseService struct {
+	contentRepo    repositories.ContentRepository
+	collectionRepo repositories.CollectionRepository
+	orderRepo      repositories.OrderRepository
+	pricingService PricingService
+	licenseService LicenseService
+	ledgerService  LedgerService
+	payments       payment.Provider
+}
+
+func NewPurchaseService(contentRepo repositories.ContentRepository, collectionRepo repositories.CollectionRepository,
+	orderRepo repositories.OrderRepository, pricingService PricingService, licenseService LicenseService,
+	ledgerService LedgerService, payments payment.Provider) PurchaseService {
+	return &purchaseService{contentRepo: contentRepo, collectionRepo: collectionRepo, orderRepo: orderRepo,
+		pricingService: pricingService, licenseService: licenseService, ledgerService: ledgerService, payments: payments}
+}
+
+func (s *purchaseService) getContent(contentId string) (*models.Content, error) {
+	content, err := s.contentRepo.GetById(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContentNotFound
+		}
+		return nil, err
+	}
+
+	if content.DeletedAt != nil {
+		return nil, ErrContentNotFound
+	}
+	if !content.Listed() {
+		return nil, ErrContentUnderReview
+	}
+
+	return content, nil
+}
+
+func (s *purchaseService) getCollection(collectionId string) (*models.Collection, error) {
+	collection, err := s.collectionRepo.GetById(collectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+
+	collection.Items, err = s.collectionRepo.GetItems(collectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.Price == nil || len(collection.Items) == 0 {
+		return nil, ErrCollectionNotForSale
+	}
+
+	return collection, nil
+}
+
+func (s *purchaseService) QuoteContent(userId, contentId, currency, couponCode string) (*models.Quote, error) {
+	content, err := s.getContent(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pricingService.QuoteContent(userId, content, currency, couponCode)
+}
+
+func (s *purchaseService) QuoteCollection(userId, collectionId, currency, couponCode string) (*models.Quote, error) {
+	collection, err := s.getCollection(collectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pricingService.QuoteCollection(userId, collection, currency, couponCode)
+}
+
+func (s *purchaseService) PurchaseContent(userId, contentId, currency, couponCode string, expiresAt time.Time) (*models.Order, error) {
+	content, err := s.getContent(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.pricingService.QuoteContent(userId, content, currency, couponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.newOrder(userId, quote)
+	if err != nil {
+		return nil, err
+	}
+	order.CreatorID = &content.CreatorID
+	order.ContentID = &content.ContentID
+
+	licenses, err := s.licenseService.Prepare(userId, &order.OrderID, []string{contentId}, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkout(&models.Fulfilment{Order: order, Licenses: licenses}); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *purchaseService) PurchaseCollection(userId, collectionId, currency, couponCode string, expiresAt time.Time) (*models.Order, error) {
+	collection, err := s.getCollection(collectionId)
+	if err != nil {
+		return nil, err
+	}
+
+	contentIds, err := s.unowned(userId, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.pricingService.QuoteCollection(userId, collection, currency, couponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.newOrder(userId, quote)
+	if err != nil {
+		return nil, err
+	}
+	order.CreatorID = &collection.CreatorID
+	order.CollectionID = &collection.CollectionID
+
+	purchase := &models.CollectionPurchase{
+		PurchaseID:   order.OrderID,
+		CollectionID: collection.CollectionID,
+		UserID:       order.UserID,
+		Price:        order.Price,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    order.CreatedAt,
+	}
+
+	licenses, err := s.licenseService.Prepare(userId, &order.OrderID, contentIds, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkout(&models.Fulfilment{Order: order, Licenses: licenses, Purchase: purchase}); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// PurchaseGift sells a gift code for the content instead of a license; whoever redeems the code is licensed.
+func (s *purchaseService) PurchaseGift(userId, contentId, currency, couponCode string) (*models.GiftCode, error) {
+	content, err := s.getContent(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.pricingService.QuoteContent(userId, content, currency, couponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.newOrder(userId, quote)
+	if err != nil {
+		return nil, err
+	}
+	order.CreatorID = &content.CreatorID
+	order.ContentID = &content.ContentID
+
+	giftId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateGiftCode()
+	if err != nil {
+		return nil, err
+	}
+
+	gift := &models.GiftCode{
+		GiftID:      giftId,
+		Code:        code,
+		ContentID:   content.ContentID,
+		PurchaserID: order.UserID,
+		OrderID:     &order.OrderID,
+		ExpiresAt:   order.CreatedAt.Add(giftCodeValidity),
+		CreatedAt:   order.CreatedAt,
+	}
+
+	if err = s.checkout(&models.Fulfilment{Order: order, Gift: gift}); err != nil {
+		return nil, err
+	}
+
+	return gift, nil
+}
+
+// unowned returns the collection's items the user does not already hold a license for.
+func (s *purchaseService) unowned(userId string, collection *models.Collection) ([]string, error) {
+	contentIds := make([]string, len(collection.Items))
+	for i, item := range collection.Items {
+		contentIds[i] = item.ContentID.String()
+	}
+
+	owned, err := s.licenseService.Licensed(userId, contentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, contentId := range contentIds {
+		if !owned[contentId] {
+			missing = append(missing, contentId)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil, ErrAlreadyOwned
+	}
+
+	return missing, nil
+}
+
+// checkout records the order as pending and redeems its coupon, charges for it, then marks it paid together with its
+// ledger entries and whatever it delivers. If anything fails after the order is recorded it is marked failed, which
+// gives back the coupon use, and any charge is refunded.
+func (s *purchaseService) checkout(fulfilment *models.Fulfilment) error {
+	order := fulfilment.Order
+
+	if err := s.orderRepo.Create(order); err != nil {
+		if errors.Is(err, repositories.ErrCouponExhausted) {
+			return ErrCouponExhausted
+		}
+		return err
+	}
+
+	if !order.Price.IsZero() {
+		paymentId, err := s.payments.Charge(order.OrderID.String(), order.Price)
+		if err != nil {
+			s.failOrder(order)
+			return err
+		}
+		order.PaymentID = paymentId
+	}
+
+	entries, err := s.ledgerService.SaleEntries(order)
+	if err == nil {
+		fulfilment.Entries = entries
+		err = s.orderRepo.Fulfil(fulfilment)
+	}
+	if err != nil {
+		if order.PaymentID != "" {
+			if refundErr := s.payments.Refund(order.PaymentID, order.Price); refundErr != nil {
+				// Left pending, since the customer has paid for an order that was never fulfilled.
+				log.Printf("failed to refund payment %s for order %s: %v\n", order.PaymentID, order.OrderID, refundErr)
+				return err
+			}
+		}
+		s.failOrder(order)
+		return err
+	}
+
+	order.Status = models.OrderPaid
+	return nil
+}
+
+func (s *purchaseService) failOrder(order *models.Order) {
+	if err := s.orderRepo.Fail(order.OrderID.String()); err != nil {
+		log.Printf("failed to mark order %s failed: %v\n", order.OrderID, err)
+	}
+	order.Status = models.OrderFailed
+}
+
+func (s *purchaseService) newOrder(userId string, quote *models.Quote) (*models.Order, error) {
+	orderId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		OrderID:   orderId,
+		UserID:    uuid.FromStringOrNil(userId),
+		ListPrice: quote.ListPrice,
+		Discount:  quote.Discount,
+		Price:     quote.Price,
+		Status:    models.OrderPending,
+		CreatedAt: time.Now(),
+	}
+
+	if quote.Coupon != nil {
+		order.CouponID = &quote.Coupon.CouponID
+	}
+
+	return order, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+)
+
+// NoLimit is the upload limit of creators whose tier sets none, and of admins.
+const NoLimit int64 = -1
+
+type QuotaService interface {
+	Usage(userId string) (*models.StorageUsage, error)
+	UploadLimit(userId string, replacing int64, newItem bool) (int64, error)
+	FileSizeLimit(userId string) (int64, error)
+	Check(userId string, size, replacing int64, newItem bool) error
+	ListTiers() ([]*models.StorageTier, error)
+	SetTier(userId, tier string) error
+}
+
+var (
+	ErrQuotaExceeded    = errors.New("storage quota exceeded")
+	ErrItemLimitReached = errors.New("content item limit reached")
+	ErrFileTooLarge     = errors.New("file exceeds the upload size limit")
+	ErrUnknownTier      = errors.New("unknown storage tier")
+	ErrUserNotFound     = errors.New("user not found")
+)
+
+type quotaService struct {
+	quotaRepo repositories.QuotaRepository
+}
+
+func NewQuotaService(quotaRepo repositories.QuotaRepository) QuotaService {
+	return &quotaService{quotaRepo: quotaRepo}
+}
+
+func (s *quotaService) getTier(userId string) (*models.StorageTier, error) {
+	tier, role, err := s.quotaRepo.GetTier(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if role == models.RoleAdmin {
+		return &models.StorageTier{Name: tier.Name}, nil
+	}
+
+	return tier, nil
+}
+
+func (s *quotaService) Usage(userId string) (*models.StorageUsage, error) {
+	tier, err := s.getTier(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	used, items, err := s.quotaRepo.GetUsage(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.StorageUsage{
+		Tier:        tier.Name,
+		UsedBytes:   used,
+		Items:       items,
+		MaxBytes:    tier.MaxBytes,
+		MaxItems:    tier.MaxItems,
+		MaxFileSize: tier.MaxFileSize,
+	}
+	if tier.MaxBytes != nil {
+		remaining := max(*tier.MaxBytes-used, 0)
+		usage.RemainingBytes = &remaining
+	}
+
+	return usage, nil
+}
+
+// limits returns the largest single file the tier allows and the bytes left in the quota, counting a file about to
+// be replaced as freed. Either is NoLimit when the tier sets none.
+func (s *quotaService) limits(userId string, replacing int64, newItem bool) (int64, int64, error) {
+	usage, err := s.Usage(userId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if newItem && usage.MaxItems != nil && usage.Items >= *usage.MaxItems {
+		return 0, 0, fmt.Errorf("%w: %d of %d items used", ErrItemLimitReached, usage.Items, *usage.MaxItems)
+	}
+
+	fileLimit, remaining := NoLimit, NoLimit
+	if usage.MaxFileSize != nil {
+		fileLimit = *usage.MaxFileSize
+	}
+	if usage.MaxBytes != nil {
+		remaining = max(*usage.MaxBytes-usage.UsedBytes+replacing, 0)
+	}
+
+	return fileLimit, remaining, nil
+}
+
+// UploadLimit returns how large a file the user may upload right now, or NoLimit. Handlers use it to cap the request
+// body before reading it; Check still has the final say once the size is known.
+func (s *quotaService) UploadLimit(userId string, replacing int64, newItem bool) (int64, error) {
+	fileLimit, remaining, err := s.limits(userId, replacing, newItem)
+	if err != nil {
+		return 0, err
+	}
+
+	if remaining == 0 {
+		return 0, ErrQuotaExceeded
+	}
+
+	switch {
+	case fileLimit == NoLimit:
+		return remaining, nil
+	case remaining == NoLimit:
+		return fileLimit, nil
+	default:
+		return min(fileLimit, remaining), nil
+	}
+}
+
+// FileSizeLimit is the per-file limit alone, for uploads such as previews that do not count towards the quota.
+func (s *quotaService) FileSizeLimit(userId string) (int64, error) {
+	fileLimit, _, err := s.limits(userId, 0, false)
+	return fileLimit, err
+}
+
+func (s *quotaService) Check(userId string, size, replacing int64, newItem bool) error {
+	fileLimit, remaining, err := s.limits(userId, replacing, newItem)
+	if err != nil {
+		return err
+	}
+
+	if fileLimit != NoLimit && size > fileLimit {
+		return fmt.Errorf("%w: %d bytes allowed", ErrFileTooLarge, fileLimit)
+	}
+	if remaining != NoLimit && size > remaining {
+		return fmt.Errorf("%w: %d bytes remaining", ErrQuotaExceeded, remaining)
+	}
+
+	return nil
+}
+
+// reservationError translates the quota errors the content repository raises when a write would exceed the tier.
+// Check runs before the upload is stored; the repository enforces the same limits atomically with the write.
+func reservationError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrQuotaExceeded):
+		return ErrQuotaExceeded
+	case errors.Is(err, repositories.ErrItemLimitReached):
+		return ErrItemLimitReached
+	default:
+		return err
+	}
+}
+
+func (s *quotaService) ListTiers() ([]*models.StorageTier, error) {
+	return s.quotaRepo.GetTiers()
+}
+
+func (s *quotaService) SetTier(userId, tier string) error {
+	err := s.quotaRepo.SetTier(userId, tier)
+	switch {
+	case errors.Is(err, repositories.ErrUnknownTier):
+		return fmt.Errorf("%w: %s", ErrUnknownTier, tier)
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrUserNotFound
+	default:
+		return err
+	}
+}
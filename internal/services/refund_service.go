@@ -0,0 +1,281 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/payment"
+	"github.com/gofrs/uuid"
+)
+
+type RefundService interface {
+	ListOrders(userId string) ([]*models.Order, error)
+	Request(userId, orderId, reason string) (*models.Refund, error)
+	Approve(userId, refundId string, isAdmin bool) (*models.Refund, error)
+	Reject(userId, refundId string, isAdmin bool) (*models.Refund, error)
+	ListByUser(userId string) ([]*models.Refund, error)
+	ListPending(userId string, isAdmin bool) ([]*models.Refund, error)
+}
+
+var (
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrRefundNotFound   = errors.New("refund not found")
+	ErrAlreadyRefunded  = errors.New("order has already been refunded")
+	ErrRefundInProgress = errors.New("a refund has already been requested for this order")
+	ErrNotRefundable    = errors.New("order can no longer be refunded")
+)
+
+// Requests made within the window for content that has never been played are refunded without review.
+const autoRefundWindow = 24 * time.Hour
+
+type refundService struct {
+	refundRepo    repositories.RefundRepository
+	orderRepo     repositories.OrderRepository
+	licenseRepo   repositories.LicenseRepository
+	giftRepo      repositories.GiftRepository
+	playRepo      repositories.PlayRepository
+	ledgerService LedgerService
+	payments      payment.Provider
+	clock         clock.Clock
+}
+
+func NewRefundService(refundRepo repositories.RefundRepository, orderRepo repositories.OrderRepository,
+	licenseRepo repositories.LicenseRepository, giftRepo repositories.GiftRepository, playRepo repositories.PlayRepository,
+	ledgerService LedgerService, payments payment.Provider, clock clock.Clock) RefundService {
+	return &refundService{refundRepo: refundRepo, orderRepo: orderRepo, licenseRepo: licenseRepo, giftRepo: giftRepo,
+		playRepo: playRepo, ledgerService: ledgerService, payments: payments, clock: clock}
+}
+
+func (s *refundService) ListOrders(userId string) ([]*models.Order, error) {
+	return s.orderRepo.GetByUser(userId)
+}
+
+func (s *refundService) Request(userId, orderId, reason string) (*models.Refund, error) {
+	order, err := s.orderRepo.GetById(orderId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	if order.UserID.String() != userId {
+		return nil, ErrOrderNotFound
+	}
+	// Orders placed before creators were recorded, for content since deleted, have no one to review a refund.
+	if order.Status != models.OrderPaid || order.CreatorID == nil {
+		return nil, ErrNotRefundable
+	}
+	if order.RefundedAt != nil {
+		return nil, ErrAlreadyRefunded
+	}
+
+	contentIds, active, err := s.orderContent(order)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrNotRefundable
+	}
+
+	refundId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	refund := &models.Refund{
+		RefundID:  refundId,
+		OrderID:   order.OrderID,
+		UserID:    order.UserID,
+		CreatorID: *order.CreatorID,
+		Reason:    reason,
+		Status:    models.RefundPending,
+		CreatedAt: now,
+	}
+
+	if err = s.refundRepo.Create(refund); err != nil {
+		if errors.Is(err, repositories.ErrRefundExists) {
+			return nil, ErrRefundInProgress
+		}
+		return nil, err
+	}
+
+	if now.Sub(order.CreatedAt) > autoRefundWindow {
+		return refund, nil
+	}
+
+	played, err := s.playRepo.CountSince(userId, contentIds, order.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if played > 0 {
+		return refund, nil
+	}
+
+	if err = s.complete(refund, order, contentIds); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func (s *refundService) getPending(userId, refundId string, isAdmin bool) (*models.Refund, error) {
+	refund, err := s.refundRepo.GetById(refundId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+
+	if !isAdmin && refund.CreatorID.String() != userId {
+		return nil, ErrRefundNotFound
+	}
+	if refund.Status != models.RefundPending {
+		return nil, ErrRefundNotFound
+	}
+
+	return refund, nil
+}
+
+func (s *refundService) Approve(userId, refundId string, isAdmin bool) (*models.Refund, error) {
+	refund, err := s.getPending(userId, refundId, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetById(refund.OrderID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	contentIds, _, err := s.orderContent(order)
+	if err != nil {
+		return nil, err
+	}
+
+	decidedBy := uuid.FromStringOrNil(userId)
+	refund.DecidedBy = &decidedBy
+
+	if err = s.complete(refund, order, contentIds); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func (s *refundService) Reject(userId, refundId string, isAdmin bool) (*models.Refund, error) {
+	refund, err := s.getPending(userId, refundId, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if err = s.refundRepo.Reject(refundId, userId, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+
+	decidedBy := uuid.FromStringOrNil(userId)
+	refund.Status = models.RefundRejected
+	refund.DecidedBy = &decidedBy
+	refund.DecidedAt = &now
+
+	return refund, nil
+}
+
+func (s *refundService) ListByUser(userId string) ([]*models.Refund, error) {
+	return s.refundRepo.GetByUser(userId)
+}
+
+func (s *refundService) ListPending(userId string, isAdmin bool) ([]*models.Refund, error) {
+	if isAdmin {
+		return s.refundRepo.GetPending("")
+	}
+
+	return s.refundRepo.GetPending(userId)
+}
+
+// orderContent returns the content the order licensed and whether any of those licenses is still active. Orders
+// whose licenses were transferred away cannot be refunded.
+func (s *refundService) orderContent(order *models.Order) ([]string, bool, error) {
+	licenses, err := s.licenseRepo.GetByOrder(order.OrderID.String())
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := s.clock.Now()
+	active := false
+	if len(licenses) == 0 {
+		// A gift order licenses nobody until its code is redeemed, and redeeming it licenses someone else.
+		gift, err := s.giftRepo.GetByOrder(order.OrderID.String())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		return nil, gift.RedeemedBy == nil && gift.ExpiresAt.After(now), nil
+	}
+
+	contentIds := make([]string, 0, len(licenses))
+	for _, license := range licenses {
+		if license.UserID != order.UserID {
+			return nil, false, ErrNotRefundable
+		}
+		if license.ExpiresAt.After(now) {
+			active = true
+		}
+		contentIds = append(contentIds, license.ContentID.String())
+	}
+
+	return contentIds, active, nil
+}
+
+// complete claims the refund, returns the money, then marks the order refunded together with the revoked access and
+// the reversing ledger entries. If the payment provider fails the claim is released so the refund can be retried.
+func (s *refundService) complete(refund *models.Refund, order *models.Order, contentIds []string) error {
+	now := s.clock.Now()
+
+	if err := s.refundRepo.Claim(refund, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAlreadyRefunded
+		}
+		return err
+	}
+
+	if !order.Price.IsZero() {
+		if err := s.payments.Refund(order.PaymentID, order.Price); err != nil {
+			if reopenErr := s.refundRepo.Reopen(refund); reopenErr != nil {
+				log.Printf("failed to reopen refund %s: %v\n", refund.RefundID, reopenErr)
+			}
+			return fmt.Errorf("refund payment: %w", err)
+		}
+	}
+
+	entries, err := s.ledgerService.RefundEntries(order)
+	if err == nil {
+		err = s.refundRepo.Complete(&models.Reversal{Refund: refund, Order: order, ContentIDs: contentIds,
+			Entries: entries}, now)
+	}
+	if err != nil {
+		// Left claimed rather than reopened, since the money has already gone back to the buyer.
+		log.Printf("refund %s was paid out but not recorded: %v\n", refund.RefundID, err)
+		return err
+	}
+
+	refund.Status = models.RefundRefunded
+	refund.DecidedAt = &now
+	order.RefundedAt = &now
+
+	return nil
+}
@@ -13,72 +13,96 @@ import (
 
 type SessionKeyService interface {
 	GetOrCreate(userId, contentId string) ([]byte, error)
+	Issue(userId, contentId string) (*models.SessionKey, error)
+	Verify(userId, contentId, keyId string) bool
+	RecordPlay(userId, contentId string) error
 }
 
 type sessionKeyService struct {
 	sessionKeyRepo repositories.SessionKeyRepository
+	playRepo       repositories.PlayRepository
 }
 
-func NewSessionKeyService(sessionKeyRepo repositories.SessionKeyRepository) SessionKeyService {
-	return &sessionKeyService{sessionKeyRepo: sessionKeyRepo}
+func NewSessionKeyService(sessionKeyRepo repositories.SessionKeyRepository, playRepo repositories.PlayRepository) SessionKeyService {
+	return &sessionKeyService{sessionKeyRepo: sessionKeyRepo, playRepo: playRepo}
 }
 
 func (s *sessionKeyService) GetOrCreate(userId, contentId string) ([]byte, error) {
-	sessionKey, err := s.sessionKeyRepo.Get(userId, contentId)
+	sessionKey, err := s.Issue(userId, contentId)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			key, err := generateSessionKey()
-			if err != nil {
-				return nil, err
-			}
-
-			keyId, err := uuid.NewV4()
-			if err != nil {
-				return nil, err
-			}
-
-			newSessionKey := &models.SessionKey{
-				KeyID:      keyId,
-				UserID:     uuid.FromStringOrNil(userId),
-				ContentID:  uuid.FromStringOrNil(contentId),
-				SessionKey: key,
-				CreatedAt:  time.Now(),
-				ExpiresAt:  time.Now().Add(24 * time.Hour),
-			}
-
-			err = s.sessionKeyRepo.Create(newSessionKey)
-			if err != nil {
-				return nil, err
-			}
-			return newSessionKey.SessionKey, nil
-		}
 		return nil, err
 	}
 
-	if sessionKey.ExpiresAt.Before(time.Now()) {
+	return sessionKey.SessionKey, nil
+}
+
+// Issue returns the viewer's current session for the content, replacing it once it has expired. Each call is
+// recorded as a play, since a session that is reused does not show when the content was last watched.
+func (s *sessionKeyService) Issue(userId, contentId string) (*models.SessionKey, error) {
+	if err := s.RecordPlay(userId, contentId); err != nil {
+		return nil, err
+	}
+
+	sessionKey, err := s.sessionKeyRepo.Get(userId, contentId)
+	if err == nil && sessionKey.ExpiresAt.After(time.Now()) {
+		return sessionKey, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if sessionKey != nil {
 		s.sessionKeyRepo.Delete(sessionKey.KeyID.String())
+	}
 
-		key, err := generateSessionKey()
-		if err != nil {
-			return nil, err
-		}
-		newSessionKey := &models.SessionKey{
-			KeyID:      uuid.Must(uuid.NewV4()),
-			UserID:     uuid.Must(uuid.FromString(userId)),
-			ContentID:  uuid.Must(uuid.FromString(contentId)),
-			SessionKey: key,
-			CreatedAt:  time.Now(),
-			ExpiresAt:  time.Now().Add(24 * time.Hour),
-		}
-
-		err = s.sessionKeyRepo.Create(newSessionKey)
-		if err != nil {
-			return nil, err
-		}
-		return newSessionKey.SessionKey, nil
+	key, err := generateSessionKey()
+	if err != nil {
+		return nil, err
 	}
 
-	return sessionKey.SessionKey, nil
+	keyId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	newSessionKey := &models.SessionKey{
+		KeyID:      keyId,
+		UserID:     uuid.FromStringOrNil(userId),
+		ContentID:  uuid.FromStringOrNil(contentId),
+		SessionKey: key,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+	}
+
+	err = s.sessionKeyRepo.Create(newSessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSessionKey, nil
+}
+
+func (s *sessionKeyService) Verify(userId, contentId, keyId string) bool {
+	sessionKey, err := s.sessionKeyRepo.Get(userId, contentId)
+	if err != nil {
+		return false
+	}
+
+	return sessionKey.KeyID.String() == keyId && sessionKey.ExpiresAt.After(time.Now())
+}
+
+func (s *sessionKeyService) RecordPlay(userId, contentId string) error {
+	playId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	return s.playRepo.Create(&models.Play{
+		PlayID:    playId,
+		UserID:    uuid.FromStringOrNil(userId),
+		ContentID: uuid.FromStringOrNil(contentId),
+		CreatedAt: time.Now(),
+	})
 }
 
 func generateSessionKey() ([]byte, error) {
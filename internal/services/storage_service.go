@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+)
+
+type StorageService interface {
+	Reconcile(deleteOrphans bool) (*models.StorageReport, error)
+	ReconcileScheduled() error
+	CollectPending() error
+}
+
+type storageService struct {
+	objectRepo repositories.ObjectRepository
+	storage    storage.Storage
+	mode       storage.GCMode
+	// grace is how old an unreferenced object must be before it counts as orphaned, which covers uploads whose row
+	// is still being written.
+	grace time.Duration
+	clock clock.Clock
+}
+
+func NewStorageService(objectRepo repositories.ObjectRepository, storage storage.Storage, mode storage.GCMode,
+	grace time.Duration, clock clock.Clock) StorageService {
+	return &storageService{objectRepo: objectRepo, storage: storage, mode: mode, grace: grace, clock: clock}
+}
+
+// Reconcile compares every stored object with the rows that reference one. Unreferenced objects older than the grace
+// period are reported as orphans, and deleted when deleteOrphans is set. Rows whose object is missing are only
+// reported, since they usually need a person to decide what to do with the content.
+func (s *storageService) Reconcile(deleteOrphans bool) (*models.StorageReport, error) {
+	ctx := context.Background()
+	now := s.clock.Now()
+	cutoff := now.Add(-s.grace)
+
+	report := &models.StorageReport{
+		DryRun:      !deleteOrphans,
+		GracePeriod: s.grace.String(),
+		Orphans:     []models.OrphanObject{},
+		Missing:     []models.MissingObject{},
+		StartedAt:   now,
+	}
+
+	refs, err := s.objectRepo.GetReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		referenced[ref.FileID] = true
+	}
+
+	stored := make(map[string]bool)
+	err = s.storage.Walk(ctx, func(obj storage.ObjectInfo) error {
+		report.Scanned++
+		stored[obj.Key] = true
+
+		switch {
+		case referenced[obj.Key]:
+		case obj.LastModified.After(cutoff):
+			report.InGrace++
+		default:
+			report.Orphans = append(report.Orphans, models.OrphanObject{Key: obj.Key, Size: obj.Size,
+				LastModified: obj.LastModified})
+			report.OrphanBytes += obj.Size
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range refs {
+		if ref.Required && !stored[ref.FileID] {
+			report.Missing = append(report.Missing, models.MissingObject{Source: ref.Source, OwnerID: ref.OwnerID,
+				FileID: ref.FileID})
+		}
+	}
+
+	if deleteOrphans {
+		var deleted []string
+		for i := range report.Orphans {
+			orphan := &report.Orphans[i]
+			if err := s.storage.Delete(ctx, orphan.Key); err != nil {
+				log.Printf("failed to delete orphaned object %s: %v\n", orphan.Key, err)
+				continue
+			}
+			orphan.Deleted = true
+			deleted = append(deleted, orphan.Key)
+		}
+		report.Deleted = len(deleted)
+
+		if err := s.objectRepo.Release(deleted); err != nil {
+			return nil, err
+		}
+	}
+
+	report.FinishedAt = s.clock.Now()
+	return report, nil
+}
+
+// ReconcileScheduled runs Reconcile in the configured mode and logs a summary of the report.
+func (s *storageService) ReconcileScheduled() error {
+	if s.mode == storage.GCOff {
+		return nil
+	}
+
+	report, err := s.Reconcile(s.mode == storage.GCDelete)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("storage reconcile: scanned %d objects, %d orphaned (%d bytes), %d deleted, %d in grace, %d missing\n",
+		report.Scanned, len(report.Orphans), report.OrphanBytes, report.Deleted, report.InGrace, len(report.Missing))
+	for _, missing := range report.Missing {
+		log.Printf("storage reconcile: %s %s points at missing object %s\n", missing.Source, missing.OwnerID,
+			missing.FileID)
+	}
+
+	return nil
+}
+
+// CollectPending settles uploads tracked past the grace period without walking the whole bucket. Keys that were
+// referenced in the meantime are released; the rest are uploads whose row never landed, and are deleted in delete
+// mode.
+func (s *storageService) CollectPending() error {
+	if s.mode == storage.GCOff {
+		return nil
+	}
+
+	fileIds, err := s.objectRepo.GetPending(s.clock.Now().Add(-s.grace))
+	if err != nil || len(fileIds) == 0 {
+		return err
+	}
+
+	referenced, err := s.objectRepo.IsReferenced(fileIds)
+	if err != nil {
+		return err
+	}
+
+	var settled []string
+	abandoned := 0
+	for _, fileId := range fileIds {
+		switch {
+		case referenced[fileId]:
+		case s.mode == storage.GCDelete:
+			if err := s.storage.Delete(context.Background(), fileId); err != nil {
+				log.Printf("failed to delete abandoned upload %s: %v\n", fileId, err)
+				continue
+			}
+			log.Printf("deleted abandoned upload %s\n", fileId)
+		default:
+			abandoned++
+			continue
+		}
+		settled = append(settled, fileId)
+	}
+
+	if abandoned > 0 {
+		log.Printf("%d abandoned uploads left in place, storage collector is in report mode\n", abandoned)
+	}
+
+	return s.objectRepo.Release(settled)
+}
+
+// trackedStorage records every key as pending before writing it, so an upload whose row is never committed can be
+// found without listing the bucket.
+type trackedStorage struct {
+	storage.Storage
+	objectRepo repositories.ObjectRepository
+	clock      clock.Clock
+}
+
+func NewTrackedStorage(inner storage.Storage, objectRepo repositories.ObjectRepository, clock clock.Clock) storage.Storage {
+	return &trackedStorage{Storage: inner, objectRepo: objectRepo, clock: clock}
+}
+
+func (s *trackedStorage) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	if err := s.objectRepo.Track(key, s.clock.Now()); err != nil {
+		return err
+	}
+
+	return s.Storage.Put(ctx, key, reader, size, contentType)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/payment"
+	"github.com/gofrs/uuid"
+)
+
+type SubscriptionService interface {
+	CreatePlan(plan *models.Plan, isAdmin bool) error
+	ListPlans(creatorId string) ([]*models.Plan, error)
+	Subscribe(userId, planId string) (*models.Subscription, error)
+	Cancel(userId, subscriptionId string) (*models.Subscription, error)
+	ListByUser(userId string) ([]*models.Subscription, error)
+	RenewDue() error
+}
+
+var (
+	ErrPlanNotFound         = errors.New("plan not found")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrForbidden            = errors.New("forbidden")
+	ErrAlreadySubscribed    = errors.New("already subscribed to this plan")
+)
+
+type subscriptionService struct {
+	subscriptionRepo repositories.SubscriptionRepository
+	payments         payment.Provider
+	clock            clock.Clock
+}
+
+func NewSubscriptionService(subscriptionRepo repositories.SubscriptionRepository, payments payment.Provider,
+	clock clock.Clock) SubscriptionService {
+	return &subscriptionService{subscriptionRepo: subscriptionRepo, payments: payments, clock: clock}
+}
+
+func (s *subscriptionService) CreatePlan(plan *models.Plan, isAdmin bool) error {
+	if plan.Name == "" {
+		return errors.New("plan name cannot be empty")
+	}
+	if err := normalizeMoney(&plan.Price); err != nil {
+		return err
+	}
+	if plan.PeriodDays <= 0 {
+		return errors.New("plan period must be at least one day")
+	}
+	if plan.GraceDays < 0 {
+		return errors.New("plan grace period cannot be negative")
+	}
+
+	switch plan.Scope {
+	case models.PlanScopeCreator:
+		if plan.CreatorID == nil {
+			return errors.New("creator plan must have a creator")
+		}
+	case models.PlanScopePlatform:
+		if !isAdmin {
+			return ErrForbidden
+		}
+		plan.CreatorID = nil
+	default:
+		return errors.New("plan scope must be creator or platform")
+	}
+
+	planId, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	plan.PlanID = planId
+	plan.Active = true
+	plan.CreatedAt = s.clock.Now()
+
+	return s.subscriptionRepo.CreatePlan(plan)
+}
+
+func (s *subscriptionService) ListPlans(creatorId string) ([]*models.Plan, error) {
+	return s.subscriptionRepo.GetPlans(creatorId)
+}
+
+func (s *subscriptionService) Subscribe(userId, planId string) (*models.Subscription, error) {
+	plan, err := s.subscriptionRepo.GetPlan(planId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+
+	if !plan.Active {
+		return nil, ErrPlanNotFound
+	}
+
+	current, err := s.subscriptionRepo.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, subscription := range current {
+		if subscription.PlanID == plan.PlanID && isCurrent(subscription) {
+			return nil, ErrAlreadySubscribed
+		}
+	}
+
+	subscriptionId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	subscription := &models.Subscription{
+		SubscriptionID:     subscriptionId,
+		PlanID:             plan.PlanID,
+		UserID:             uuid.FromStringOrNil(userId),
+		Status:             models.SubscriptionActive,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.AddDate(0, 0, plan.PeriodDays),
+		CreatedAt:          now,
+	}
+
+	paymentId, err := s.charge(subscription, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.subscriptionRepo.Create(subscription); err != nil {
+		if paymentId != "" {
+			if refundErr := s.payments.Refund(paymentId, plan.Price); refundErr != nil {
+				log.Printf("failed to refund payment %s for subscription %s: %v\n", paymentId, subscriptionId, refundErr)
+			}
+		}
+		if errors.Is(err, repositories.ErrSubscriptionExists) {
+			return nil, ErrAlreadySubscribed
+		}
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// charge bills the plan's price for the subscription's current period. Free plans are not charged and return no
+// payment id.
+func (s *subscriptionService) charge(subscription *models.Subscription, plan *models.Plan) (string, error) {
+	if plan.Price.IsZero() {
+		return "", nil
+	}
+
+	reference := fmt.Sprintf("%s:%s", subscription.SubscriptionID, subscription.CurrentPeriodStart.Format(time.DateOnly))
+	return s.payments.Charge(reference, plan.Price)
+}
+
+func isCurrent(subscription *models.Subscription) bool {
+	return subscription.Status == models.SubscriptionActive || subscription.Status == models.SubscriptionPastDue
+}
+
+func (s *subscriptionService) Cancel(userId, subscriptionId string) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetById(subscriptionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+
+	if subscription.UserID.String() != userId || !isCurrent(subscription) {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	now := s.clock.Now()
+	subscription.CancelAtPeriodEnd = true
+	subscription.CanceledAt = &now
+
+	if err = s.subscriptionRepo.Update(subscription); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (s *subscriptionService) ListByUser(userId string) ([]*models.Subscription, error) {
+	return s.subscriptionRepo.GetByUser(userId)
+}
+
+func (s *subscriptionService) RenewDue() error {
+	now := s.clock.Now()
+
+	due, err := s.subscriptionRepo.GetDue(now)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range due {
+		if err := s.renew(subscription, now); err != nil {
+			log.Printf("failed to renew subscription %s: %v\n", subscription.SubscriptionID, err)
+		}
+	}
+
+	return nil
+}
+
+// renew charges for the period after the one that has ended. A failed charge leaves the subscription past due, still
+// entitled through the plan's grace period and retried on every run, and it expires once the grace period is over.
+func (s *subscriptionService) renew(subscription *models.Subscription, now time.Time) error {
+	plan, err := s.subscriptionRepo.GetPlan(subscription.PlanID.String())
+	if err != nil {
+		return err
+	}
+
+	var paymentId string
+	switch {
+	case subscription.CancelAtPeriodEnd:
+		subscription.Status = models.SubscriptionCanceled
+	case !plan.Active:
+		subscription.Status = models.SubscriptionCanceled
+		subscription.CanceledAt = &now
+	default:
+		next := *subscription
+		next.CurrentPeriodStart = subscription.CurrentPeriodEnd
+		next.CurrentPeriodEnd = subscription.CurrentPeriodEnd.AddDate(0, 0, plan.PeriodDays)
+
+		paymentId, err = s.charge(&next, plan)
+		if err != nil {
+			log.Printf("failed to charge subscription %s: %v\n", subscription.SubscriptionID, err)
+			if now.Before(subscription.CurrentPeriodEnd.AddDate(0, 0, plan.GraceDays)) {
+				subscription.Status = models.SubscriptionPastDue
+			} else {
+				subscription.Status = models.SubscriptionExpired
+			}
+			break
+		}
+
+		next.Status = models.SubscriptionActive
+		*subscription = next
+	}
+
+	if err = s.subscriptionRepo.Update(subscription); err != nil {
+		// The period was not advanced, so the next run would charge for it again.
+		if paymentId != "" {
+			if refundErr := s.payments.Refund(paymentId, plan.Price); refundErr != nil {
+				log.Printf("failed to refund payment %s for subscription %s: %v\n", paymentId,
+					subscription.SubscriptionID, refundErr)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/payment"
+	"github.com/gofrs/uuid"
+)
+
+type fakeSubscriptionRepo struct {
+	repositories.SubscriptionRepository
+	plans         map[uuid.UUID]*models.Plan
+	subscriptions map[uuid.UUID]*models.Subscription
+}
+
+func newFakeSubscriptionRepo() *fakeSubscriptionRepo {
+	return &fakeSubscriptionRepo{plans: map[uuid.UUID]*models.Plan{}, subscriptions: map[uuid.UUID]*models.Subscription{}}
+}
+
+func (r *fakeSubscriptionRepo) GetPlan(id string) (*models.Plan, error) {
+	plan, ok := r.plans[uuid.FromStringOrNil(id)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *plan
+	return &copied, nil
+}
+
+func (r *fakeSubscriptionRepo) Create(subscription *models.Subscription) error {
+	for _, existing := range r.subscriptions {
+		if existing.UserID == subscription.UserID && existing.PlanID == subscription.PlanID && isCurrent(existing) {
+			return repositories.ErrSubscriptionExists
+		}
+	}
+	copied := *subscription
+	r.subscriptions[subscription.SubscriptionID] = &copied
+	return nil
+}
+
+func (r *fakeSubscriptionRepo) GetById(id string) (*models.Subscription, error) {
+	subscription, ok := r.subscriptions[uuid.FromStringOrNil(id)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (r *fakeSubscriptionRepo) GetByUser(userId string) ([]*models.Subscription, error) {
+	var subscriptions []*models.Subscription
+	for _, subscription := range r.subscriptions {
+		if subscription.UserID.String() == userId {
+			copied := *subscription
+			subscriptions = append(subscriptions, &copied)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *fakeSubscriptionRepo) Update(subscription *models.Subscription) error {
+	if _, ok := r.subscriptions[subscription.SubscriptionID]; !ok {
+		return sql.ErrNoRows
+	}
+	copied := *subscription
+	r.subscriptions[subscription.SubscriptionID] = &copied
+	return nil
+}
+
+func (r *fakeSubscriptionRepo) GetDue(at time.Time) ([]*models.Subscription, error) {
+	var due []*models.Subscription
+	for _, subscription := range r.subscriptions {
+		if isCurrent(subscription) && !subscription.CurrentPeriodEnd.After(at) {
+			copied := *subscription
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+type fakePayments struct {
+	fail    bool
+	charges []string
+	refunds []string
+}
+
+func (p *fakePayments) Charge(reference string, amount money.Money) (string, error) {
+	if p.fail {
+		return "", payment.ErrPaymentFailed
+	}
+	p.charges = append(p.charges, reference)
+	return fmt.Sprintf("payment_%d", len(p.charges)), nil
+}
+
+func (p *fakePayments) Refund(paymentId string, amount money.Money) error {
+	p.refunds = append(p.refunds, paymentId)
+	return nil
+}
+
+var subscriptionStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestSubscriptionService(t *testing.T) (SubscriptionService, *fakeSubscriptionRepo, *fakePayments, *clock.Fake, *models.Plan) {
+	t.Helper()
+
+	repo := newFakeSubscriptionRepo()
+	plan := &models.Plan{
+		PlanID:     uuid.Must(uuid.NewV4()),
+		Name:       "monthly",
+		Scope:      models.PlanScopePlatform,
+		Price:      money.Money{Amount: 500, Currency: "USD"},
+		PeriodDays: 30,
+		GraceDays:  3,
+		Active:     true,
+	}
+	repo.plans[plan.PlanID] = plan
+
+	payments := &fakePayments{}
+	clk := clock.NewFake(subscriptionStart)
+
+	return NewSubscriptionService(repo, payments, clk), repo, payments, clk, plan
+}
+
+func stored(t *testing.T, repo *fakeSubscriptionRepo, id uuid.UUID) *models.Subscription {
+	t.Helper()
+
+	subscription, err := repo.GetById(id.String())
+	if err != nil {
+		t.Fatalf("subscription %s not stored: %v", id, err)
+	}
+	return subscription
+}
+
+func TestSubscribeChargesFirstPeriod(t *testing.T) {
+	service, repo, payments, _, plan := newTestSubscriptionService(t)
+	userId := uuid.Must(uuid.NewV4()).String()
+
+	subscription, err := service.Subscribe(userId, plan.PlanID.String())
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if len(payments.charges) != 1 {
+		t.Fatalf("charges = %d, want 1", len(payments.charges))
+	}
+	if want := subscriptionStart.AddDate(0, 0, plan.PeriodDays); !subscription.CurrentPeriodEnd.Equal(want) {
+		t.Errorf("period end = %v, want %v", subscription.CurrentPeriodEnd, want)
+	}
+	if got := stored(t, repo, subscription.SubscriptionID).Status; got != models.SubscriptionActive {
+		t.Errorf("status = %q, want %q", got, models.SubscriptionActive)
+	}
+}
+
+func TestSubscribeFailedChargeCreatesNothing(t *testing.T) {
+	service, repo, payments, _, plan := newTestSubscriptionService(t)
+	payments.fail = true
+
+	_, err := service.Subscribe(uuid.Must(uuid.NewV4()).String(), plan.PlanID.String())
+	if !errors.Is(err, payment.ErrPaymentFailed) {
+		t.Fatalf("err = %v, want %v", err, payment.ErrPaymentFailed)
+	}
+	if len(repo.subscriptions) != 0 {
+		t.Errorf("stored %d subscriptions, want 0", len(repo.subscriptions))
+	}
+}
+
+func TestSubscribeRejectsDuplicate(t *testing.T) {
+	service, _, payments, _, plan := newTestSubscriptionService(t)
+	userId := uuid.Must(uuid.NewV4()).String()
+
+	if _, err := service.Subscribe(userId, plan.PlanID.String()); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	_, err := service.Subscribe(userId, plan.PlanID.String())
+	if !errors.Is(err, ErrAlreadySubscribed) {
+		t.Fatalf("err = %v, want %v", err, ErrAlreadySubscribed)
+	}
+	if len(payments.charges) != 1 {
+		t.Errorf("charges = %d, want 1", len(payments.charges))
+	}
+}
+
+func TestRenewDueChargesOnePeriod(t *testing.T) {
+	service, repo, payments, clk, plan := newTestSubscriptionService(t)
+
+	subscription, err := service.Subscribe(uuid.Must(uuid.NewV4()).String(), plan.PlanID.String())
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	clk.Advance(31 * 24 * time.Hour)
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+
+	renewed := stored(t, repo, subscription.SubscriptionID)
+	if renewed.Status != models.SubscriptionActive {
+		t.Errorf("status = %q, want %q", renewed.Status, models.SubscriptionActive)
+	}
+	if !renewed.CurrentPeriodStart.Equal(subscription.CurrentPeriodEnd) {
+		t.Errorf("period start = %v, want %v", renewed.CurrentPeriodStart, subscription.CurrentPeriodEnd)
+	}
+	if want := subscription.CurrentPeriodEnd.AddDate(0, 0, plan.PeriodDays); !renewed.CurrentPeriodEnd.Equal(want) {
+		t.Errorf("period end = %v, want %v", renewed.CurrentPeriodEnd, want)
+	}
+	if len(payments.charges) != 2 {
+		t.Errorf("charges = %d, want 2", len(payments.charges))
+	}
+
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+	if len(payments.charges) != 2 {
+		t.Errorf("charges after second run = %d, want 2", len(payments.charges))
+	}
+}
+
+func TestRenewDueFailedChargeGraceThenExpiry(t *testing.T) {
+	service, repo, payments, clk, plan := newTestSubscriptionService(t)
+
+	subscription, err := service.Subscribe(uuid.Must(uuid.NewV4()).String(), plan.PlanID.String())
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	payments.fail = true
+	clk.Set(subscription.CurrentPeriodEnd.Add(time.Hour))
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+
+	pastDue := stored(t, repo, subscription.SubscriptionID)
+	if pastDue.Status != models.SubscriptionPastDue {
+		t.Fatalf("status = %q, want %q", pastDue.Status, models.SubscriptionPastDue)
+	}
+	if !pastDue.CurrentPeriodEnd.Equal(subscription.CurrentPeriodEnd) {
+		t.Errorf("period end moved to %v while past due", pastDue.CurrentPeriodEnd)
+	}
+
+	clk.Set(subscription.CurrentPeriodEnd.AddDate(0, 0, plan.GraceDays))
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+
+	if got := stored(t, repo, subscription.SubscriptionID).Status; got != models.SubscriptionExpired {
+		t.Errorf("status = %q, want %q", got, models.SubscriptionExpired)
+	}
+}
+
+func TestRenewDueRecoversWithinGrace(t *testing.T) {
+	service, repo, payments, clk, plan := newTestSubscriptionService(t)
+
+	subscription, err := service.Subscribe(uuid.Must(uuid.NewV4()).String(), plan.PlanID.String())
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	payments.fail = true
+	clk.Set(subscription.CurrentPeriodEnd.Add(time.Hour))
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+
+	payments.fail = false
+	clk.Advance(24 * time.Hour)
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+
+	renewed := stored(t, repo, subscription.SubscriptionID)
+	if renewed.Status != models.SubscriptionActive {
+		t.Errorf("status = %q, want %q", renewed.Status, models.SubscriptionActive)
+	}
+	if want := subscription.CurrentPeriodEnd.AddDate(0, 0, plan.PeriodDays); !renewed.CurrentPeriodEnd.Equal(want) {
+		t.Errorf("period end = %v, want %v", renewed.CurrentPeriodEnd, want)
+	}
+}
+
+func TestRenewDueCancelsAtPeriodEnd(t *testing.T) {
+	service, repo, payments, clk, plan := newTestSubscriptionService(t)
+	userId := uuid.Must(uuid.NewV4()).String()
+
+	subscription, err := service.Subscribe(userId, plan.PlanID.String())
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err = service.Cancel(userId, subscription.SubscriptionID.String()); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	clk.Set(subscription.CurrentPeriodEnd)
+	if err = service.RenewDue(); err != nil {
+		t.Fatalf("RenewDue: %v", err)
+	}
+
+	if got := stored(t, repo, subscription.SubscriptionID).Status; got != models.SubscriptionCanceled {
+		t.Errorf("status = %q, want %q", got, models.SubscriptionCanceled)
+	}
+	if len(payments.charges) != 1 {
+		t.Errorf("charges = %d, want 1", len(payments.charges))
+	}
+}
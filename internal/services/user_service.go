@@ -29,6 +29,7 @@ func (s *userService) Register(user *models.User) error {
 		return err
 	}
 	user.UserID = userId
+	user.Role = models.RoleUser
 
 	hashedPassword, err := hashPassword(user.Password)
 	if err != nil {
@@ -49,7 +50,7 @@ func (s *userService) Authenticate(email, password string) (string, error) {
 		return "", errors.New("invalid credentials")
 	}
 
-	return auth.GenerateJWT(user.UserID.String())
+	return auth.GenerateJWT(user.UserID.String(), user.Role)
 }
 
 func hashPassword(password string) (string, error) {
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/models"
+	"github.com/AaravShirvoikar/is-project-drm-backend/internal/repositories"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/storage"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/watermark"
+	"github.com/gofrs/uuid"
+)
+
+type WatermarkService interface {
+	PrepareMissing() error
+	Open(content *models.Content, userId string) (io.ReadSeekCloser, bool, error)
+	Payload(content *models.Content, userId string) (uint64, error)
+	Identify(contentId, leakedPath string) (*models.WatermarkMatch, error)
+}
+
+var ErrWatermarkNotFound = errors.New("no watermark could be matched")
+
+const (
+	watermarkBatchSize = 10
+	minMatchConfidence = 0.75
+)
+
+type watermarkService struct {
+	watermarkRepo repositories.WatermarkRepository
+	contentRepo   repositories.ContentRepository
+	licenseRepo   repositories.LicenseRepository
+	storage       storage.Storage
+	clock         clock.Clock
+}
+
+func NewWatermarkService(watermarkRepo repositories.WatermarkRepository, contentRepo repositories.ContentRepository,
+	licenseRepo repositories.LicenseRepository, storage storage.Storage, clock clock.Clock) WatermarkService {
+	return &watermarkService{watermarkRepo: watermarkRepo, contentRepo: contentRepo, licenseRepo: licenseRepo,
+		storage: storage, clock: clock}
+}
+
+// PrepareMissing renders A/B segment variants for content whose current file has none yet.
+// Without ffmpeg nothing is rendered and content keeps being served unwatermarked.
+func (s *watermarkService) PrepareMissing() error {
+	if !watermark.Available() {
+		return nil
+	}
+
+	contents, err := s.watermarkRepo.GetPending(watermarkBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		if err := s.prepare(content); err != nil {
+			log.Printf("failed to watermark content %s: %v\n", content.ContentID, err)
+			if err := s.watermarkRepo.RecordFailure(content, err.Error()); err != nil {
+				log.Printf("failed to record failure for content %s: %v\n", content.ContentID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *watermarkService) prepare(content *models.Content) error {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "watermark-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "source"+filepath.Ext(content.FileID))
+	if err := s.download(ctx, content.FileID, src); err != nil {
+		return err
+	}
+
+	variants := make(map[string][]string, 2)
+	for _, variant := range []string{models.VariantA, models.VariantB} {
+		out := filepath.Join(dir, variant)
+		if err := os.Mkdir(out, 0o700); err != nil {
+			return err
+		}
+
+		variants[variant], err = watermark.Segment(ctx, src, out, variant == models.VariantB)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(variants[models.VariantA]) == 0 || len(variants[models.VariantA]) != len(variants[models.VariantB]) {
+		return fmt.Errorf("variant segment counts differ: %d and %d",
+			len(variants[models.VariantA]), len(variants[models.VariantB]))
+	}
+
+	var segments []*models.WatermarkSegment
+	for variant, paths := range variants {
+		for i, path := range paths {
+			fileId, size, err := s.upload(ctx, path)
+			if err != nil {
+				return err
+			}
+
+			segments = append(segments, &models.WatermarkSegment{
+				ContentID:    content.ContentID,
+				SourceFileID: content.FileID,
+				Index:        i,
+				Variant:      variant,
+				FileID:       fileId,
+				FileSize:     size,
+			})
+		}
+	}
+
+	stale, err := s.watermarkRepo.SetSegments(content.ContentID.String(), content.FileID, segments)
+	if err != nil {
+		return err
+	}
+
+	for _, fileId := range stale {
+		if err := s.storage.Delete(ctx, fileId); err != nil {
+			log.Printf("failed to delete watermark segment %s: %v\n", fileId, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *watermarkService) download(ctx context.Context, fileId, path string) error {
+	file, err := s.storage.Get(ctx, fileId)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, file); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func (s *watermarkService) upload(ctx context.Context, path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+
+	fileId, err := storage.Upload(ctx, s.storage, file, ".ts", info.Size(), "video/mp2t")
+	if err != nil {
+		return "", 0, err
+	}
+
+	return fileId, info.Size(), nil
+}
+
+func (s *watermarkService) readSegments(ctx context.Context, content *models.Content) ([][]byte, [][]byte, error) {
+	segments, err := s.watermarkRepo.GetSegments(content.ContentID.String(), content.FileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var a, b [][]byte
+	for _, segment := range segments {
+		file, err := s.storage.Get(ctx, segment.FileID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if segment.Variant == models.VariantA {
+			a = append(a, data)
+		} else {
+			b = append(b, data)
+		}
+	}
+
+	if len(a) != len(b) {
+		return nil, nil, fmt.Errorf("incomplete watermark variants for content %s", content.ContentID)
+	}
+
+	return a, b, nil
+}
+
+// Open assembles the stream for a viewer by picking the A or B variant of each segment from the bits of their payload.
+// Segments are read from storage as the stream is read rather than up front. It reports false when the content has
+// not been prepared for watermarking yet.
+func (s *watermarkService) Open(content *models.Content, userId string) (io.ReadSeekCloser, bool, error) {
+	segments, err := s.watermarkRepo.GetSegments(content.ContentID.String(), content.FileID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(segments) == 0 {
+		return nil, false, nil
+	}
+
+	var a, b []*models.WatermarkSegment
+	for _, segment := range segments {
+		if segment.Variant == models.VariantA {
+			a = append(a, segment)
+		} else {
+			b = append(b, segment)
+		}
+	}
+
+	if len(a) != len(b) {
+		return nil, false, fmt.Errorf("incomplete watermark variants for content %s", content.ContentID)
+	}
+
+	mark, err := s.mark(content, userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	parts := make([]storage.Part, len(a))
+	for i := range a {
+		segment := a[i]
+		if watermark.Bit(mark.Payload, i) == 1 {
+			segment = b[i]
+		}
+		parts[i] = storage.Part{Key: segment.FileID, Size: segment.FileSize}
+	}
+
+	return storage.NewReader(context.Background(), s.storage, parts), true, nil
+}
+
+// Payload returns the watermark payload of a viewer, for streams that select variants themselves.
+func (s *watermarkService) Payload(content *models.Content, userId string) (uint64, error) {
+	mark, err := s.mark(content, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return mark.Payload, nil
+}
+
+// mark returns the viewer's watermark, creating it on their first stream. The payload is derived from the license the
+// content is streamed under, so every stream under one license carries the same mark. Viewers entitled through a
+// subscription have no license and get a payload derived from their user and the content instead.
+func (s *watermarkService) mark(content *models.Content, userId string) (*models.Watermark, error) {
+	mark := &models.Watermark{
+		ContentID: content.ContentID,
+		UserID:    uuid.FromStringOrNil(userId),
+		CreatedAt: s.clock.Now(),
+	}
+
+	license, err := s.licenseRepo.Get(userId, content.ContentID.String())
+	if err == nil {
+		mark.LicenseID = &license.LicenseID
+		mark.Payload = foldUUID(license.LicenseID)
+	} else if errors.Is(err, sql.ErrNoRows) {
+		mark.Payload = foldUUID(mark.UserID) ^ foldUUID(content.ContentID)
+	} else {
+		return nil, err
+	}
+
+	return s.watermarkRepo.CreateMark(mark)
+}
+
+// foldUUID folds the 128 bits of an id into a 64-bit payload.
+func foldUUID(id uuid.UUID) uint64 {
+	return binary.BigEndian.Uint64(id[:8]) ^ binary.BigEndian.Uint64(id[8:])
+}
+
+// Identify reads the payload back from a leaked file and returns the viewer it was served to. Byte-identical copies
+// are matched segment by segment; anything re-encoded falls back to comparing per-segment brightness. A copy is not
+// attributed when too little of the payload could be read or when another viewer's mark fits it nearly as well.
+func (s *watermarkService) Identify(contentId, leakedPath string) (*models.WatermarkMatch, error) {
+	ctx := context.Background()
+
+	content, err := s.contentRepo.GetById(contentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContentNotFound
+		}
+		return nil, err
+	}
+
+	a, b, err := s.readSegments(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 0 {
+		return nil, ErrWatermarkNotFound
+	}
+
+	leaked, err := os.ReadFile(leakedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	method := "exact"
+	segmentBits := watermark.ExtractExact(leaked, a, b)
+	if len(segmentBits) == 0 {
+		method = "luma"
+		segmentBits, err = s.extractLuma(ctx, leakedPath, a, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recovered := watermark.Decode(segmentBits)
+	if recovered.KnownBits() < watermark.MinKnownBits {
+		return nil, ErrWatermarkNotFound
+	}
+
+	marks, err := s.watermarkRepo.GetMarks(contentId)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([]uint64, len(marks))
+	for i, mark := range marks {
+		payloads[i] = mark.Payload
+	}
+
+	best, confidence, ok := recovered.Match(payloads)
+	if !ok || confidence < minMatchConfidence {
+		return nil, ErrWatermarkNotFound
+	}
+
+	match := &models.WatermarkMatch{
+		Watermark:  marks[best],
+		Method:     method,
+		Segments:   recovered.Segments,
+		KnownBits:  recovered.KnownBits(),
+		Confidence: confidence,
+	}
+
+	return match, nil
+}
+
+func (s *watermarkService) extractLuma(ctx context.Context, leakedPath string, a, b [][]byte) ([]int, error) {
+	dir, err := os.MkdirTemp("", "watermark-extract-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	references := make([][]float64, 2)
+	for i, variant := range [][][]byte{a, b} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.ts", i))
+		if err := os.WriteFile(path, bytes.Join(variant, nil), 0o600); err != nil {
+			return nil, err
+		}
+
+		references[i], err = watermark.SegmentLuma(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	leaked, err := watermark.SegmentLuma(ctx, leakedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return watermark.ExtractLuma(leaked, references[0], references[1]), nil
+}
@@ -0,0 +1,3 @@
+ALTER TABLE content
+ADD COLUMN deleted_at TIMESTAMP,
+ADD COLUMN purged_at TIMESTAMP;
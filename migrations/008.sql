@@ -0,0 +1,11 @@
+ALTER TABLE content
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX content_search_vector_idx ON content USING GIN (search_vector);
+CREATE INDEX content_creator_id_idx ON content (creator_id);
+CREATE INDEX content_created_at_idx ON content (created_at, id);
+CREATE INDEX content_price_idx ON content (price, id);
+CREATE INDEX content_title_idx ON content (title, id);
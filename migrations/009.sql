@@ -0,0 +1 @@
+CREATE INDEX licenses_user_content_idx ON licenses (user_id, content_id, expires_at);
@@ -0,0 +1,58 @@
+CREATE TABLE categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL
+);
+
+INSERT INTO categories (slug, name) VALUES
+    ('education', 'Education'),
+    ('entertainment', 'Entertainment'),
+    ('music', 'Music'),
+    ('gaming', 'Gaming'),
+    ('sports', 'Sports'),
+    ('technology', 'Technology');
+
+CREATE TABLE content_categories (
+    content_id UUID NOT NULL,
+    category_id UUID NOT NULL,
+    PRIMARY KEY (content_id, category_id),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE content_tags (
+    content_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+    PRIMARY KEY (content_id, tag_id),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX content_tags_tag_id_idx ON content_tags (tag_id);
+CREATE INDEX content_categories_category_id_idx ON content_categories (category_id);
+
+CREATE TABLE collections (
+    id UUID PRIMARY KEY,
+    creator_id UUID NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE collection_items (
+    collection_id UUID NOT NULL,
+    content_id UUID NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (collection_id, content_id),
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+CREATE INDEX collection_items_content_id_idx ON collection_items (content_id);
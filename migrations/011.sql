@@ -0,0 +1,16 @@
+ALTER TABLE collections
+ADD COLUMN price DECIMAL(10, 2),
+ADD COLUMN grant_new_items BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE collection_purchases (
+    id UUID PRIMARY KEY,
+    collection_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX collection_purchases_collection_id_idx ON collection_purchases (collection_id, expires_at);
@@ -0,0 +1,34 @@
+ALTER TABLE users
+ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+CREATE TABLE plans (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(16) NOT NULL,
+    creator_id UUID,
+    price DECIMAL(10, 2) NOT NULL,
+    period_days INT NOT NULL,
+    grace_days INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (scope IN ('creator', 'platform')),
+    CHECK ((scope = 'creator') = (creator_id IS NOT NULL))
+);
+
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    plan_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    canceled_at TIMESTAMP,
+    created_at TIMESTAMP,
+    FOREIGN KEY (plan_id) REFERENCES plans(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX subscriptions_user_id_idx ON subscriptions (user_id, status);
+CREATE INDEX subscriptions_period_end_idx ON subscriptions (status, current_period_end);
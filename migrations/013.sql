@@ -0,0 +1,16 @@
+ALTER TABLE content
+ADD COLUMN transferable BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE gift_codes (
+    id UUID PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    content_id UUID NOT NULL,
+    purchaser_id UUID NOT NULL,
+    redeemed_by UUID,
+    redeemed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE,
+    FOREIGN KEY (purchaser_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (redeemed_by) REFERENCES users(id) ON DELETE SET NULL
+);
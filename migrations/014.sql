@@ -0,0 +1,49 @@
+CREATE TABLE coupons (
+    id UUID PRIMARY KEY,
+    code VARCHAR(64) NOT NULL UNIQUE,
+    created_by UUID NOT NULL,
+    creator_id UUID,
+    content_id UUID,
+    kind VARCHAR(16) NOT NULL,
+    value DECIMAL(10, 2) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    max_uses INT,
+    per_user_limit INT,
+    uses INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE,
+    CHECK (kind IN ('percent', 'fixed'))
+);
+
+CREATE TABLE content_sales (
+    id UUID PRIMARY KEY,
+    content_id UUID NOT NULL,
+    sale_price DECIMAL(10, 2) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+CREATE INDEX content_sales_content_id_idx ON content_sales (content_id, starts_at, ends_at);
+
+CREATE TABLE orders (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    content_id UUID,
+    collection_id UUID,
+    list_price DECIMAL(10, 2) NOT NULL,
+    discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    price DECIMAL(10, 2) NOT NULL,
+    coupon_id UUID,
+    created_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE SET NULL,
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE SET NULL,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE SET NULL
+);
+
+CREATE INDEX orders_user_coupon_idx ON orders (user_id, coupon_id);
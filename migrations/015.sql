@@ -0,0 +1,84 @@
+ALTER TABLE content
+ADD COLUMN price_amount BIGINT,
+ADD COLUMN price_currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE content SET price_amount = ROUND(price * 100);
+
+ALTER TABLE content
+ALTER COLUMN price_amount SET NOT NULL;
+
+DROP INDEX content_price_idx;
+ALTER TABLE content DROP COLUMN price;
+CREATE INDEX content_price_idx ON content (price_amount, id);
+
+CREATE TABLE content_prices (
+    content_id UUID NOT NULL,
+    currency CHAR(3) NOT NULL,
+    amount BIGINT NOT NULL,
+    PRIMARY KEY (content_id, currency),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+ALTER TABLE collections
+ADD COLUMN price_amount BIGINT,
+ADD COLUMN price_currency CHAR(3);
+
+UPDATE collections SET price_amount = ROUND(price * 100), price_currency = 'USD' WHERE price IS NOT NULL;
+
+ALTER TABLE collections DROP COLUMN price;
+
+ALTER TABLE collection_purchases
+ADD COLUMN amount BIGINT,
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE collection_purchases SET amount = ROUND(price * 100);
+
+ALTER TABLE collection_purchases
+ALTER COLUMN amount SET NOT NULL,
+DROP COLUMN price;
+
+ALTER TABLE plans
+ADD COLUMN price_amount BIGINT,
+ADD COLUMN price_currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE plans SET price_amount = ROUND(price * 100);
+
+ALTER TABLE plans
+ALTER COLUMN price_amount SET NOT NULL,
+DROP COLUMN price;
+
+ALTER TABLE coupons
+ADD COLUMN percent INT,
+ADD COLUMN amount BIGINT,
+ADD COLUMN currency CHAR(3);
+
+UPDATE coupons SET percent = ROUND(value) WHERE kind = 'percent';
+UPDATE coupons SET amount = ROUND(value * 100), currency = 'USD' WHERE kind = 'fixed';
+
+ALTER TABLE coupons DROP COLUMN value;
+
+ALTER TABLE content_sales
+ADD COLUMN amount BIGINT,
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE content_sales SET amount = ROUND(sale_price * 100);
+
+ALTER TABLE content_sales
+ALTER COLUMN amount SET NOT NULL,
+DROP COLUMN sale_price;
+
+ALTER TABLE orders
+ADD COLUMN list_amount BIGINT,
+ADD COLUMN discount_amount BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN amount BIGINT,
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE orders SET list_amount = ROUND(list_price * 100), discount_amount = ROUND(discount * 100),
+    amount = ROUND(price * 100);
+
+ALTER TABLE orders
+ALTER COLUMN list_amount SET NOT NULL,
+ALTER COLUMN amount SET NOT NULL,
+DROP COLUMN list_price,
+DROP COLUMN discount,
+DROP COLUMN price;
@@ -0,0 +1,37 @@
+ALTER TABLE orders ADD COLUMN creator_id UUID REFERENCES users(id);
+
+UPDATE orders o SET creator_id = c.creator_id FROM content c WHERE c.id = o.content_id;
+UPDATE orders o SET creator_id = c.creator_id FROM collections c WHERE c.id = o.collection_id;
+
+-- Orders whose content or collection was deleted before this have nothing left to backfill from, so the column stays
+-- nullable. Earnings come from the ledger, which starts here, so those orders never count towards a creator.
+
+CREATE TABLE payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    creator_id UUID NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX payouts_creator_idx ON payouts (creator_id, created_at);
+
+CREATE TABLE ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('sale', 'refund', 'payout')),
+    account VARCHAR(16) NOT NULL CHECK (account IN ('cash', 'creator', 'platform', 'tax')),
+    creator_id UUID,
+    order_id UUID,
+    payout_id UUID,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (creator_id) REFERENCES users(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (payout_id) REFERENCES payouts(id)
+);
+
+CREATE INDEX ledger_entries_transaction_idx ON ledger_entries (transaction_id);
+CREATE INDEX ledger_entries_creator_idx ON ledger_entries (creator_id, created_at) WHERE account = 'creator';
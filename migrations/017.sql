@@ -0,0 +1,24 @@
+ALTER TABLE orders
+ADD COLUMN payment_id VARCHAR(255),
+ADD COLUMN refunded_at TIMESTAMP;
+
+CREATE INDEX orders_user_idx ON orders (user_id, created_at);
+
+CREATE TABLE refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    creator_id UUID NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'refunded', 'rejected')),
+    decided_by UUID,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (decided_by) REFERENCES users(id)
+);
+
+CREATE UNIQUE INDEX refunds_open_order_idx ON refunds (order_id) WHERE status <> 'rejected';
+CREATE INDEX refunds_creator_pending_idx ON refunds (creator_id, created_at) WHERE status = 'pending';
@@ -0,0 +1,21 @@
+CREATE TABLE watermark_segments (
+    content_id UUID NOT NULL,
+    source_file_id VARCHAR(255) NOT NULL,
+    idx INT NOT NULL,
+    variant CHAR(1) NOT NULL CHECK (variant IN ('A', 'B')),
+    file_id VARCHAR(255) NOT NULL,
+    file_size BIGINT NOT NULL,
+    PRIMARY KEY (content_id, idx, variant),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+CREATE TABLE watermarks (
+    payload BIGINT PRIMARY KEY,
+    content_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    license_id UUID,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (content_id, user_id),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,21 @@
+CREATE TABLE renditions (
+    content_id UUID PRIMARY KEY,
+    source_file_id VARCHAR(255) NOT NULL,
+    format VARCHAR(16) NOT NULL CHECK (format IN ('hls')),
+    method VARCHAR(16) NOT NULL CHECK (method IN ('AES-128')),
+    encryption_key BYTEA NOT NULL,
+    init_file_id VARCHAR(255),
+    target_duration INT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+CREATE TABLE rendition_segments (
+    content_id UUID NOT NULL,
+    idx INT NOT NULL,
+    file_id VARCHAR(255) NOT NULL,
+    duration DOUBLE PRECISION NOT NULL,
+    file_size BIGINT NOT NULL,
+    PRIMARY KEY (content_id, idx),
+    FOREIGN KEY (content_id) REFERENCES renditions(content_id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE content ADD COLUMN mime_type VARCHAR(100) NOT NULL DEFAULT 'video/mp4';
@@ -0,0 +1,11 @@
+CREATE TABLE content_previews (
+    content_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('thumbnail', 'trailer')),
+    file_id VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    file_size BIGINT NOT NULL,
+    source_file_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (content_id, kind),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
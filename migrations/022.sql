@@ -0,0 +1,4 @@
+ALTER TABLE content ADD COLUMN similarity_status VARCHAR(20) NOT NULL DEFAULT 'passed'
+    CHECK (similarity_status IN ('passed', 'unchecked', 'flagged', 'review'));
+
+CREATE INDEX idx_content_similarity_unchecked ON content (created_at) WHERE similarity_status IN ('unchecked', 'flagged');
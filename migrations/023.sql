@@ -0,0 +1,37 @@
+ALTER TABLE content DROP CONSTRAINT content_similarity_status_check;
+ALTER TABLE content ADD CONSTRAINT content_similarity_status_check
+    CHECK (similarity_status IN ('passed', 'unchecked', 'flagged', 'review', 'rejected'));
+
+CREATE TABLE similarity_checks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    content_id UUID NOT NULL,
+    file_id VARCHAR(255),
+    matched_id VARCHAR(255),
+    score DOUBLE PRECISION NOT NULL,
+    similar BOOLEAN NOT NULL,
+    model_version VARCHAR(100) NOT NULL DEFAULT '',
+    threshold DOUBLE PRECISION,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+CREATE INDEX similarity_checks_content_idx ON similarity_checks (content_id, created_at);
+
+CREATE TABLE similarity_disputes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    content_id UUID NOT NULL,
+    check_id UUID,
+    creator_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('open', 'approved', 'denied')),
+    note TEXT NOT NULL DEFAULT '',
+    decided_by UUID,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE,
+    FOREIGN KEY (check_id) REFERENCES similarity_checks(id) ON DELETE SET NULL,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (decided_by) REFERENCES users(id)
+);
+
+CREATE UNIQUE INDEX similarity_disputes_open_idx ON similarity_disputes (content_id) WHERE status = 'open';
@@ -0,0 +1,4 @@
+ALTER TABLE categories ADD COLUMN similarity_threshold DOUBLE PRECISION
+    CHECK (similarity_threshold > 0 AND similarity_threshold <= 1);
+
+ALTER TABLE similarity_checks ADD COLUMN exempt BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,19 @@
+-- Fingerprints for the in-process similarity backend. Each 64 bit hash is also split into four 16 bit bands: two
+-- hashes at most 3 bits apart share at least one band exactly, so lookups only compare hashes that match an
+-- indexed band.
+CREATE TABLE fingerprints (
+    content_id UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('visual', 'audio')),
+    position INTEGER NOT NULL,
+    hash BIGINT NOT NULL,
+    band0 INTEGER GENERATED ALWAYS AS (((hash >> 48) & 65535)::INTEGER) STORED,
+    band1 INTEGER GENERATED ALWAYS AS (((hash >> 32) & 65535)::INTEGER) STORED,
+    band2 INTEGER GENERATED ALWAYS AS (((hash >> 16) & 65535)::INTEGER) STORED,
+    band3 INTEGER GENERATED ALWAYS AS ((hash & 65535)::INTEGER) STORED,
+    PRIMARY KEY (content_id, kind, position)
+);
+
+CREATE INDEX idx_fingerprints_band0 ON fingerprints (kind, band0);
+CREATE INDEX idx_fingerprints_band1 ON fingerprints (kind, band1);
+CREATE INDEX idx_fingerprints_band2 ON fingerprints (kind, band2);
+CREATE INDEX idx_fingerprints_band3 ON fingerprints (kind, band3);
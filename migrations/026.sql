@@ -0,0 +1,7 @@
+-- sha256 is NULL for content uploaded before checksums were recorded until the scrubber first hashes it.
+ALTER TABLE content ADD COLUMN sha256 CHAR(64);
+ALTER TABLE content ADD COLUMN corrupt BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE content ADD COLUMN integrity_checked_at TIMESTAMP;
+
+CREATE INDEX idx_content_sha256 ON content (sha256) WHERE deleted_at IS NULL;
+CREATE INDEX idx_content_integrity_checked_at ON content (integrity_checked_at NULLS FIRST) WHERE deleted_at IS NULL;
@@ -0,0 +1,8 @@
+-- Every object key is recorded here before it is written to storage. Rows are cleared once the key is referenced by
+-- the table that owns it, or once the storage collector removes an upload that never got one.
+CREATE TABLE pending_objects (
+    file_id VARCHAR(255) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_pending_objects_created_at ON pending_objects (created_at);
@@ -0,0 +1,16 @@
+-- A NULL limit means unlimited. Admins are never limited, whatever tier they are on.
+CREATE TABLE storage_tiers (
+    name VARCHAR(32) PRIMARY KEY,
+    max_bytes BIGINT CHECK (max_bytes >= 0),
+    max_items INT CHECK (max_items >= 0),
+    max_file_size BIGINT CHECK (max_file_size > 0)
+);
+
+INSERT INTO storage_tiers (name, max_bytes, max_items, max_file_size) VALUES
+    ('free', 1073741824, 50, 104857600),
+    ('pro', 53687091200, 1000, 2147483648),
+    ('unlimited', NULL, NULL, NULL);
+
+ALTER TABLE users ADD COLUMN storage_tier VARCHAR(32) NOT NULL DEFAULT 'free' REFERENCES storage_tiers(name);
+
+CREATE INDEX idx_content_creator_live ON content (creator_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Orders are written as pending before the customer is charged and only become paid once everything they deliver
+-- has been recorded. Existing orders were all charged when they were written.
+ALTER TABLE orders ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'paid'
+    CHECK (status IN ('pending', 'paid', 'failed'));
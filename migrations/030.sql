@@ -0,0 +1,18 @@
+-- Licenses remember the order that granted them, so a refund revokes exactly what it paid for. A redeemed gift's
+-- license carries the order that bought the gift, and a transferred license keeps its original order. Licenses
+-- granted outside an order have a NULL one.
+ALTER TABLE licenses ADD COLUMN order_id UUID REFERENCES orders(id) ON DELETE SET NULL;
+
+CREATE INDEX licenses_order_idx ON licenses (order_id) WHERE order_id IS NOT NULL;
+
+-- Existing purchases wrote their licenses straight after the order, so match them up by buyer, content and time.
+UPDATE licenses l SET order_id = o.id
+FROM orders o
+WHERE l.order_id IS NULL AND l.user_id = o.user_id AND l.content_id = o.content_id
+    AND l.created_at BETWEEN o.created_at AND o.created_at + INTERVAL '1 minute';
+
+UPDATE licenses l SET order_id = o.id
+FROM orders o
+JOIN collection_items ci ON ci.collection_id = o.collection_id
+WHERE l.order_id IS NULL AND l.user_id = o.user_id AND l.content_id = ci.content_id
+    AND l.created_at BETWEEN o.created_at AND o.created_at + INTERVAL '1 minute';
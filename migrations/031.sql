@@ -0,0 +1,5 @@
+-- Gift codes are paid for through an order like any other purchase, and can only be redeemed while that order is paid
+-- and not refunded. Codes issued before this had no payment behind them and can no longer be redeemed.
+ALTER TABLE gift_codes ADD COLUMN order_id UUID REFERENCES orders(id);
+
+CREATE INDEX gift_codes_order_idx ON gift_codes (order_id);
@@ -0,0 +1,15 @@
+-- Every stream, download or HLS session is recorded as a play, so a refund can tell whether the content was used.
+CREATE TABLE plays (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    content_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
+
+CREATE INDEX plays_user_content_idx ON plays (user_id, content_id, created_at);
+
+-- Session keys were the only record of playback until now.
+INSERT INTO plays (id, user_id, content_id, created_at)
+SELECT id, user_id, content_id, created_at FROM session_keys WHERE created_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- A renewal whose charge fails leaves the subscription past due for the plan's grace period, after which it expires.
+-- A user can hold only one current subscription to a plan.
+CREATE UNIQUE INDEX subscriptions_user_plan_idx ON subscriptions (user_id, plan_id)
+    WHERE status IN ('active', 'past_due');
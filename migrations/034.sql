@@ -0,0 +1,6 @@
+-- Watermarks are derived from the license a stream is served under, so a viewer gets one mark per license rather
+-- than one per content item. Marks handed out before this keep their random payloads and can still be identified.
+ALTER TABLE watermarks DROP CONSTRAINT watermarks_content_id_user_id_key;
+
+CREATE INDEX watermarks_content_idx ON watermarks (content_id);
+CREATE UNIQUE INDEX watermarks_license_idx ON watermarks (license_id) WHERE license_id IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Background jobs that fail on a content file back off instead of retrying it on every run. A row applies to the
+-- file it failed on; replacing the file starts the job over.
+CREATE TABLE processing_failures (
+    content_id UUID NOT NULL,
+    job VARCHAR(16) NOT NULL CHECK (job IN ('watermark', 'rendition', 'preview')),
+    source_file_id VARCHAR(255) NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT NOT NULL,
+    failed_at TIMESTAMP NOT NULL,
+    retry_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (content_id, job),
+    FOREIGN KEY (content_id) REFERENCES content(id) ON DELETE CASCADE
+);
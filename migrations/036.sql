@@ -0,0 +1,7 @@
+-- Renditions packaged from the stored file carry an A and a B variant of every segment so each viewer can be served
+-- their watermark. Imported renditions have only the A variant and are not served to viewers.
+ALTER TABLE renditions ADD COLUMN watermarked BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE rendition_segments ADD COLUMN variant CHAR(1) NOT NULL DEFAULT 'A' CHECK (variant IN ('A', 'B'));
+ALTER TABLE rendition_segments DROP CONSTRAINT rendition_segments_pkey;
+ALTER TABLE rendition_segments ADD PRIMARY KEY (content_id, idx, variant);
@@ -0,0 +1,5 @@
+-- A refund is claimed before its payment goes back to the buyer and only marked refunded, together with the revoked
+-- licenses and reversing ledger entries, once the payment provider has returned the money.
+ALTER TABLE refunds DROP CONSTRAINT refunds_status_check;
+ALTER TABLE refunds ADD CONSTRAINT refunds_status_check
+    CHECK (status IN ('pending', 'refunding', 'refunded', 'rejected'));
@@ -11,6 +11,7 @@ var jwtSecret []byte
 
 type Claims struct {
 	UserID string `json:"id"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -23,10 +24,11 @@ func Init(secret string) error {
 	return nil
 }
 
-func GenerateJWT(userID string) (string, error) {
+func GenerateJWT(userID, role string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
 		UserID: userID,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
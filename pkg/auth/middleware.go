@@ -28,8 +28,25 @@ func AuthenticateToken(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), "id", claims.UserID)
+		ctx = context.WithValue(ctx, "role", claims.Role)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func IsAdmin(r *http.Request) bool {
+	role, _ := r.Context().Value("role").(string)
+	return role == "admin"
+}
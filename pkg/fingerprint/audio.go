@@ -0,0 +1,104 @@
+package fingerprint
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// SampleRate is the mono rate audio is resampled to. Everything the fingerprint looks at sits below 2 kHz.
+	SampleRate = 5512
+	frameSize  = 2048
+	frameHop   = frameSize / 2
+	minFreq    = 300
+	maxFreq    = 2000
+	bands      = 65
+)
+
+var (
+	window = func() []float64 {
+		w := make([]float64, frameSize)
+		for i := range w {
+			w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/(frameSize-1))
+		}
+		return w
+	}()
+
+	// bandEdges splits minFreq..maxFreq into log-spaced bands, as FFT bin indexes.
+	bandEdges = func() [bands + 1]int {
+		var edges [bands + 1]int
+		for i := range edges {
+			freq := minFreq * math.Pow(maxFreq/minFreq, float64(i)/bands)
+			edges[i] = int(freq * frameSize / SampleRate)
+		}
+		return edges
+	}()
+)
+
+// Audio fingerprints mono samples in the range [-1, 1], one hash per overlapping frame of about a third of a second.
+// Each bit records whether the energy difference between two adjacent bands rose or fell since the previous frame,
+// which is robust to volume changes, equalisation and lossy re-encoding.
+func Audio(samples []float64) []Hash {
+	var hashes []Hash
+	var prev []float64
+	for start := 0; start+frameSize <= len(samples); start += frameHop {
+		energy := bandEnergy(samples[start : start+frameSize])
+		if prev != nil {
+			var hash Hash
+			for m := range bands - 1 {
+				if energy[m]-energy[m+1]-(prev[m]-prev[m+1]) > 0 {
+					hash |= 1 << (63 - m)
+				}
+			}
+			hashes = append(hashes, hash)
+		}
+		prev = energy
+	}
+
+	return hashes
+}
+
+func bandEnergy(frame []float64) []float64 {
+	buf := make([]complex128, frameSize)
+	for i, s := range frame {
+		buf[i] = complex(s*window[i], 0)
+	}
+	fft(buf)
+
+	energy := make([]float64, bands)
+	for b := range bands {
+		for k := bandEdges[b]; k < max(bandEdges[b+1], bandEdges[b]+1); k++ {
+			mag := cmplx.Abs(buf[k])
+			energy[b] += mag * mag
+		}
+	}
+
+	return energy
+}
+
+// fft is an in-place iterative radix-2 transform. len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range size / 2 {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
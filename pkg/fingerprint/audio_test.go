@@ -0,0 +1,114 @@
+package fingerprint
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func whiteNoise(seed int64, seconds float64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+
+	samples := make([]float64, int(seconds*SampleRate))
+	for i := range samples {
+		samples[i] = r.Float64()*2 - 1
+	}
+
+	return samples
+}
+
+func meanDistance(a, b []Hash) float64 {
+	var sum int
+	for i := range a {
+		sum += Distance(a[i], b[i])
+	}
+
+	return float64(sum) / float64(len(a))
+}
+
+func TestFFTMatchesDFT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	const n = 64
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(r.Float64()*2-1, r.Float64()*2-1)
+	}
+
+	want := make([]complex128, n)
+	for k := range n {
+		for j := range n {
+			want[k] += x[j] * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/n))
+		}
+	}
+
+	got := append([]complex128(nil), x...)
+	fft(got)
+
+	for k := range n {
+		if cmplx.Abs(got[k]-want[k]) > 1e-9 {
+			t.Errorf("bin %d = %v, want %v", k, got[k], want[k])
+		}
+	}
+}
+
+func TestFFTImpulse(t *testing.T) {
+	x := make([]complex128, 16)
+	x[0] = 1
+
+	fft(x)
+
+	for k, v := range x {
+		if cmplx.Abs(v-1) > 1e-12 {
+			t.Errorf("bin %d = %v, want 1", k, v)
+		}
+	}
+}
+
+func TestAudioGolden(t *testing.T) {
+	hashes := Audio(whiteNoise(1, 3))
+
+	// One hash per hop after the first frame.
+	if want := (int(3*SampleRate) - frameSize) / frameHop; len(hashes) != want {
+		t.Fatalf("got %d hashes, want %d", len(hashes), want)
+	}
+
+	golden := []Hash{0x534aca949949196b, 0xac45646b51ad9eba}
+	for i, want := range golden {
+		if hashes[i] != want {
+			t.Errorf("hash %d = %#016x, want %#016x", i, uint64(hashes[i]), uint64(want))
+		}
+	}
+}
+
+func TestAudioIgnoresVolume(t *testing.T) {
+	samples := whiteNoise(1, 3)
+
+	quiet := make([]float64, len(samples))
+	for i, s := range samples {
+		quiet[i] = s * 0.5
+	}
+
+	if d := meanDistance(Audio(samples), Audio(quiet)); d != 0 {
+		t.Errorf("mean distance to quieter copy = %.2f, want 0", d)
+	}
+}
+
+func TestAudioNearDuplicates(t *testing.T) {
+	samples := whiteNoise(1, 3)
+	hashes := Audio(samples)
+
+	r := rand.New(rand.NewSource(9))
+	noisy := make([]float64, len(samples))
+	for i, s := range samples {
+		noisy[i] = s + r.NormFloat64()*0.05
+	}
+
+	if d := meanDistance(hashes, Audio(noisy)); d > 6 {
+		t.Errorf("mean distance to noisy copy = %.2f, want at most 6", d)
+	}
+	if d := meanDistance(hashes, Audio(whiteNoise(2, 3))); d < 20 {
+		t.Errorf("mean distance to unrelated audio = %.2f, want at least 20", d)
+	}
+}
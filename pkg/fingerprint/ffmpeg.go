@@ -0,0 +1,138 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+var ErrFFmpegUnavailable = errors.New("ffmpeg is not installed")
+
+const (
+	// FrameInterval is how many seconds of video each sampled frame stands for.
+	FrameInterval = 2
+	// MaxSeconds bounds how much of a long file is fingerprinted.
+	MaxSeconds = 600
+)
+
+func Available() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+func output(ctx context.Context, args ...string) ([]byte, error) {
+	if !Available() {
+		return nil, ErrFFmpegUnavailable
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", append([]string{"-hide_banner", "-loglevel", "error"}, args...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// withTempFile gives ffmpeg a seekable file holding data, which containers with their index at the end need. Data
+// that is already a file on disk is used in place.
+func withTempFile[T any](data io.ReadSeeker, fn func(path string) (T, error)) (T, error) {
+	var zero T
+
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return zero, err
+	}
+	if f, ok := data.(*os.File); ok {
+		return fn(f.Name())
+	}
+
+	f, err := os.CreateTemp("", "fingerprint-*")
+	if err != nil {
+		return zero, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, data); err != nil {
+		f.Close()
+		return zero, err
+	}
+	if err := f.Close(); err != nil {
+		return zero, err
+	}
+
+	return fn(f.Name())
+}
+
+// Image hashes a still image. Formats the standard library cannot decode are handed to ffmpeg.
+func Image(ctx context.Context, data io.ReadSeeker) (Hash, error) {
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if img, _, err := image.Decode(data); err == nil {
+		return PHash(Gray(img)), nil
+	}
+
+	hashes, err := frames(ctx, data, "-frames:v", "1")
+	if err != nil {
+		return 0, err
+	}
+	if len(hashes) == 0 {
+		return 0, errors.New("fingerprint: no frame decoded")
+	}
+
+	return hashes[0], nil
+}
+
+// Video hashes one frame every FrameInterval seconds.
+func Video(ctx context.Context, data io.ReadSeeker) ([]Hash, error) {
+	return frames(ctx, data, "-t", strconv.Itoa(MaxSeconds), "-r", "1/"+strconv.Itoa(FrameInterval))
+}
+
+func frames(ctx context.Context, data io.ReadSeeker, extra ...string) ([]Hash, error) {
+	return withTempFile(data, func(path string) ([]Hash, error) {
+		args := append([]string{"-i", path, "-an"}, extra...)
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d:flags=area,format=gray", hashSize, hashSize),
+			"-f", "rawvideo", "pipe:1")
+
+		raw, err := output(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		var hashes []Hash
+		for frame := hashSize * hashSize; len(raw) >= frame; raw = raw[frame:] {
+			hashes = append(hashes, PHash(raw[:frame]))
+		}
+
+		return hashes, nil
+	})
+}
+
+// AudioFile decodes the first audio track of data and fingerprints it.
+func AudioFile(ctx context.Context, data io.ReadSeeker) ([]Hash, error) {
+	return withTempFile(data, func(path string) ([]Hash, error) {
+		raw, err := output(ctx, "-i", path, "-vn", "-t", strconv.Itoa(MaxSeconds), "-ac", "1",
+			"-ar", strconv.Itoa(SampleRate), "-f", "s16le", "pipe:1")
+		if err != nil {
+			return nil, err
+		}
+
+		samples := make([]float64, len(raw)/2)
+		for i := range samples {
+			samples[i] = float64(int16(binary.LittleEndian.Uint16(raw[2*i:]))) / 32768
+		}
+
+		return Audio(samples), nil
+	})
+}
@@ -0,0 +1,10 @@
+package fingerprint
+
+import "math/bits"
+
+// Hash is a 64 bit fingerprint. Near-duplicate inputs give hashes a small Hamming distance apart.
+type Hash uint64
+
+func Distance(a, b Hash) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
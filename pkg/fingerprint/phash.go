@@ -0,0 +1,90 @@
+package fingerprint
+
+import (
+	"image"
+	"math"
+	"slices"
+)
+
+// hashSize is the side of the grayscale thumbnail the DCT runs over. Only the lowest 8x8 frequencies end up in the
+// hash, which is what makes it survive rescaling, recompression and small colour changes.
+const hashSize = 32
+
+var dctTable = func() [hashSize][hashSize]float64 {
+	var table [hashSize][hashSize]float64
+	for k := range hashSize {
+		for n := range hashSize {
+			table[k][n] = math.Cos(math.Pi / hashSize * (float64(n) + 0.5) * float64(k))
+		}
+	}
+	return table
+}()
+
+// PHash hashes a 32x32 grayscale frame, row by row, as produced by Gray.
+func PHash(gray []byte) Hash {
+	var rows [hashSize][8]float64
+	for y := range hashSize {
+		for k := range 8 {
+			var sum float64
+			for x := range hashSize {
+				sum += float64(gray[y*hashSize+x]) * dctTable[k][x]
+			}
+			rows[y][k] = sum
+		}
+	}
+
+	coeffs := make([]float64, 0, 64)
+	for k := range 8 {
+		for l := range 8 {
+			var sum float64
+			for y := range hashSize {
+				sum += rows[y][l] * dctTable[k][y]
+			}
+			coeffs = append(coeffs, sum)
+		}
+	}
+
+	// The DC term only tracks overall brightness, so it is left out of the median.
+	sorted := slices.Clone(coeffs[1:])
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash Hash
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << (63 - i)
+		}
+	}
+
+	return hash
+}
+
+// Gray shrinks img to the 32x32 grayscale frame PHash expects by averaging the pixels that fall in each cell.
+func Gray(img image.Image) []byte {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	gray := make([]byte, hashSize*hashSize)
+	if w == 0 || h == 0 {
+		return gray
+	}
+
+	for cy := range hashSize {
+		y0, y1 := cy*h/hashSize, max((cy+1)*h/hashSize, cy*h/hashSize+1)
+		for cx := range hashSize {
+			x0, x1 := cx*w/hashSize, max((cx+1)*w/hashSize, cx*w/hashSize+1)
+
+			var sum, count float64
+			for y := y0; y < y1 && y < h; y++ {
+				for x := x0; x < x1 && x < w; x++ {
+					r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+					sum += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+					count++
+				}
+			}
+			gray[cy*hashSize+cx] = byte(sum / count)
+		}
+	}
+
+	return gray
+}
@@ -0,0 +1,109 @@
+package fingerprint
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// scene draws a few soft blobs at positions picked from seed, a stand-in for a downscaled video frame.
+func scene(seed int64) []byte {
+	r := rand.New(rand.NewSource(seed))
+
+	type blob struct{ x, y, size, amplitude float64 }
+	blobs := make([]blob, 6)
+	for i := range blobs {
+		blobs[i] = blob{r.Float64() * hashSize, r.Float64() * hashSize, 3 + r.Float64()*6, r.Float64()*200 - 100}
+	}
+
+	gray := make([]byte, hashSize*hashSize)
+	for y := range hashSize {
+		for x := range hashSize {
+			v := 128.0
+			for _, b := range blobs {
+				dx, dy := float64(x)-b.x, float64(y)-b.y
+				v += b.amplitude * math.Exp(-(dx*dx+dy*dy)/(2*b.size*b.size))
+			}
+			gray[y*hashSize+x] = byte(min(max(v, 0), 255))
+		}
+	}
+
+	return gray
+}
+
+func addNoise(gray []byte, amount int, seed int64) []byte {
+	r := rand.New(rand.NewSource(seed))
+
+	out := make([]byte, len(gray))
+	for i, v := range gray {
+		out[i] = byte(min(max(int(v)+r.Intn(2*amount+1)-amount, 0), 255))
+	}
+
+	return out
+}
+
+func brighten(gray []byte, delta int) []byte {
+	out := make([]byte, len(gray))
+	for i, v := range gray {
+		out[i] = byte(min(int(v)+delta, 255))
+	}
+
+	return out
+}
+
+func TestPHashGolden(t *testing.T) {
+	tests := []struct {
+		seed int64
+		want Hash
+	}{
+		{1, 0xf1cc0f338c0f33cc},
+		{2, 0xcfcf61f0e0641e0e},
+	}
+
+	for _, tt := range tests {
+		if got := PHash(scene(tt.seed)); got != tt.want {
+			t.Errorf("PHash(scene(%d)) = %#016x, want %#016x", tt.seed, uint64(got), uint64(tt.want))
+		}
+	}
+}
+
+func TestPHashNearDuplicates(t *testing.T) {
+	frame := scene(1)
+	hash := PHash(frame)
+
+	if d := Distance(hash, PHash(addNoise(frame, 8, 1))); d > 6 {
+		t.Errorf("distance to noisy copy = %d, want at most 6", d)
+	}
+	if d := Distance(hash, PHash(brighten(frame, 20))); d > 6 {
+		t.Errorf("distance to brightened copy = %d, want at most 6", d)
+	}
+}
+
+func TestPHashDistinctFrames(t *testing.T) {
+	hash := PHash(scene(1))
+
+	for seed := int64(2); seed <= 7; seed++ {
+		if d := Distance(hash, PHash(scene(seed))); d < 16 {
+			t.Errorf("distance to scene(%d) = %d, want at least 16", seed, d)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b Hash
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xffffffffffffffff, 0, 64},
+		{0xf0f0f0f0f0f0f0f0, 0x0f0f0f0f0f0f0f0f, 64},
+		{0x8000000000000001, 0x0000000000000001, 1},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%#x, %#x) = %d, want %d", uint64(tt.a), uint64(tt.b), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package mediatype
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrUnsupported = errors.New("unsupported file type")
+
+type Kind string
+
+const (
+	KindVideo    Kind = "video"
+	KindAudio    Kind = "audio"
+	KindImage    Kind = "image"
+	KindDocument Kind = "document"
+	KindArchive  Kind = "archive"
+)
+
+type Type struct {
+	MIME      string
+	Kind      Kind
+	Extension string
+	// Sniffed lists what http.DetectContentType reports for a genuine file. Formats it cannot recognise also accept
+	// application/octet-stream, so only files that sniff as something else are rejected.
+	Sniffed []string
+}
+
+var types = []Type{
+	{MIME: "video/mp4", Kind: KindVideo, Extension: ".mp4", Sniffed: []string{"video/mp4"}},
+	{MIME: "video/webm", Kind: KindVideo, Extension: ".webm", Sniffed: []string{"video/webm"}},
+	{MIME: "video/mp2t", Kind: KindVideo, Extension: ".ts", Sniffed: []string{"application/octet-stream"}},
+	{MIME: "video/quicktime", Kind: KindVideo, Extension: ".mov", Sniffed: []string{"video/mp4", "application/octet-stream"}},
+	{MIME: "audio/mpeg", Kind: KindAudio, Extension: ".mp3", Sniffed: []string{"audio/mpeg", "application/octet-stream"}},
+	{MIME: "audio/mp4", Kind: KindAudio, Extension: ".m4a", Sniffed: []string{"video/mp4", "application/octet-stream"}},
+	{MIME: "audio/wav", Kind: KindAudio, Extension: ".wav", Sniffed: []string{"audio/wave"}},
+	{MIME: "audio/ogg", Kind: KindAudio, Extension: ".ogg", Sniffed: []string{"application/ogg"}},
+	{MIME: "audio/flac", Kind: KindAudio, Extension: ".flac", Sniffed: []string{"application/octet-stream"}},
+	{MIME: "image/jpeg", Kind: KindImage, Extension: ".jpg", Sniffed: []string{"image/jpeg"}},
+	{MIME: "image/png", Kind: KindImage, Extension: ".png", Sniffed: []string{"image/png"}},
+	{MIME: "image/gif", Kind: KindImage, Extension: ".gif", Sniffed: []string{"image/gif"}},
+	{MIME: "image/webp", Kind: KindImage, Extension: ".webp", Sniffed: []string{"image/webp"}},
+	{MIME: "application/pdf", Kind: KindDocument, Extension: ".pdf", Sniffed: []string{"application/pdf"}},
+	{MIME: "application/epub+zip", Kind: KindDocument, Extension: ".epub", Sniffed: []string{"application/zip"}},
+	{MIME: "application/zip", Kind: KindArchive, Extension: ".zip", Sniffed: []string{"application/zip"}},
+	{MIME: "application/gzip", Kind: KindArchive, Extension: ".gz", Sniffed: []string{"application/x-gzip"}},
+}
+
+var aliases = map[string]string{
+	".jpeg": ".jpg",
+	".m4v":  ".mp4",
+	".tgz":  ".gz",
+}
+
+func byExtension(ext string) (Type, bool) {
+	ext = strings.ToLower(ext)
+	if alias, ok := aliases[ext]; ok {
+		ext = alias
+	}
+
+	for _, t := range types {
+		if t.Extension == ext {
+			return t, true
+		}
+	}
+
+	return Type{}, false
+}
+
+// Detect checks the extension against the allowlist and the leading bytes of the file against what that extension
+// should look like, so a renamed executable or HTML page is refused rather than served under a trusted type.
+func Detect(data []byte, ext string) (Type, error) {
+	t, ok := byExtension(ext)
+	if !ok {
+		return Type{}, fmt.Errorf("%w: %q", ErrUnsupported, ext)
+	}
+
+	sniffed, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	for _, s := range t.Sniffed {
+		if s == sniffed {
+			return t, nil
+		}
+	}
+
+	return Type{}, fmt.Errorf("%w: %s file looks like %s", ErrUnsupported, ext, sniffed)
+}
+
+// Lookup returns the registered type for a stored MIME type. Unknown types, such as rows written before detection
+// existed, fall back to an opaque download.
+func Lookup(mime string) Type {
+	for _, t := range types {
+		if t.MIME == mime {
+			return t
+		}
+	}
+
+	return Type{MIME: "application/octet-stream", Kind: KindArchive, Extension: ".bin"}
+}
+
+// Inline reports whether browsers should display the type rather than download it.
+func (t Type) Inline() bool {
+	return t.Kind != KindArchive
+}
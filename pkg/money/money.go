@@ -0,0 +1,111 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const DefaultCurrency = "USD"
+
+var (
+	ErrInvalidCurrency  = errors.New("invalid currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+var minorUnits = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"USD": 2,
+}
+
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if _, ok := minorUnits[currency]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+
+	return currency, nil
+}
+
+func (m Money) Validate() error {
+	if _, ok := minorUnits[m.Currency]; !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, m.Currency)
+	}
+
+	if m.Amount < 0 {
+		return errors.New("amount cannot be negative")
+	}
+
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}, nil
+}
+
+// MulRatio returns m * num / den rounded half away from zero.
+func (m Money) MulRatio(num, den int64) Money {
+	product := m.Amount * num
+	half := den / 2
+	if product < 0 {
+		half = -half
+	}
+
+	return Money{Amount: (product + half) / den, Currency: m.Currency}
+}
+
+func (m Money) Percent(percent int64) Money {
+	return m.MulRatio(percent, 100)
+}
+
+func (m Money) String() string {
+	units := minorUnits[m.Currency]
+	if units == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	scale := int64(1)
+	for i := 0; i < units; i++ {
+		scale *= 10
+	}
+
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/scale, units, amount%scale, m.Currency)
+}
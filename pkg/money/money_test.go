@@ -0,0 +1,70 @@
+package money
+
+import "testing"
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{"exact", 10000, 2000, 10000, 2000},
+		{"rounds down below half", 14, 1, 10, 1},
+		{"rounds half up", 15, 1, 10, 2},
+		{"rounds up above half", 16, 1, 10, 2},
+		{"negative rounds toward zero below half", -14, 1, 10, -1},
+		{"negative rounds half away from zero", -15, 1, 10, -2},
+		{"negative rounds away from zero above half", -16, 1, 10, -2},
+		{"negative ratio", 15, -1, 10, -2},
+		{"odd denominator", 2, 1, 3, 1},
+		{"fee on a cent", 1, 2000, 10000, 0},
+		{"fee on half a unit", 25, 2000, 10000, 5},
+		{"tax on an odd price", 1999, 1750, 10000, 350},
+		{"refund of an odd price", -1999, 1750, 10000, -350},
+		{"zero", 0, 2000, 10000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, "USD").MulRatio(tt.num, tt.den)
+			if got.Amount != tt.want || got.Currency != "USD" {
+				t.Errorf("%d * %d / %d = %v, want %d USD", tt.amount, tt.num, tt.den, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulRatioSplitsBalance(t *testing.T) {
+	// A refund reverses each leg of the sale, so rounding must be symmetric for the legs to cancel exactly.
+	for amount := int64(-500); amount <= 500; amount++ {
+		sale := New(amount, "EUR").MulRatio(1750, 10000)
+		refund := New(-amount, "EUR").MulRatio(1750, 10000)
+		if sale.Amount != -refund.Amount {
+			t.Fatalf("%d: sale leg %d, refund leg %d", amount, sale.Amount, refund.Amount)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{New(1234, "USD"), "12.34 USD"},
+		{New(5, "USD"), "0.05 USD"},
+		{New(0, "USD"), "0.00 USD"},
+		{New(-5, "USD"), "-0.05 USD"},
+		{New(-1234, "GBP"), "-12.34 GBP"},
+		{New(100000, "EUR"), "1000.00 EUR"},
+		{New(1234, "JPY"), "1234 JPY"},
+		{New(0, "JPY"), "0 JPY"},
+		{New(-50, "JPY"), "-50 JPY"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("String(%d %s) = %q, want %q", tt.money.Amount, tt.money.Currency, got, tt.want)
+		}
+	}
+}
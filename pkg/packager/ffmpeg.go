@@ -0,0 +1,50 @@
+package packager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+var ErrFFmpegUnavailable = errors.New("ffmpeg is not installed")
+
+const SegmentSeconds = 4
+
+func Available() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+// PackageHLS transcodes src into a clear fMP4 HLS rendition in dir and returns the playlist path. videoFilter, when
+// set, is applied to the video; keyframes are forced at the same times either way, so renditions of one source
+// packaged with different filters have aligned segments.
+func PackageHLS(ctx context.Context, src, dir, videoFilter string) (string, error) {
+	if !Available() {
+		return "", ErrFFmpegUnavailable
+	}
+
+	playlist := filepath.Join(dir, "index.m3u8")
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", src,
+		"-map", "0:v:0", "-map", "0:a?",
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "21", "-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", SegmentSeconds),
+	}
+	if videoFilter != "" {
+		args = append(args, "-vf", videoFilter)
+	}
+	args = append(args, "-c:a", "aac",
+		"-f", "hls", "-hls_time", strconv.Itoa(SegmentSeconds), "-hls_playlist_type", "vod",
+		"-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init.mp4",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%05d.m4s"),
+		playlist)
+
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("ffmpeg hls: %v: %s", err, bytes.TrimSpace(out))
+	}
+
+	return playlist, nil
+}
@@ -0,0 +1,156 @@
+package packager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPlaylist = errors.New("invalid HLS playlist")
+
+type Segment struct {
+	URI      string
+	Duration float64
+}
+
+// Playlist is the subset of an HLS media playlist needed to re-serve its segments: the fMP4 init section, if any,
+// and the ordered segments. Encrypted input is rejected because segments are always re-encrypted on import.
+type Playlist struct {
+	InitURI  string
+	Segments []Segment
+}
+
+func (p *Playlist) TargetDuration() int {
+	target := 0.0
+	for _, segment := range p.Segments {
+		target = math.Max(target, segment.Duration)
+	}
+
+	return int(math.Ceil(target))
+}
+
+func ParsePlaylist(r io.Reader) (*Playlist, error) {
+	scanner := bufio.NewScanner(r)
+
+	var playlist Playlist
+	var duration float64
+	pending := false
+	first := true
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if first {
+			if line != "#EXTM3U" {
+				return nil, fmt.Errorf("%w: missing #EXTM3U header", ErrInvalidPlaylist)
+			}
+			first = false
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			d, err := strconv.ParseFloat(value, 64)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%w: bad segment duration %q", ErrInvalidPlaylist, value)
+			}
+			duration = d
+			pending = true
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			uri, ok := attribute(strings.TrimPrefix(line, "#EXT-X-MAP:"), "URI")
+			if !ok {
+				return nil, fmt.Errorf("%w: EXT-X-MAP without URI", ErrInvalidPlaylist)
+			}
+			playlist.InitURI = uri
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			method, _ := attribute(strings.TrimPrefix(line, "#EXT-X-KEY:"), "METHOD")
+			if method != "NONE" {
+				return nil, fmt.Errorf("%w: input segments must not be encrypted", ErrInvalidPlaylist)
+			}
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF"):
+			return nil, fmt.Errorf("%w: expected a media playlist, got a master playlist", ErrInvalidPlaylist)
+		case strings.HasPrefix(line, "#"):
+		default:
+			if !pending {
+				return nil, fmt.Errorf("%w: segment %q has no #EXTINF", ErrInvalidPlaylist, line)
+			}
+			playlist.Segments = append(playlist.Segments, Segment{URI: line, Duration: duration})
+			pending = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(playlist.Segments) == 0 {
+		return nil, fmt.Errorf("%w: no segments", ErrInvalidPlaylist)
+	}
+
+	return &playlist, nil
+}
+
+func attribute(list, name string) (string, bool) {
+	for _, attr := range splitAttributes(list) {
+		key, value, ok := strings.Cut(attr, "=")
+		if ok && key == name {
+			return strings.Trim(value, `"`), true
+		}
+	}
+
+	return "", false
+}
+
+func splitAttributes(list string) []string {
+	var attrs []string
+	quoted := false
+	start := 0
+	for i, c := range list {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			attrs = append(attrs, list[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(attrs, list[start:])
+}
+
+// MediaPlaylist is what gets written out for a viewer: URIs are already resolved for that viewer.
+type MediaPlaylist struct {
+	TargetDuration int
+	InitURI        string
+	KeyURI         string
+	SegmentURIs    []string
+	Durations      []float64
+}
+
+// Write renders a VOD playlist. The key tag follows EXT-X-MAP so the init section stays in the clear while every
+// media segment is AES-128 encrypted with the default IV, its media sequence number.
+func (p *MediaPlaylist) Write(w io.Writer) error {
+	b := &strings.Builder{}
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n")
+	fmt.Fprintf(b, "#EXT-X-TARGETDURATION:%d\n", p.TargetDuration)
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	if p.InitURI != "" {
+		fmt.Fprintf(b, "#EXT-X-MAP:URI=%q\n", p.InitURI)
+	}
+	fmt.Fprintf(b, "#EXT-X-KEY:METHOD=AES-128,URI=%q\n", p.KeyURI)
+	for i, uri := range p.SegmentURIs {
+		fmt.Fprintf(b, "#EXTINF:%.3f,\n%s\n", p.Durations[i], uri)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/money"
+	"github.com/gofrs/uuid"
+)
+
+var ErrPaymentFailed = errors.New("payment failed")
+
+type Provider interface {
+	Charge(reference string, amount money.Money) (string, error)
+	Refund(paymentId string, amount money.Money) error
+}
+
+// Manual accepts every charge and refund without contacting a processor; settlement happens out of band.
+type Manual struct{}
+
+func NewManual() Provider {
+	return Manual{}
+}
+
+func (Manual) Charge(reference string, amount money.Money) (string, error) {
+	paymentId, err := uuid.NewV4()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+
+	log.Printf("recorded manual payment %s of %s for %s\n", paymentId, amount, reference)
+	return "manual_" + paymentId.String(), nil
+}
+
+func (Manual) Refund(paymentId string, amount money.Money) error {
+	if paymentId == "" {
+		return fmt.Errorf("%w: missing payment id", ErrPaymentFailed)
+	}
+
+	log.Printf("recorded manual refund of %s for payment %s\n", amount, paymentId)
+	return nil
+}
@@ -0,0 +1,59 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+var ErrFFmpegUnavailable = errors.New("ffmpeg is not installed")
+
+const (
+	TrailerSeconds = 30
+	// Seek offset for the thumbnail frame, past the black frames most videos open with.
+	thumbnailOffset = "00:00:03"
+)
+
+func Available() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+func run(ctx context.Context, args ...string) error {
+	if !Available() {
+		return ErrFFmpegUnavailable
+	}
+
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+// Thumbnail writes a single 640px wide JPEG frame of src to dst. Clips shorter than the offset fall back to their
+// first frame.
+func Thumbnail(ctx context.Context, src, dst string) error {
+	err := run(ctx, "-ss", thumbnailOffset, "-i", src, "-frames:v", "1", "-vf", "scale=640:-2", "-q:v", "3", dst)
+	if errors.Is(err, ErrFFmpegUnavailable) {
+		return err
+	}
+	if info, statErr := os.Stat(dst); err == nil && statErr == nil && info.Size() > 0 {
+		return nil
+	}
+
+	return run(ctx, "-i", src, "-frames:v", "1", "-vf", "scale=640:-2", "-q:v", "3", dst)
+}
+
+// Trailer writes the first TrailerSeconds of src to dst as a low bitrate 480p MP4 that starts playing before it has
+// fully downloaded.
+func Trailer(ctx context.Context, src, dst string) error {
+	return run(ctx, "-i", src, "-t", strconv.Itoa(TrailerSeconds), "-map", "0:v:0", "-map", "0:a?",
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "28", "-vf", "scale=-2:480",
+		"-c:a", "aac", "-b:a", "96k", "-movflags", "+faststart", dst)
+}
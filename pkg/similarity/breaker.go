@@ -0,0 +1,58 @@
+package similarity
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+)
+
+// breaker opens after threshold consecutive failures and refuses calls until cooldown has passed. It then lets a
+// single probe through: success closes it again, failure restarts the cooldown.
+type breaker struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(clock clock.Clock, threshold int, cooldown time.Duration) *breaker {
+	return &breaker{clock: clock, threshold: threshold, cooldown: cooldown}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.clock.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = b.clock.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,56 @@
+package similarity
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+)
+
+// Fake answers every check with the configured result or error and records the file ids it was asked about.
+type Fake struct {
+	mu     sync.Mutex
+	result Result
+	err    error
+	calls  []string
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) SetResult(result Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.result = result
+	f.err = nil
+}
+
+func (f *Fake) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.calls...)
+}
+
+func (f *Fake) Check(ctx context.Context, file io.ReadSeeker, fileId string, fileType mediatype.Type) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, fileId)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	result := f.result
+	return &result, nil
+}
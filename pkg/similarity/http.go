@@ -0,0 +1,207 @@
+package similarity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+)
+
+// DefaultRoutes maps each kind of file to the route of the similarity service that fingerprints it.
+var DefaultRoutes = map[mediatype.Kind]string{
+	mediatype.KindVideo: "/compare-video-bytes",
+}
+
+type HTTPConfig struct {
+	BaseURL string
+	Routes  map[mediatype.Kind]string
+	// Timeout bounds a single attempt. Defaults to 10 seconds.
+	Timeout time.Duration
+	// Deadline bounds the whole check, retries and backoff included, so an upload waiting on it finishes before the
+	// server's write timeout. Defaults to 20 seconds.
+	Deadline         time.Duration
+	MaxAttempts      int
+	Backoff          time.Duration
+	FailureThreshold int
+	Cooldown         time.Duration
+	Clock            clock.Clock
+}
+
+type HTTP struct {
+	config  HTTPConfig
+	client  *http.Client
+	breaker *breaker
+}
+
+func NewHTTP(config HTTPConfig) *HTTP {
+	if config.Routes == nil {
+		config.Routes = DefaultRoutes
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Deadline <= 0 {
+		config.Deadline = 20 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 500 * time.Millisecond
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+
+	return &HTTP{
+		config:  config,
+		client:  &http.Client{},
+		breaker: newBreaker(config.Clock, config.FailureThreshold, config.Cooldown),
+	}
+}
+
+type compareResponse struct {
+	VideoID       string   `json:"video_id"`
+	MaxSimilarity float64  `json:"max_similarity"`
+	Similar       bool     `json:"similar"`
+	ModelVersion  string   `json:"model_version"`
+	Threshold     *float64 `json:"threshold"`
+}
+
+func (c *HTTP) Check(ctx context.Context, file io.ReadSeeker, fileId string, fileType mediatype.Type) (*Result, error) {
+	route, ok := c.config.Routes[fileType.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKind, fileType.Kind)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Deadline)
+	defer cancel()
+
+	var err error
+	for attempt := 0; attempt < c.config.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+			}
+		}
+
+		if !c.breaker.allow() {
+			return nil, ErrCircuitOpen
+		}
+
+		var result *Result
+		result, err = c.post(ctx, c.config.BaseURL+route, file, fileId, fileType)
+		if err == nil {
+			c.breaker.success()
+			return result, nil
+		}
+
+		if !errors.Is(err, ErrUnavailable) {
+			// The service answered, so it is up even though it refused this file.
+			c.breaker.success()
+			return nil, err
+		}
+		c.breaker.failure()
+	}
+
+	return nil, err
+}
+
+// backoff doubles the delay on each retry and adds up to half of it again as jitter.
+func (c *HTTP) backoff(attempt int) time.Duration {
+	delay := c.config.Backoff << (attempt - 1)
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *HTTP) post(ctx context.Context, url string, file io.ReadSeeker, fileId string, fileType mediatype.Type) (*Result, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	// The form is written as the request sends it, so the file is streamed rather than buffered on every attempt.
+	body, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	go func() {
+		pipe.CloseWithError(writeForm(writer, file, fileId, fileType))
+	}()
+	defer body.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: status %d: %s", ErrUnavailable, res.StatusCode, bytes.TrimSpace(resBody))
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return nil, fmt.Errorf("%w: status %d: %s", ErrRejected, res.StatusCode, bytes.TrimSpace(resBody))
+	}
+
+	var resp compareResponse
+	if err = json.Unmarshal(resBody, &resp); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrUnavailable, err)
+	}
+
+	return &Result{MatchID: resp.VideoID, Similarity: resp.MaxSimilarity, Similar: resp.Similar,
+		ModelVersion: resp.ModelVersion, Threshold: resp.Threshold}, nil
+}
+
+func writeForm(writer *multipart.Writer, file io.Reader, fileId string, fileType mediatype.Type) error {
+	part, err := writer.CreateFormFile("file", "file"+fileType.Extension)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(part, file); err != nil {
+		return err
+	}
+
+	if err = writer.WriteField("file_id", fileId); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
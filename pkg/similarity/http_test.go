@@ -0,0 +1,229 @@
+package similarity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/clock"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+)
+
+var video = mediatype.Type{Kind: mediatype.KindVideo, Extension: ".mp4", MIME: "video/mp4"}
+
+// testServer answers each request with the next status in statuses, repeating the last one, and counts requests.
+func testServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil || r.FormValue("file_id") == "" {
+			http.Error(w, "missing file", http.StatusBadRequest)
+			return
+		}
+
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		json.NewEncoder(w).Encode(compareResponse{VideoID: "match", MaxSimilarity: 0.95, Similar: true,
+			ModelVersion: "v2"})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func newTestHTTP(url string, clk clock.Clock, failureThreshold int) *HTTP {
+	return NewHTTP(HTTPConfig{
+		BaseURL:          url,
+		Timeout:          time.Second,
+		Deadline:         2 * time.Second,
+		MaxAttempts:      3,
+		Backoff:          time.Millisecond,
+		FailureThreshold: failureThreshold,
+		Cooldown:         time.Minute,
+		Clock:            clk,
+	})
+}
+
+func TestHTTPCheckParsesResult(t *testing.T) {
+	server, _ := testServer(t, http.StatusOK)
+	checker := newTestHTTP(server.URL, clock.New(), 2)
+
+	result, err := checker.Check(context.Background(), strings.NewReader("video"), "file", video)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	if result.MatchID != "match" || result.Similarity != 0.95 || !result.Similar || result.ModelVersion != "v2" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestHTTPCheckStatusCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  error
+		wantHits int32
+	}{
+		{"retries server errors", []int{http.StatusInternalServerError, http.StatusOK}, nil, 2},
+		{"retries rate limiting", []int{http.StatusTooManyRequests, http.StatusOK}, nil, 2},
+		{"gives up after max attempts", []int{http.StatusServiceUnavailable}, ErrUnavailable, 3},
+		{"does not retry rejections", []int{http.StatusUnprocessableEntity}, ErrRejected, 1},
+		{"treats not found as a rejection", []int{http.StatusNotFound}, ErrRejected, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := testServer(t, tt.statuses...)
+			checker := newTestHTTP(server.URL, clock.New(), 10)
+
+			_, err := checker.Check(context.Background(), strings.NewReader("video"), "file", video)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantHits {
+				t.Errorf("requests = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckUnsupportedKind(t *testing.T) {
+	server, calls := testServer(t, http.StatusOK)
+	checker := newTestHTTP(server.URL, clock.New(), 2)
+
+	_, err := checker.Check(context.Background(), strings.NewReader("audio"), "file", mediatype.Type{Kind: mediatype.KindAudio})
+	if !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("err = %v, want %v", err, ErrUnsupportedKind)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("requests = %d, want 0", calls.Load())
+	}
+}
+
+func TestHTTPBreakerOpensAndRecovers(t *testing.T) {
+	server, calls := testServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	checker := newTestHTTP(server.URL, clk, 2)
+
+	// Two failed attempts reach the threshold, so the third attempt is refused without a request.
+	_, err := checker.Check(context.Background(), strings.NewReader("video"), "file", video)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want %v", err, ErrCircuitOpen)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("requests = %d, want 2", calls.Load())
+	}
+
+	_, err = checker.Check(context.Background(), strings.NewReader("video"), "file", video)
+	if !errors.Is(err, ErrCircuitOpen) || calls.Load() != 2 {
+		t.Fatalf("err = %v after %d requests, want %v without a request", err, calls.Load(), ErrCircuitOpen)
+	}
+
+	clk.Advance(time.Minute)
+	if _, err = checker.Check(context.Background(), strings.NewReader("video"), "file", video); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+
+	if _, err = checker.Check(context.Background(), strings.NewReader("video"), "file", video); err != nil {
+		t.Fatalf("check after recovery: %v", err)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("requests = %d, want 4", calls.Load())
+	}
+}
+
+func TestHTTPRejectionKeepsBreakerClosed(t *testing.T) {
+	server, calls := testServer(t, http.StatusBadRequest)
+	checker := newTestHTTP(server.URL, clock.New(), 2)
+
+	for range 3 {
+		if _, err := checker.Check(context.Background(), strings.NewReader("video"), "file", video); !errors.Is(err, ErrRejected) {
+			t.Fatalf("err = %v, want %v", err, ErrRejected)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Errorf("requests = %d, want 3", calls.Load())
+	}
+}
+
+func TestHTTPCheckDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	checker := NewHTTP(HTTPConfig{
+		BaseURL:          server.URL,
+		Timeout:          150 * time.Millisecond,
+		Deadline:         250 * time.Millisecond,
+		MaxAttempts:      5,
+		Backoff:          50 * time.Millisecond,
+		FailureThreshold: 10,
+	})
+
+	start := time.Now()
+	_, err := checker.Check(context.Background(), strings.NewReader("video"), "file", video)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want %v", err, ErrUnavailable)
+	}
+	if elapsed > time.Second {
+		t.Errorf("check took %v, want it stopped near the 250ms deadline", elapsed)
+	}
+}
+
+func TestHTTPCheckResendsWholeFile(t *testing.T) {
+	file := strings.Repeat("frame", 100_000)
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "missing file", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(part)
+		received = append(received, string(data))
+
+		if len(received) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(compareResponse{VideoID: "match", ModelVersion: "v2"})
+	}))
+	t.Cleanup(server.Close)
+
+	checker := newTestHTTP(server.URL, clock.New(), 10)
+	if _, err := checker.Check(context.Background(), strings.NewReader(file), "file", video); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("requests = %d, want 2", len(received))
+	}
+	for i, data := range received {
+		if data != file {
+			t.Errorf("attempt %d sent %d bytes, want the whole %d byte file", i+1, len(data), len(file))
+		}
+	}
+}
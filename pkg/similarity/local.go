@@ -0,0 +1,101 @@
+package similarity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/fingerprint"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+)
+
+const (
+	KindVisual = "visual"
+	KindAudio  = "audio"
+
+	localModelVersion = "phash-v1"
+)
+
+// Index finds the file whose stored fingerprints most overlap a query, ignoring the queried file itself. Nearest
+// returns how many of the query hashes have a stored hash within maxDistance bits.
+type Index interface {
+	Nearest(fileId, kind string, hashes []fingerprint.Hash, maxDistance int) (string, int, error)
+}
+
+type LocalConfig struct {
+	// MaxDistance is the Hamming distance up to which two hashes count as the same frame. Defaults to 6.
+	MaxDistance int
+	// Timeout bounds fingerprinting a single file. Defaults to two minutes.
+	Timeout time.Duration
+}
+
+// Local fingerprints files in-process and looks them up in Index, so a deployment can run without the similarity
+// service. Images and sampled video frames are compared by perceptual hash, audio by band-energy fingerprints. The
+// score is the share of the file's hashes that match its closest file; whether that makes it a duplicate is left to
+// the caller's threshold. The file's own hashes are returned in the result rather than indexed, so a check for an
+// upload that is never stored leaves nothing behind.
+type Local struct {
+	index  Index
+	config LocalConfig
+}
+
+func NewLocal(index Index, config LocalConfig) *Local {
+	if config.MaxDistance <= 0 {
+		config.MaxDistance = 6
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+
+	return &Local{index: index, config: config}
+}
+
+func (l *Local) Check(ctx context.Context, file io.ReadSeeker, fileId string, fileType mediatype.Type) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.config.Timeout)
+	defer cancel()
+
+	kind, hashes, err := l.fingerprint(ctx, file, fileType)
+	if err != nil {
+		if errors.Is(err, fingerprint.ErrFFmpegUnavailable) {
+			return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return nil, err
+	}
+
+	result := &Result{ModelVersion: localModelVersion, Fingerprints: &Fingerprints{Kind: kind, Hashes: hashes}}
+	if len(hashes) == 0 {
+		return result, nil
+	}
+
+	matchId, matched, err := l.index.Nearest(fileId, kind, hashes, l.config.MaxDistance)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if matched > 0 {
+		result.MatchID = matchId
+		result.Similarity = float64(matched) / float64(len(hashes))
+	}
+
+	return result, nil
+}
+
+func (l *Local) fingerprint(ctx context.Context, file io.ReadSeeker, fileType mediatype.Type) (string, []fingerprint.Hash, error) {
+	switch fileType.Kind {
+	case mediatype.KindImage:
+		hash, err := fingerprint.Image(ctx, file)
+		if err != nil {
+			return "", nil, err
+		}
+		return KindVisual, []fingerprint.Hash{hash}, nil
+	case mediatype.KindVideo:
+		hashes, err := fingerprint.Video(ctx, file)
+		return KindVisual, hashes, err
+	case mediatype.KindAudio:
+		hashes, err := fingerprint.AudioFile(ctx, file)
+		return KindAudio, hashes, err
+	default:
+		return "", nil, ErrUnsupportedKind
+	}
+}
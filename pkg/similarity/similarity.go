@@ -0,0 +1,64 @@
+package similarity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/fingerprint"
+	"github.com/AaravShirvoikar/is-project-drm-backend/pkg/mediatype"
+)
+
+var (
+	// ErrUnavailable covers timeouts, connection failures, 5xx responses and an open circuit: the file was not checked.
+	ErrUnavailable = errors.New("similarity service unavailable")
+	ErrCircuitOpen = fmt.Errorf("%w: circuit open", ErrUnavailable)
+	// ErrRejected means the service refused the request itself, so retrying it would not help.
+	ErrRejected = errors.New("similarity service rejected the request")
+	// ErrUnsupportedKind is returned for kinds of file the checker has no backend for.
+	ErrUnsupportedKind = errors.New("no similarity check for this kind of file")
+)
+
+// UnavailablePolicy decides what happens to an upload when the similarity service cannot be reached.
+type UnavailablePolicy string
+
+const (
+	RejectWhenUnavailable UnavailablePolicy = "reject"
+	QueueWhenUnavailable  UnavailablePolicy = "queue"
+	FlagWhenUnavailable   UnavailablePolicy = "flag"
+)
+
+func (p UnavailablePolicy) Valid() bool {
+	switch p {
+	case RejectWhenUnavailable, QueueWhenUnavailable, FlagWhenUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidThreshold reports whether threshold can be used as a similarity score cut-off.
+func ValidThreshold(threshold float64) bool {
+	return threshold > 0 && threshold <= 1
+}
+
+type Result struct {
+	MatchID      string   `json:"match_id"`
+	Similarity   float64  `json:"similarity"`
+	Similar      bool     `json:"similar"`
+	ModelVersion string   `json:"model_version,omitempty"`
+	Threshold    *float64 `json:"threshold,omitempty"`
+	// Fingerprints are the hashes a local check computed for the file. They are not indexed by the check itself, so
+	// the caller saves them once the file has been stored.
+	Fingerprints *Fingerprints `json:"-"`
+}
+
+type Fingerprints struct {
+	Kind   string
+	Hashes []fingerprint.Hash
+}
+
+type Checker interface {
+	Check(ctx context.Context, file io.ReadSeeker, fileId string, fileType mediatype.Type) (*Result, error)
+}
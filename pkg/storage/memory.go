@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+// Memory keeps objects in a map. It is meant for tests and throwaway runs; nothing survives a restart.
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+func NewMemory() *Memory {
+	return &Memory{objects: make(map[string]memoryObject)}
+}
+
+func (s *Memory) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = memoryObject{data: data, contentType: contentType, modified: time.Now()}
+	return nil
+}
+
+func (s *Memory) object(key string) (memoryObject, error) {
+	if !validKey(key) {
+		return memoryObject{}, ErrInvalidKey
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[key]
+	if !ok {
+		return memoryObject{}, ErrNotFound
+	}
+
+	return obj, nil
+}
+
+func (s *Memory) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (s *Memory) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	obj, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(io.NewSectionReader(bytes.NewReader(obj.data), offset, length)), nil
+}
+
+func (s *Memory) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	obj, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectInfo{Key: key, Size: int64(len(obj.data)), ContentType: obj.contentType, LastModified: obj.modified}, nil
+}
+
+func (s *Memory) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *Memory) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+func (s *Memory) Walk(ctx context.Context, fn func(ObjectInfo) error) error {
+	s.mu.RLock()
+	infos := make([]ObjectInfo, 0, len(s.objects))
+	for key, obj := range s.objects {
+		infos = append(infos, ObjectInfo{Key: key, Size: int64(len(obj.data)), ContentType: obj.contentType,
+			LastModified: obj.modified})
+	}
+	s.mu.RUnlock()
+
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MinIO stores objects in a bucket of a MinIO or other S3 compatible server.
+type MinIO struct {
+	minioClient *minio.Client
+	bucketName  string
+}
+
+func NewMinIO(endpoint, accessKeyID, secretAccessKey, bucketName string, useSSL bool) (*MinIO, error) {
+	minioClient, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := minioClient.BucketExists(context.Background(), bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		err = minioClient.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("bucket %s created successfully\n", bucketName)
+	} else {
+		log.Printf("bucket %s already exists\n", bucketName)
+	}
+
+	return &MinIO{minioClient: minioClient, bucketName: bucketName}, nil
+}
+
+func mapError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MinIO) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	_, err := s.minioClient.PutObject(ctx, s.bucketName, key, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *MinIO) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.get(ctx, key, minio.GetObjectOptions{})
+}
+
+func (s *MinIO) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	var opts minio.GetObjectOptions
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+
+	return s.get(ctx, key, opts)
+}
+
+// get stats the object before returning it, since GetObject itself only fails on the first read.
+func (s *MinIO) get(ctx context.Context, key string, opts minio.GetObjectOptions) (io.ReadCloser, error) {
+	obj, err := s.minioClient.GetObject(ctx, s.bucketName, key, opts)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, mapError(err)
+	}
+
+	return obj, nil
+}
+
+func (s *MinIO) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.minioClient.StatObject(ctx, s.bucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return &ObjectInfo{Key: key, Size: info.Size, ContentType: info.ContentType, LastModified: info.LastModified}, nil
+}
+
+func (s *MinIO) Delete(ctx context.Context, key string) error {
+	return s.minioClient.RemoveObject(ctx, s.bucketName, key, minio.RemoveObjectOptions{})
+}
+
+func (s *MinIO) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s.minioClient.PresignedGetObject(ctx, s.bucketName, key, expiry, nil)
+	if err != nil {
+		return "", mapError(err)
+	}
+
+	return u.String(), nil
+}
+
+func (s *MinIO) Walk(ctx context.Context, fn func(ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for obj := range s.minioClient.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+
+		err := fn(ObjectInfo{Key: obj.Key, Size: obj.Size, ContentType: obj.ContentType, LastModified: obj.LastModified})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Part is one object of a stream assembled by NewReader. Size must be the object's stored size.
+type Part struct {
+	Key  string
+	Size int64
+}
+
+type reader struct {
+	ctx     context.Context
+	storage Storage
+	parts   []Part
+	size    int64
+	offset  int64
+
+	current    io.ReadCloser
+	currentPos int64
+	currentEnd int64
+}
+
+// NewReader returns the concatenation of parts as one seekable stream. Objects are fetched with GetRange only as the
+// stream is read, so nothing more than a read buffer is held in memory and a seek only fetches from the new offset.
+func NewReader(ctx context.Context, s Storage, parts []Part) io.ReadSeekCloser {
+	var size int64
+	for _, part := range parts {
+		size += part.Size
+	}
+
+	return &reader{ctx: ctx, storage: s, parts: parts, size: size}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.current == nil || r.currentPos != r.offset {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if remaining := r.currentEnd - r.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := r.current.Read(p)
+	r.offset += int64(n)
+	r.currentPos += int64(n)
+
+	if errors.Is(err, io.EOF) {
+		if r.offset < r.currentEnd {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	if r.offset == r.currentEnd {
+		r.closeCurrent()
+	}
+
+	return n, err
+}
+
+// open starts reading the part that holds the current offset, from that offset.
+func (r *reader) open() error {
+	r.closeCurrent()
+
+	var start int64
+	for _, part := range r.parts {
+		if r.offset < start+part.Size {
+			within := r.offset - start
+			body, err := r.storage.GetRange(r.ctx, part.Key, within, part.Size-within)
+			if err != nil {
+				return err
+			}
+
+			r.current = body
+			r.currentPos = r.offset
+			r.currentEnd = start + part.Size
+			return nil
+		}
+		start += part.Size
+	}
+
+	return io.EOF
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *reader) closeCurrent() {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
+
+func (r *reader) Close() error {
+	r.closeCurrent()
+	return nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+// countingStorage counts the objects opened through it.
+type countingStorage struct {
+	Storage
+	opened int
+}
+
+func (s *countingStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	s.opened++
+	return s.Storage.GetRange(ctx, key, offset, length)
+}
+
+func newPartsReader(t *testing.T, contents ...string) (*countingStorage, io.ReadSeekCloser, []byte) {
+	t.Helper()
+
+	s := &countingStorage{Storage: NewMemory()}
+	var parts []Part
+	var all []byte
+	for i, content := range contents {
+		key := string(rune('a'+i)) + ".ts"
+		if err := s.Put(context.Background(), key, bytes.NewReader([]byte(content)), int64(len(content)), ""); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		parts = append(parts, Part{Key: key, Size: int64(len(content))})
+		all = append(all, content...)
+	}
+
+	return s, NewReader(context.Background(), s, parts), all
+}
+
+func TestReaderReadsParts(t *testing.T) {
+	s, r, want := newPartsReader(t, "first-", "", "second-", "third")
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+	if s.opened != 3 {
+		t.Errorf("opened %d parts, want 3", s.opened)
+	}
+}
+
+func TestReaderSeek(t *testing.T) {
+	_, r, all := newPartsReader(t, "first-", "second-", "third")
+	defer r.Close()
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil || size != int64(len(all)) {
+		t.Fatalf("Seek to end = %d, %v; want %d", size, err, len(all))
+	}
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{8, io.SeekStart, 8},
+		{-5, io.SeekEnd, int64(len(all)) - 5},
+		{0, io.SeekStart, 0},
+		{6, io.SeekCurrent, 6},
+	}
+
+	for _, tt := range tests {
+		pos, err := r.Seek(tt.offset, tt.whence)
+		if err != nil || pos != tt.want {
+			t.Fatalf("Seek(%d, %d) = %d, %v; want %d", tt.offset, tt.whence, pos, err, tt.want)
+		}
+
+		buf := make([]byte, 4)
+		n, err := io.ReadFull(r, buf)
+		if end := min(int(pos)+4, len(all)); string(buf[:n]) != string(all[pos:end]) {
+			t.Errorf("read %q after Seek(%d, %d), want %q (err %v)", buf[:n], tt.offset, tt.whence, all[pos:end], err)
+		}
+
+		// Rewind what was read so SeekCurrent below starts from the seek target.
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Seek to a negative position succeeded")
+	}
+}
+
+func TestReaderSeekPastEnd(t *testing.T) {
+	_, r, all := newPartsReader(t, "first-", "second-")
+	defer r.Close()
+
+	if _, err := r.Seek(int64(len(all))+10, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+
+	if n, err := r.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+		t.Errorf("Read past end = %d, %v; want 0, EOF", n, err)
+	}
+}
+
+func TestReaderShortPart(t *testing.T) {
+	s := NewMemory()
+	if err := s.Put(context.Background(), "short.ts", bytes.NewReader([]byte("abc")), 3, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	r := NewReader(context.Background(), s, []Part{{Key: "short.ts", Size: 10}})
+	defer r.Close()
+
+	if _, err := io.ReadAll(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadAll = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
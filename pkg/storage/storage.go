@@ -4,65 +4,109 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"strings"
 	"time"
+)
 
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+var (
+	ErrNotFound = errors.New("object not found")
+	// ErrPresignUnsupported is returned by backends that cannot hand out URLs clients fetch from directly.
+	ErrPresignUnsupported = errors.New("storage backend cannot presign urls")
+	ErrInvalidKey         = errors.New("invalid object key")
 )
 
-type FileStorage struct {
-	minioClient *minio.Client
-	bucketName  string
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
 }
 
-func NewFileStorage(endpoint, accessKeyID, secretAccessKey, bucketName string, useSSL bool) (*FileStorage, error) {
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		return nil, err
-	}
+// Storage is an object store addressed by flat keys. Get and GetRange return ErrNotFound for missing keys; Delete of
+// a missing key is not an error. Walk calls fn for every stored object, in no particular order, and stops at the first
+// error fn returns.
+type Storage interface {
+	Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+	Walk(ctx context.Context, fn func(ObjectInfo) error) error
+}
 
-	exists, err := minioClient.BucketExists(context.Background(), bucketName)
-	if err != nil {
-		return nil, err
-	}
+// validKey reports whether key is a flat object name. Keys with path separators or a leading dot are rejected so that
+// every backend accepts the same keys and none can escape its root or collide with in-progress uploads.
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, ".") && !strings.ContainsAny(key, `/\`)
+}
+
+// GCMode decides whether the scheduled storage collector only reports orphaned objects or deletes them.
+type GCMode string
+
+const (
+	GCOff    GCMode = "off"
+	GCReport GCMode = "report"
+	GCDelete GCMode = "delete"
+)
 
-	if !exists {
-		err = minioClient.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{})
-		if err != nil {
-			return nil, err
-		}
-		log.Printf("bucket %s created successfully\n", bucketName)
-	} else {
-		log.Printf("bucket %s already exists\n", bucketName)
+func (m GCMode) Valid() bool {
+	switch m {
+	case GCOff, GCReport, GCDelete:
+		return true
+	default:
+		return false
 	}
+}
+
+const (
+	BackendMinIO  = "minio"
+	BackendLocal  = "local"
+	BackendMemory = "memory"
+)
+
+type Config struct {
+	// Backend is one of minio, local or memory. Defaults to minio.
+	Backend   string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	UseSSL    bool
+	// Dir is the root directory of the local backend.
+	Dir string
+}
 
-	return &FileStorage{minioClient: minioClient, bucketName: bucketName}, nil
+func New(config Config) (Storage, error) {
+	switch config.Backend {
+	case "", BackendMinIO:
+		return NewMinIO(config.Endpoint, config.AccessKey, config.SecretKey, config.Bucket, config.UseSSL)
+	case BackendLocal:
+		return NewLocal(config.Dir)
+	case BackendMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
 }
 
-func (s *FileStorage) UploadFile(ctx context.Context, reader io.Reader, ext string, size int64) (string, error) {
+// Upload stores reader under a new unique key ending in ext and returns the key.
+func Upload(ctx context.Context, s Storage, reader io.Reader, ext string, size int64, contentType string) (string, error) {
 	fileId, err := generateUniqueFilename(ext)
 	if err != nil {
-		return "",  err
+		return "", err
 	}
 
-	_, err = s.minioClient.PutObject(ctx, s.bucketName, fileId, reader, size, minio.PutObjectOptions{ContentType: "application/octet-stream"})
-	if err != nil {
+	if err := s.Put(ctx, fileId, reader, size, contentType); err != nil {
 		return "", err
 	}
 
 	return fileId, nil
 }
 
-func (s *FileStorage) DownloadFile(ctx context.Context, fileId string) (io.ReadCloser, error) {
-	return s.minioClient.GetObject(ctx, s.bucketName, fileId, minio.GetObjectOptions{})
-}
-
 func generateUniqueFilename(fileExtension string) (string, error) {
 	timestamp := time.Now().Format("20060102-150405")
 
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	testStorage(t, NewMemory())
+}
+
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	testStorage(t, s)
+}
+
+// testStorage checks the behaviour every backend must share.
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	data := []byte("0123456789abcdefghij")
+
+	put := func(t *testing.T, key string, data []byte) {
+		t.Helper()
+		if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+
+	read := func(t *testing.T, body io.ReadCloser, err error) []byte {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer body.Close()
+
+		got, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return got
+	}
+
+	t.Run("PutGet", func(t *testing.T) {
+		put(t, "object.txt", data)
+
+		body, err := s.Get(ctx, "object.txt")
+		if got := read(t, body, err); !bytes.Equal(got, data) {
+			t.Errorf("Get = %q, want %q", got, data)
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		put(t, "overwrite.txt", data)
+		put(t, "overwrite.txt", []byte("new"))
+
+		body, err := s.Get(ctx, "overwrite.txt")
+		if got := read(t, body, err); string(got) != "new" {
+			t.Errorf("Get = %q, want %q", got, "new")
+		}
+	})
+
+	t.Run("GetRange", func(t *testing.T) {
+		put(t, "range.txt", data)
+
+		tests := []struct {
+			offset, length int64
+			want           string
+		}{
+			{0, 5, "01234"},
+			{5, 5, "56789"},
+			{15, 5, "fghij"},
+			{15, 100, "fghij"},
+			{0, int64(len(data)), string(data)},
+		}
+
+		for _, tt := range tests {
+			body, err := s.GetRange(ctx, "range.txt", tt.offset, tt.length)
+			if got := read(t, body, err); string(got) != tt.want {
+				t.Errorf("GetRange(%d, %d) = %q, want %q", tt.offset, tt.length, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		put(t, "stat.txt", data)
+
+		info, err := s.Stat(ctx, "stat.txt")
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Key != "stat.txt" || info.Size != int64(len(data)) {
+			t.Errorf("Stat = %+v, want key stat.txt and size %d", info, len(data))
+		}
+		if !strings.HasPrefix(info.ContentType, "text/plain") {
+			t.Errorf("content type = %q, want text/plain", info.ContentType)
+		}
+		if info.LastModified.IsZero() {
+			t.Errorf("last modified is zero")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		put(t, "delete.txt", data)
+
+		if err := s.Delete(ctx, "delete.txt"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := s.Stat(ctx, "delete.txt"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Stat after Delete: err = %v, want %v", err, ErrNotFound)
+		}
+		if err := s.Delete(ctx, "delete.txt"); err != nil {
+			t.Errorf("Delete of a missing key: %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, err := s.Get(ctx, "missing.txt"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get: err = %v, want %v", err, ErrNotFound)
+		}
+		if _, err := s.GetRange(ctx, "missing.txt", 0, 1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetRange: err = %v, want %v", err, ErrNotFound)
+		}
+		if _, err := s.Stat(ctx, "missing.txt"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Stat: err = %v, want %v", err, ErrNotFound)
+		}
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		for _, key := range []string{"", ".", "..", "../escape.txt", "dir/object.txt", `dir\object.txt`, ".hidden"} {
+			err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "")
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Put(%q): err = %v, want %v", key, err, ErrInvalidKey)
+			}
+			if _, err := s.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Get(%q): err = %v, want %v", key, err, ErrInvalidKey)
+			}
+			if _, err := s.GetRange(ctx, key, 0, 1); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("GetRange(%q): err = %v, want %v", key, err, ErrInvalidKey)
+			}
+			if _, err := s.Stat(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Stat(%q): err = %v, want %v", key, err, ErrInvalidKey)
+			}
+			if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Delete(%q): err = %v, want %v", key, err, ErrInvalidKey)
+			}
+		}
+	})
+
+	t.Run("Walk", func(t *testing.T) {
+		var keys []string
+		sizes := make(map[string]int64)
+		err := s.Walk(ctx, func(info ObjectInfo) error {
+			keys = append(keys, info.Key)
+			sizes[info.Key] = info.Size
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk: %v", err)
+		}
+
+		sort.Strings(keys)
+		want := []string{"object.txt", "overwrite.txt", "range.txt", "stat.txt"}
+		if strings.Join(keys, ",") != strings.Join(want, ",") {
+			t.Errorf("Walk keys = %v, want %v", keys, want)
+		}
+		if sizes["overwrite.txt"] != 3 {
+			t.Errorf("overwrite.txt size = %d, want 3", sizes["overwrite.txt"])
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err = s.Walk(ctx, func(ObjectInfo) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("Walk returned %v after %d calls, want %v after 1", err, calls, stop)
+		}
+	})
+
+	t.Run("Presign", func(t *testing.T) {
+		if _, err := s.Presign(ctx, "object.txt", 0); !errors.Is(err, ErrPresignUnsupported) {
+			t.Errorf("Presign: err = %v, want %v", err, ErrPresignUnsupported)
+		}
+	})
+}
+
+func TestUpload(t *testing.T) {
+	s := NewMemory()
+
+	key, err := Upload(context.Background(), s, strings.NewReader("data"), ".txt", 4, "text/plain")
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if !validKey(key) || !strings.HasSuffix(key, ".txt") {
+		t.Errorf("key = %q, want a valid key ending in .txt", key)
+	}
+
+	if _, err := s.Stat(context.Background(), key); err != nil {
+		t.Errorf("Stat(%q): %v", key, err)
+	}
+}
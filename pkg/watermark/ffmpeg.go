@@ -0,0 +1,127 @@
+package watermark
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrFFmpegUnavailable = errors.New("ffmpeg is not installed")
+
+// VariantBFilter is the ffmpeg video filter that renders the B variant: a brightness offset small enough to be
+// invisible and large enough to survive re-encoding.
+const VariantBFilter = "eq=brightness=0.012"
+
+func Available() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+// Segment re-encodes src into fixed-length MPEG-TS segments in dir and returns their paths in order.
+// Both variants are encoded with identical keyframe placement so segment n of A and B always cover the same frames.
+func Segment(ctx context.Context, src, dir string, variantB bool) ([]string, error) {
+	if !Available() {
+		return nil, ErrFFmpegUnavailable
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", src,
+		"-map", "0:v:0", "-map", "0:a?",
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "20", "-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", SegmentSeconds),
+	}
+	if variantB {
+		args = append(args, "-vf", VariantBFilter)
+	}
+	args = append(args, "-c:a", "aac",
+		"-f", "segment", "-segment_time", strconv.Itoa(SegmentSeconds), "-segment_format", "mpegts",
+		filepath.Join(dir, "%05d.ts"))
+
+	if out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg segment: %v: %s", err, bytes.TrimSpace(out))
+	}
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.ts"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+
+	return segments, nil
+}
+
+// SegmentLuma returns the average luma of every SegmentSeconds window of the video at path.
+func SegmentLuma(ctx context.Context, path string) ([]float64, error) {
+	if !Available() {
+		return nil, ErrFFmpegUnavailable
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error", "-i", path, "-an",
+		"-vf", "signalstats,metadata=mode=print:key=lavfi.signalstats.YAVG:file=-", "-f", "null", "-")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg signalstats: %w", err)
+	}
+
+	var sums []float64
+	var counts []int
+	start, pts := -1.0, 0.0
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if i := strings.Index(line, "pts_time:"); i >= 0 {
+			fields := strings.Fields(line[i+len("pts_time:"):])
+			if len(fields) == 0 {
+				continue
+			}
+			pts, err = strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse pts_time: %w", err)
+			}
+			if start < 0 {
+				start = pts
+			}
+			continue
+		}
+
+		value, ok := strings.CutPrefix(line, "lavfi.signalstats.YAVG=")
+		if !ok {
+			continue
+		}
+
+		luma, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse YAVG: %w", err)
+		}
+
+		segment := int((pts - start) / SegmentSeconds)
+		for len(sums) <= segment {
+			sums = append(sums, 0)
+			counts = append(counts, 0)
+		}
+		sums[segment] += luma
+		counts[segment]++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	averages := make([]float64, len(sums))
+	for i := range sums {
+		if counts[i] > 0 {
+			averages[i] = sums[i] / float64(counts[i])
+		}
+	}
+
+	return averages, nil
+}
@@ -0,0 +1,157 @@
+package watermark
+
+import (
+	"bytes"
+	"math/bits"
+)
+
+const (
+	SegmentSeconds = 4
+	PayloadBits    = 64
+	// MinKnownBits is how many payload positions must be read before a copy is attributed to anyone. Fewer leave too
+	// many viewers agreeing with the recovered bits by chance.
+	MinKnownBits = 24
+	// MinMargin is how many more known positions the best candidate must match than the runner-up.
+	MinMargin = 4
+)
+
+// Bit returns which variant (0 for A, 1 for B) segment n carries for the payload.
+// The payload repeats every PayloadBits segments so longer files carry more votes per bit.
+func Bit(payload uint64, segment int) int {
+	return int(payload>>(segment%PayloadBits)) & 1
+}
+
+// Recovered holds the payload bits read back from a file; Known marks the positions that had at least one vote.
+type Recovered struct {
+	Payload  uint64
+	Known    uint64
+	Segments int
+}
+
+// Decode majority-votes each payload position over the per-segment bits. Negative entries are treated as unreadable.
+func Decode(segmentBits []int) Recovered {
+	var votes, ones [PayloadBits]int
+	recovered := Recovered{Segments: len(segmentBits)}
+
+	for i, bit := range segmentBits {
+		if bit < 0 {
+			continue
+		}
+		votes[i%PayloadBits]++
+		ones[i%PayloadBits] += bit
+	}
+
+	for i := 0; i < PayloadBits; i++ {
+		if votes[i] == 0 || ones[i]*2 == votes[i] {
+			continue
+		}
+		recovered.Known |= 1 << i
+		if ones[i]*2 > votes[i] {
+			recovered.Payload |= 1 << i
+		}
+	}
+
+	return recovered
+}
+
+// KnownBits is the number of payload positions that could be read.
+func (r Recovered) KnownBits() int {
+	return bits.OnesCount64(r.Known)
+}
+
+// Score is the fraction of known positions on which candidate agrees with the recovered payload.
+func (r Recovered) Score(candidate uint64) float64 {
+	known := r.KnownBits()
+	if known == 0 {
+		return 0
+	}
+
+	mismatched := bits.OnesCount64((r.Payload ^ candidate) & r.Known)
+	return float64(known-mismatched) / float64(known)
+}
+
+// Match returns the index of the candidate that best agrees with the recovered payload and its score. It reports
+// false when too few bits were read or when the runner-up comes within MinMargin positions of the best, since either
+// could then name the wrong viewer.
+func (r Recovered) Match(candidates []uint64) (int, float64, bool) {
+	known := r.KnownBits()
+	if known < MinKnownBits || len(candidates) == 0 {
+		return -1, 0, false
+	}
+
+	best, bestMatches, runnerUp := -1, -1, -1
+	for i, candidate := range candidates {
+		matches := known - bits.OnesCount64((r.Payload^candidate)&r.Known)
+		switch {
+		case matches > bestMatches:
+			runnerUp = bestMatches
+			best, bestMatches = i, matches
+		case matches > runnerUp:
+			runnerUp = matches
+		}
+	}
+
+	if runnerUp >= 0 && bestMatches-runnerUp < MinMargin {
+		return -1, 0, false
+	}
+
+	return best, float64(bestMatches) / float64(known), true
+}
+
+// ExtractExact walks a leaked copy of the served stream and reports, per segment, whether the A or B variant
+// was sent. It only succeeds on byte-identical copies and returns nil as soon as a segment matches neither.
+func ExtractExact(leaked []byte, a, b [][]byte) []int {
+	if len(a) != len(b) {
+		return nil
+	}
+
+	segmentBits := make([]int, 0, len(a))
+	offset := 0
+	for i := range a {
+		rest := leaked[offset:]
+		switch {
+		case len(rest) == 0:
+			return segmentBits
+		case bytes.HasPrefix(rest, a[i]):
+			segmentBits = append(segmentBits, 0)
+			offset += len(a[i])
+		case bytes.HasPrefix(rest, b[i]):
+			segmentBits = append(segmentBits, 1)
+			offset += len(b[i])
+		default:
+			return nil
+		}
+	}
+
+	return segmentBits
+}
+
+// ExtractLuma classifies each segment from its average brightness. The B variant is rendered slightly brighter, so
+// a leaked segment is read as B when it sits above the midpoint of the two references, after removing any global
+// brightness shift introduced by re-encoding or screen capture.
+func ExtractLuma(leaked, a, b []float64) []int {
+	n := min(len(leaked), len(a), len(b))
+	if n == 0 {
+		return nil
+	}
+
+	var shift float64
+	for i := 0; i < n; i++ {
+		shift += leaked[i] - (a[i]+b[i])/2
+	}
+	shift /= float64(n)
+
+	segmentBits := make([]int, n)
+	for i := 0; i < n; i++ {
+		if b[i] <= a[i] {
+			segmentBits[i] = -1
+			continue
+		}
+
+		if leaked[i]-shift > (a[i]+b[i])/2 {
+			segmentBits[i] = 1
+		}
+	}
+
+	return segmentBits
+}
@@ -0,0 +1,213 @@
+package watermark
+
+import (
+	"math"
+	"testing"
+)
+
+// served returns the variant bits of the first n segments streamed with payload.
+func served(payload uint64, n int) []int {
+	segmentBits := make([]int, n)
+	for i := range segmentBits {
+		segmentBits[i] = Bit(payload, i)
+	}
+	return segmentBits
+}
+
+// tiedPosition reads position 0 once as A and once as B and nothing else.
+func tiedPosition() []int {
+	segmentBits := make([]int, PayloadBits+1)
+	for i := range segmentBits {
+		segmentBits[i] = -1
+	}
+	segmentBits[0], segmentBits[PayloadBits] = 0, 1
+	return segmentBits
+}
+
+func TestDecode(t *testing.T) {
+	const payload = 0xdeadbeefcafef00d
+
+	tests := []struct {
+		name        string
+		segmentBits []int
+		wantPayload uint64
+		wantKnown   uint64
+	}{
+		{"empty", nil, 0, 0},
+		{"full payload", served(payload, PayloadBits), payload, math.MaxUint64},
+		{"short clip", served(payload, 8), payload & 0xff, 0xff},
+		{"unreadable segments", []int{1, -1, 0, 1}, 0b1001, 0b1101},
+		{"majority wins", append(served(0, PayloadBits), append(served(1, PayloadBits), served(1, 1)...)...), 1,
+			math.MaxUint64},
+		{"tie is unknown", tiedPosition(), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recovered := Decode(tt.segmentBits)
+			if recovered.Known != tt.wantKnown || recovered.Payload&recovered.Known != tt.wantPayload {
+				t.Errorf("Decode = payload %#x known %#x, want payload %#x known %#x", recovered.Payload,
+					recovered.Known, tt.wantPayload, tt.wantKnown)
+			}
+			if recovered.Segments != len(tt.segmentBits) {
+				t.Errorf("segments = %d, want %d", recovered.Segments, len(tt.segmentBits))
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	recovered := Recovered{Payload: 0b1010, Known: 0b1111}
+
+	tests := []struct {
+		candidate uint64
+		want      float64
+	}{
+		{0b1010, 1},
+		{0b1011, 0.75},
+		{0b0101, 0},
+		{0xff0a, 1},
+	}
+
+	for _, tt := range tests {
+		if got := recovered.Score(tt.candidate); got != tt.want {
+			t.Errorf("Score(%#b) = %v, want %v", tt.candidate, got, tt.want)
+		}
+	}
+
+	if got := (Recovered{}).Score(0b1010); got != 0 {
+		t.Errorf("Score with nothing known = %v, want 0", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	const (
+		viewer  = 0x0123456789abcdef
+		other   = 0xfedcba9876543210
+		sibling = viewer ^ 1<<40
+	)
+
+	tests := []struct {
+		name        string
+		segmentBits []int
+		candidates  []uint64
+		want        int
+		wantOk      bool
+	}{
+		{"full copy", served(viewer, 2*PayloadBits), []uint64{other, viewer}, 1, true},
+		{"enough of a clip", served(viewer, MinKnownBits), []uint64{viewer, other}, 0, true},
+		{"single segment", served(viewer, 1), []uint64{viewer, other}, -1, false},
+		{"short clip", served(viewer, MinKnownBits-1), []uint64{viewer, other}, -1, false},
+		// The candidates differ only past the part of the payload this clip carries, so it fits both equally.
+		{"ambiguous clip", served(viewer, 32), []uint64{viewer, sibling}, -1, false},
+		{"candidates one position apart", served(viewer, PayloadBits), []uint64{sibling, viewer}, -1, false},
+		{"no candidates", served(viewer, PayloadBits), nil, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, score, ok := Decode(tt.segmentBits).Match(tt.candidates)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Match = %d, %v; want %d, %v", got, ok, tt.want, tt.wantOk)
+			}
+			if ok && score != 1 {
+				t.Errorf("score = %v, want 1 for an untouched copy", score)
+			}
+		})
+	}
+}
+
+func TestMatchMargin(t *testing.T) {
+	const viewer = 0x0123456789abcdef
+	recovered := Decode(served(viewer, PayloadBits))
+
+	// Each candidate disagrees with the copy on the given number of positions.
+	for _, tt := range []struct {
+		runnerUpMisses int
+		wantOk         bool
+	}{
+		{MinMargin - 1, false},
+		{MinMargin, true},
+	} {
+		runnerUp := viewer ^ (uint64(1)<<tt.runnerUpMisses - 1)
+		if _, _, ok := recovered.Match([]uint64{viewer, runnerUp}); ok != tt.wantOk {
+			t.Errorf("runner-up %d positions behind: ok = %v, want %v", tt.runnerUpMisses, ok, tt.wantOk)
+		}
+	}
+}
+
+func TestExtractExact(t *testing.T) {
+	a := [][]byte{[]byte("a0"), []byte("a1-"), []byte("a2")}
+	b := [][]byte{[]byte("b0"), []byte("b1-"), []byte("b2")}
+
+	join := func(parts ...[]byte) []byte {
+		var out []byte
+		for _, part := range parts {
+			out = append(out, part...)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		leaked []byte
+		a, b   [][]byte
+		want   []int
+	}{
+		{"all a", join(a[0], a[1], a[2]), a, b, []int{0, 0, 0}},
+		{"mixed", join(b[0], a[1], b[2]), a, b, []int{1, 0, 1}},
+		{"truncated at a segment boundary", join(a[0], b[1]), a, b, []int{0, 1}},
+		{"re-encoded", []byte("something else"), a, b, nil},
+		{"corrupted segment", join(a[0], []byte("xx-"), a[2]), a, b, nil},
+		{"mismatched variants", join(a[0]), a, b[:1], nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractExact(tt.leaked, tt.a, tt.b)
+			if !equalBits(got, tt.want) {
+				t.Errorf("ExtractExact = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLuma(t *testing.T) {
+	a := []float64{100, 50, 80, 120}
+	b := []float64{103, 53, 83, 123}
+
+	tests := []struct {
+		name   string
+		leaked []float64
+		a, b   []float64
+		want   []int
+	}{
+		{"exact", []float64{100, 53, 83, 120}, a, b, []int{0, 1, 1, 0}},
+		{"brightened by capture", []float64{110, 63, 93, 130}, a, b, []int{0, 1, 1, 0}},
+		{"darkened by capture", []float64{90, 43, 73, 110}, a, b, []int{0, 1, 1, 0}},
+		{"shorter clip", []float64{103, 50}, a, b, []int{1, 0}},
+		{"indistinguishable variants", []float64{100, 53}, []float64{100, 50}, []float64{100, 53}, []int{-1, 1}},
+		{"empty", nil, a, b, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractLuma(tt.leaked, tt.a, tt.b)
+			if !equalBits(got, tt.want) {
+				t.Errorf("ExtractLuma = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalBits(got, want []int) bool {
+	if len(got) != len(want) || (got == nil) != (want == nil) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}